@@ -0,0 +1,159 @@
+package calc
+
+import (
+	"math/big"
+	"strconv"
+)
+
+// Node is an element of a parsed program's syntax tree.
+type Node interface {
+	String() string
+}
+
+// NumberLit is a numeric literal such as 2 or 6.67428e-11.
+type NumberLit struct {
+	Value float64
+	Int   *big.Int // exact value of integers a float64 can't hold, nil otherwise
+}
+
+// Ident is a reference to a variable.
+type Ident struct {
+	Name string
+}
+
+// UnaryExpr is a prefix operation, e.g. -x.
+type UnaryExpr struct {
+	Op string
+	X  Node
+}
+
+// BinaryExpr is an infix operation, e.g. x + 1.
+type BinaryExpr struct {
+	Op   string
+	X, Y Node
+	Pos  int // position of Op in the program, counting bytes from 1, or 0 if unknown
+}
+
+// CallExpr is a function application, e.g. log(2, x).
+type CallExpr struct {
+	Func string
+	Args []Node
+	Pos  int // position of Func in the program, counting bytes from 1, or 0 if unknown
+}
+
+// ListExpr is a list of expressions, e.g. [1, x, 3].
+type ListExpr struct {
+	Elems []Node
+}
+
+// AssignExpr binds the value of an expression to a variable, e.g. a = 2.
+type AssignExpr struct {
+	Name  string
+	Value Node
+}
+
+// LambdaExpr is an anonymous function, e.g. x -> x * 2 or (a, b) -> a + b.
+type LambdaExpr struct {
+	Params []string
+	Body   Node
+}
+
+// ComprehensionExpr builds a list from the elements of another one, e.g.
+// [k^2 for k in range(1, 10) if k % 2 == 1].
+type ComprehensionExpr struct {
+	Elem Node
+	Var  string
+	Iter Node
+	Cond Node // nil to keep every element
+}
+
+func (n *NumberLit) String() string {
+	return defaultPrinter.Print(n)
+}
+
+func (n *Ident) String() string {
+	return defaultPrinter.Print(n)
+}
+
+func (n *UnaryExpr) String() string {
+	return defaultPrinter.Print(n)
+}
+
+func (n *BinaryExpr) String() string {
+	return defaultPrinter.Print(n)
+}
+
+func (n *CallExpr) String() string {
+	return defaultPrinter.Print(n)
+}
+
+func (n *ListExpr) String() string {
+	return defaultPrinter.Print(n)
+}
+
+func (n *AssignExpr) String() string {
+	return defaultPrinter.Print(n)
+}
+
+func (n *LambdaExpr) String() string {
+	return defaultPrinter.Print(n)
+}
+
+func (n *ComprehensionExpr) String() string {
+	return defaultPrinter.Print(n)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// children returns the subexpressions of n.
+func children(n Node) []Node {
+	switch n := n.(type) {
+	case *UnaryExpr:
+		return []Node{n.X}
+	case *BinaryExpr:
+		return []Node{n.X, n.Y}
+	case *CallExpr:
+		return n.Args
+	case *ListExpr:
+		return n.Elems
+	case *AssignExpr:
+		return []Node{n.Value}
+	case *LambdaExpr:
+		return []Node{n.Body}
+	case *ComprehensionExpr:
+		if n.Cond != nil {
+			return []Node{n.Elem, n.Iter, n.Cond}
+		}
+		return []Node{n.Elem, n.Iter}
+	}
+
+	return nil
+}
+
+// isRandom tells if n rolls dice or calls a random builtin, so that its
+// value differs every time it is evaluated.
+func isRandom(n Node) bool {
+	switch n := n.(type) {
+	case *DiceExpr:
+		return true
+	case *CallExpr:
+		if _, ok := randomBuiltins[n.Func]; ok {
+			return true
+		}
+	}
+
+	for _, child := range children(n) {
+		if isRandom(child) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// equalNodes reports whether a and b are structurally identical trees.
+func equalNodes(a, b Node) bool {
+	return a.String() == b.String()
+}
@@ -2,25 +2,108 @@
 package calc
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
+	"math/big"
 	"regexp"
 	"strconv"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 )
 
-var result float64 // yyParse stores the end result here
-
 // Evaluate takes a program and returns the value of it's last statement.
 func Evaluate(program string) (float64, error) {
+	return EvaluateContext(context.Background(), program)
+}
+
+// EvaluateContext is like Evaluate, but gives up with the context's error
+// once ctx is done.
+func EvaluateContext(ctx context.Context, program string) (float64, error) {
+	v, err := NewEvaluator().EvaluateContext(ctx, program)
+	if err != nil {
+		return 0.0, err
+	}
+
+	switch v := v.(type) {
+	case Number:
+		return float64(v), nil
+	case Integer:
+		return v.float(), nil
+	}
+
+	return 0.0, fmt.Errorf("Result is not a number: %s", v)
+}
+
+// ImplicitProduct is a convention for how tightly implicit multiplication,
+// as in 2x or 3(4 + 5), binds.
+type ImplicitProduct int
+
+const (
+	// ImplicitBindsTighter makes implicit products bind tighter than
+	// explicit ones, so 1/2x is 1/(2x).
+	ImplicitBindsTighter ImplicitProduct = iota
+	// ImplicitLikeExplicit parses 2x exactly like 2 * x, so 1/2x is (1/2)x.
+	ImplicitLikeExplicit
+)
+
+// WithImplicitProduct makes the evaluator parse implicit products following
+// convention p instead of ImplicitBindsTighter.
+func WithImplicitProduct(p ImplicitProduct) Option {
+	return func(e *Evaluator) {
+		e.implicit = p
+	}
+}
+
+// Parse takes a program and returns the syntax trees of its statements.
+// Implicit products bind tighter than explicit ones and numbers are written
+// like in Go.
+func Parse(program string) ([]Node, error) {
+	stmts, _, err := parse(program, ImplicitBindsTighter, Plain, nil)
+	return stmts, err
+}
+
+// Parse takes a program and returns the syntax trees of its statements,
+// following the conventions the evaluator was configured with. Programs
+// longer or nesting deeper than the evaluator's Limits fail to parse.
+func (e *Evaluator) Parse(program string) ([]Node, error) {
+	stmts, _, err := e.parse(program)
+	return stmts, err
+}
+
+// parse is Parse, also returning where each statement is in program.
+func (e *Evaluator) parse(program string) ([]Node, []Span, error) {
+	if err := e.checkInput(program); err != nil {
+		return nil, nil, err
+	}
+
+	stmts, spans, err := parse(program, e.implicit, e.locale, e.vars)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return stmts, spans, e.checkDepth(stmts)
+}
+
+// parse parses program, where the variables in vars are defined.
+func parse(program string, implicit ImplicitProduct, locale Locale, vars map[string]Value) ([]Node, []Span, error) {
 	lexer := newCalcLexer(program)
-	if yyParse(lexer) != 0 {
-		return 0.0, errors.New("Failed to parse program")
+	lexer.locale = locale
+	for name := range vars {
+		lexer.names[name] = true
+	}
+	if implicit == ImplicitLikeExplicit {
+		lexer.implicit = '*'
+	}
+	// Unknown characters end the token stream, so the parse may succeed
+	// even though the lexer failed.
+	if yyParse(lexer) != 0 || lexer.failed {
+		return nil, nil, errors.New("Failed to parse program")
 	}
 
-	return lexer.result, nil
+	return lexer.stmts, lexer.spans, nil
 }
 
 func log(base, arg float64) float64 {
@@ -38,66 +121,189 @@ func exp(x float64) float64 {
 // Lexer is a math expressions (plus variables) tokenizer.
 type calcLexer struct {
 	program string
-	ts, te  int                // current token is program[ts:te]
-	result  float64            // storage for the interpreter's result
-	symTab  map[string]float64 // symbol table
+	ts, te  int    // current token is program[ts:te]
+	stmts   []Node // storage for the parsed statements
+	spans   []Span // and where they are in the program
+	stmt    *Span  // span of the statement being lexed, if any
+	failed  bool   // whether Error was called
+
+	locale   Locale    // convention numbers are written in
+	implicit int       // token standing for implicit products
+	last     int       // last token returned
+	nesting  int       // how many parenthesis and brackets are open
+	next     int       // token to return after an implicit product, if any
+	nextVal  yySymType // and its value
+
+	// names are the variables, lexed as names even if they look like
+	// numbers or dice.
+	names map[string]bool
 }
 
 // NewLexer returns a new lexer for the given program.
 func newCalcLexer(program string) *calcLexer {
 	return &calcLexer{
-		program: program,
-		ts:      -1, // current token's start
-		te:      0,  // and end positions
-		symTab:  make(map[string]float64),
+		program:  program,
+		ts:       -1, // current token's start
+		te:       0,  // and end positions
+		names:    declaredNames(program),
+		locale:   Plain,
+		implicit: IMPLICIT,
+	}
+}
+
+var (
+	assignedName = regexp.MustCompile(`([\p{L}_][\p{L}\p{N}_]*)\s*=(?:[^=]|$)`)
+	iteratedName = regexp.MustCompile(`\bfor\s+([\p{L}_][\p{L}\p{N}_]*)`)
+	paramName    = regexp.MustCompile(`([\p{L}_][\p{L}\p{N}_]*)\s*->`)
+	paramNames   = regexp.MustCompile(`\(([^()]*)\)\s*->`)
+)
+
+// declaredNames returns the names program assigns to or binds in lambdas
+// and comprehensions. They stay names where they are used, so that
+// d20 = 5; d20 or one = 1; one refer to the variables rather than to a roll
+// or a number.
+func declaredNames(program string) map[string]bool {
+	names := make(map[string]bool)
+	for _, re := range []*regexp.Regexp{assignedName, iteratedName, paramName} {
+		for _, m := range re.FindAllStringSubmatch(program, -1) {
+			names[m[1]] = true
+		}
 	}
+	for _, m := range paramNames.FindAllStringSubmatch(program, -1) {
+		for _, name := range strings.Split(m[1], ",") {
+			names[strings.TrimSpace(name)] = true
+		}
+	}
+
+	return names
 }
 
 // Lex returns the next token type and puts its value (if any) in lval.
+// Juxtaposed operands, as in 2x, are separated by an implicit product token.
 func (l *calcLexer) Lex(lval *yySymType) int {
-	l.consumeWhiteSpace()
+	var token int
+	if l.next != 0 {
+		token, *lval = l.next, l.nextVal
+		l.next = 0
+	} else {
+		token = l.lex(lval)
+		switch token {
+		case '(', '[':
+			l.nesting++
+		case ')', ']':
+			l.nesting--
+		}
+		l.span(token)
+		if isImplicitProduct(l.last, token) {
+			l.next, l.nextVal = token, *lval
+			token = l.implicit
+		}
+	}
+
+	l.last = token
+	return token
+}
+
+// span extends the span of the current statement to the token just lexed,
+// or ends it if the token ends the statement.
+func (l *calcLexer) span(token int) {
+	switch {
+	case token == ';' || token == 0:
+		if l.stmt != nil {
+			l.spans = append(l.spans, *l.stmt)
+			l.stmt = nil
+		}
+	case l.stmt == nil:
+		l.stmt = &Span{l.ts, l.te}
+	default:
+		l.stmt.End = l.te
+	}
+}
+
+// isImplicitProduct tells if the operands ending with token last and
+// starting with token next are multiplied. Numbers must come first, as x2 is
+// an identifier and x 2 is most likely a typo, and a name followed by
+// parenthesis is a call.
+func isImplicitProduct(last, next int) bool {
+	if !endsOperand(last) {
+		return false
+	}
+
+	switch next {
+	case IDENTIFIER, LOG, LOG10, LOG2, LN, POW, EXP, SQRT:
+		return true
+	case '(':
+		return last != IDENTIFIER
+	}
+
+	return false
+}
+
+// endsOperand tells if token can be the last one of an operand.
+func endsOperand(token int) bool {
+	switch token {
+	case NUMBER, IDENTIFIER, DICE, SUPERSCRIPT, ')', ']':
+		return true
+	}
+
+	return false
+}
+
+func (l *calcLexer) lex(lval *yySymType) int {
+	newline := l.consumeWhiteSpace()
+	l.ts = l.te
+	lval.pos = l.ts + 1
 
 	if l.eof() {
 		return 0
 	}
 
-	reLn := regexp.MustCompile(`ln`)
-	reLog10 := regexp.MustCompile(`log10`)
-	reLog2 := regexp.MustCompile(`log2`)
-	reLog := regexp.MustCompile(`log`)
-	reExp := regexp.MustCompile(`exp`)
-	rePow := regexp.MustCompile(`pow`)
-	reOp := regexp.MustCompile(`[;=,()+/*-]`)
-	reIdent := regexp.MustCompile(`\pL(\pL|[0-9_])*`)
+	// A line break separates statements like ';' does, unless it is inside
+	// parenthesis or brackets or the line ends with an operator, so long
+	// expressions and lambda bodies can span several lines.
+	if newline && l.nesting == 0 && endsOperand(l.last) {
+		return ';'
+	}
 
-	// This scary-looking regex was taken from
-	// https://golang.org/ref/spec#Floating-point_literals
-	// with the added option to have no decimal point. Funny enough, putting the
-	// [0-9]+ at the beginning fails to match 1.5, for example.
-	// TODO: find documentation about in what order Go tries to match the ORed
-	// regexes.
-	reNumber := regexp.MustCompile(`[0-9]+\.([0-9]+)?([eE][+-]?[0-9]+)?|[0-9]+([eE][+-]?[0-9]+)|\.[0-9]+([eE][+-]?[0-9]+)?|[0-9]+`)
+	if token, ok := l.lexSymbol(lval); ok {
+		return token
+	}
+	if params, ok := l.lexLambda(); ok {
+		lval.names = params
+		return LAMBDA
+	}
+	if token, ok := l.lexOperator(); ok {
+		return token
+	}
+	if dice, ok := l.lexDice(); ok {
+		lval.node = dice
+		return DICE
+	}
 
+	c := l.peekRune()
 	switch {
-	case l.matchAndAdvance(reLn):
-		return LN
-	case l.matchAndAdvance(reLog2):
-		return LOG2
-	case l.matchAndAdvance(reLog10):
-		return LOG10
-	case l.matchAndAdvance(reLog):
-		return LOG
-	case l.matchAndAdvance(reExp):
-		return EXP
-	case l.matchAndAdvance(rePow):
-		return POW
-	case l.matchAndAdvance(reOp):
-		return int(l.currentToken()[0])
-	case l.matchAndAdvance(reIdent):
+	case unicode.IsLetter(c) || c == '_':
+		l.scanIdentifier()
+		if token, ok := keywords[l.currentToken()]; ok {
+			return token
+		}
+		if l.lexBase(lval) || !l.names[l.currentToken()] && (l.lexRoman(lval) || l.lexWords(lval)) {
+			return NUMBER
+		}
 		lval.name = l.currentToken()
+		if name, ok := symbolNames[lval.name]; ok {
+			lval.name = name
+		}
 		return IDENTIFIER
-	case l.matchAndAdvance(reNumber):
-		lval.val = l.parseFloat()
+	case l.lexBase(lval) || l.lexPrefixed(lval):
+		return NUMBER
+	case l.scanNumber():
+		lval.val, lval.num = l.parseNumber()
+		// A vulgar fraction right after a number makes a mixed number, 1½.
+		if f, ok := vulgarFractions[l.peekRune()]; ok {
+			l.nextRune()
+			lval.val, lval.num = lval.val+f, nil
+		}
 		return NUMBER
 	default:
 		l.Error(fmt.Sprintf("Error parsing expression: %s", l.program[l.te:]))
@@ -105,15 +311,509 @@ func (l *calcLexer) Lex(lval *yySymType) int {
 	}
 }
 
-func (l *calcLexer) eof() bool {
-	return l.te == len(l.program)
+// keywords are the names lexed as tokens of their own rather than as
+// identifiers. Only whole names are keywords, so lnx is an identifier.
+var keywords = map[string]int{
+	"ln":    LN,
+	"log":   LOG,
+	"log2":  LOG2,
+	"log10": LOG10,
+	"exp":   EXP,
+	"pow":   POW,
+	"for":   FOR,
+	"in":    IN,
+	"if":    IF,
+}
+
+// lexOperator lexes the ASCII operators and punctuation.
+func (l *calcLexer) lexOperator() (int, bool) {
+	c := l.byteAt(l.te)
+	switch c {
+	case '<', '>':
+		l.te++
+		if l.byteAt(l.te) == '=' {
+			l.te++
+			if c == '<' {
+				return LE, true
+			}
+			return GE, true
+		}
+		return int(c), true
+	case '=', '!':
+		if l.byteAt(l.te+1) == '=' {
+			l.te += 2
+			if c == '=' {
+				return EQ, true
+			}
+			return NE, true
+		}
+		if c == '=' {
+			l.te++
+			return int(c), true
+		}
+	case ';', ',', '(', ')', '[', ']', '+', '-', '*', '/', '%', '^':
+		l.te++
+		return int(c), true
+	}
+
+	return 0, false
+}
+
+// lexLambda lexes the parameters of a lambda and its arrow, as in x -> or
+// (a, b) ->.
+func (l *calcLexer) lexLambda() ([]string, bool) {
+	var params []string
+	i := l.te
+	if l.byteAt(i) == '(' {
+		for i = l.skipSpace(i + 1); l.byteAt(i) != ')'; {
+			name, end := l.wordAt(i)
+			if _, ok := keywords[name]; ok || name == "" || !unicode.IsLetter([]rune(name)[0]) {
+				return nil, false
+			}
+			params = append(params, name)
+			if i = l.skipSpace(end); l.byteAt(i) == ',' {
+				i = l.skipSpace(i + 1)
+			} else if l.byteAt(i) != ')' {
+				return nil, false
+			}
+		}
+		i++
+	} else {
+		name, end := l.wordAt(i)
+		if _, ok := keywords[name]; ok || name == "" || !unicode.IsLetter([]rune(name)[0]) {
+			return nil, false
+		}
+		params, i = []string{name}, end
+	}
+
+	if i = l.skipSpace(i); !strings.HasPrefix(l.program[i:], "->") {
+		return nil, false
+	}
+
+	l.te = i + 2
+	return params, true
+}
+
+// lexDice lexes tabletop dice notation: an optional count, d, the number of
+// sides and optionally k, h or l and how many dice to keep, as in 4d6kh3.
+func (l *calcLexer) lexDice() (*DiceExpr, bool) {
+	i := l.te
+	count := l.scanDigits(i)
+	if l.byteAt(count) != 'd' {
+		return nil, false
+	}
+	sides := l.scanDigits(count + 1)
+	if sides == count+1 {
+		return nil, false
+	}
+
+	dice := &DiceExpr{
+		Count: atoiOr(l.program[i:count], 1),
+		Sides: atoiOr(l.program[count+1:sides], 0),
+	}
+
+	end := sides
+	if l.byteAt(end) == 'k' {
+		keep := end + 1
+		lowest := l.byteAt(keep) == 'l'
+		if lowest || l.byteAt(keep) == 'h' {
+			keep++
+		}
+		if n := l.scanDigits(keep); n > keep {
+			dice.Keep = atoiOr(l.program[keep:n], 0)
+			dice.KeepLowest = lowest
+			end = n
+		}
+	}
+
+	// d20x is a name, not a roll, and so is d20 if it is a variable.
+	if c, _ := utf8.DecodeRuneInString(l.program[end:]); isIdentifierRune(c) || l.names[l.program[i:end]] {
+		return nil, false
+	}
+
+	l.te = end
+	return dice, true
+}
+
+// atoiOr returns the integer s stands for, def if s is empty or -1 if it is
+// too large to roll anyway.
+func atoiOr(s string, def int) int {
+	if s == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return -1
+	}
+
+	return n
+}
+
+// lexBase lexes an integer written in a base from 2 to 36 with the base as
+// a subscript, as in 513₇ or FF₁₆.
+func (l *calcLexer) lexBase(lval *yySymType) bool {
+	end := l.ts
+	for isAlphanumeric(l.byteAt(end)) {
+		end++
+	}
+
+	base, next := 0, end
+	for {
+		c, width := utf8.DecodeRuneInString(l.program[next:])
+		if c < '₀' || c > '₉' {
+			break
+		}
+		base, next = base*10+int(c-'₀'), next+width
+	}
+	if next == end || end == l.ts {
+		return false
+	}
+
+	digits := l.program[l.ts:end]
+	l.te = next
+	if base < 2 || base > 36 {
+		l.Error(fmt.Sprintf("Base of %s must be from 2 to 36", l.currentToken()))
+		return true
+	}
+
+	l.setDigits(lval, digits, base)
+	return true
+}
+
+// prefixBases are the bases of the integers written with the prefixes 0b,
+// 0o and 0x.
+var prefixBases = map[byte]int{'b': 2, 'B': 2, 'o': 8, 'O': 8, 'x': 16, 'X': 16}
+
+// lexPrefixed lexes an integer written in binary, octal or hexadecimal with
+// a prefix, as in 0xFF. Without a digit of its base after the prefix, as in
+// 0x, it is an implicit product of 0 instead.
+func (l *calcLexer) lexPrefixed(lval *yySymType) bool {
+	base, ok := prefixBases[l.byteAt(l.te+1)]
+	if l.byteAt(l.te) != '0' || !ok {
+		return false
+	}
+	if d := digitValue(l.byteAt(l.te + 2)); d < 0 || d >= base {
+		return false
+	}
+
+	end := l.te + 2
+	for isAlphanumeric(l.byteAt(end)) {
+		end++
+	}
+
+	l.te = end
+	l.setDigits(lval, l.program[l.ts+2:end], base)
+	return true
+}
+
+// lexRoman lexes the current identifier as a number if it is written in
+// Roman numerals, as in MCMXCIV. Single letters, like C or X, are left as
+// names.
+func (l *calcLexer) lexRoman(lval *yySymType) bool {
+	n, ok := parseRoman(l.currentToken())
+	if !ok || len(l.currentToken()) < 2 {
+		return false
+	}
+
+	lval.val, lval.num = float64(n), nil
+	return true
+}
+
+// lexWords lexes the current identifier and the ones following it as a
+// number if they spell one in English, as in one thousand two hundred.
+// Variables end the number, so two hundred is 2 * hundred if hundred is
+// one.
+func (l *calcLexer) lexWords(lval *yySymType) bool {
+	if !isNumberWord(l.currentToken()) {
+		return false
+	}
+
+	words := []string{l.currentToken()}
+	for {
+		word, end := l.wordAt(l.te)
+		switch {
+		case l.byteAt(l.te) == '-':
+			// Only tens and ones are joined, as in twenty-one. Otherwise, as
+			// in twenty-x, the hyphen is a minus.
+			if word, end = l.wordAt(l.te + 1); !isHyphenated(words[len(words)-1], word) {
+				word = ""
+			}
+		case strings.EqualFold(word, "and"):
+			word, end = l.wordAt(end)
+		}
+		if !isNumberWord(word) || l.names[word] {
+			break
+		}
+		words, l.te = append(words, word), end
+	}
+
+	x, ok := parseWords(words)
+	if !ok {
+		l.Error(fmt.Sprintf("%s is not a number", l.currentToken()))
+		return true
+	}
+
+	setInteger(lval, x)
+	return true
+}
+
+// wordAt returns the word starting at i, after white space, and where it
+// ends.
+func (l *calcLexer) wordAt(i int) (string, int) {
+	j := l.skipSpace(i)
+	if strings.Contains(l.program[i:j], "\n") {
+		// Words on the next line are another statement.
+		return "", i
+	}
+	i = j
+	end := i
+	for {
+		c, width := utf8.DecodeRuneInString(l.program[end:])
+		if width == 0 || !isIdentifierRune(c) {
+			break
+		}
+		end += width
+	}
+
+	return l.program[i:end], end
+}
+
+// setDigits sets the value of the token to the integer digits write in
+// base, or fails if they don't.
+func (l *calcLexer) setDigits(lval *yySymType, digits string, base int) {
+	x, ok := new(big.Int).SetString(digits, base)
+	if !ok {
+		l.Error(fmt.Sprintf("%s is not a number in base %d", digits, base))
+		return
+	}
+
+	setInteger(lval, x)
+}
+
+// setInteger sets the value of the token to x, exactly if a float64 can't
+// hold it.
+func setInteger(lval *yySymType, x *big.Int) {
+	lval.val, lval.num = Integer{x}.float(), nil
+	if !isExactFloat(x) {
+		lval.num = x
+	}
+}
+
+// skipSpace returns where the white space starting at i ends.
+func (l *calcLexer) skipSpace(i int) int {
+	for {
+		c, width := utf8.DecodeRuneInString(l.program[i:])
+		if width == 0 || !unicode.IsSpace(c) {
+			return i
+		}
+		i += width
+	}
+}
+
+func isAlphanumeric(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// digitValue returns the value of the digit c in bases up to 36, or -1 if
+// it isn't one.
+func digitValue(c byte) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'z':
+		return int(c-'a') + 10
+	case 'A' <= c && c <= 'Z':
+		return int(c-'A') + 10
+	}
+
+	return -1
+}
+
+// scanIdentifier advances past a letter and the letters, digits and
+// underscores following it.
+func (l *calcLexer) scanIdentifier() {
+	l.nextRune()
+	for isIdentifierRune(l.peekRune()) {
+		l.nextRune()
+	}
+}
+
+func isIdentifierRune(c rune) bool {
+	return unicode.IsLetter(c) || '0' <= c && c <= '9' || c == '_'
+}
+
+// scanNumber advances past the number written following l.locale at the
+// current position, if there is one. Group separators, other than commas,
+// are allowed only between groups of three digits, and decimal separators
+// other than the point must be followed by digits, or the comma in
+// pow(1, 2) would be taken for the one in 1,5.
+func (l *calcLexer) scanNumber() bool {
+	decimal := l.locale.Decimal
+
+	i := l.scanInteger(l.te)
+	switch {
+	case i > l.te:
+		if c, width := utf8.DecodeRuneInString(l.program[i:]); c == decimal {
+			if j := l.scanDigits(i + width); j > i+width || decimal == '.' {
+				i = j
+			}
+		}
+	case decimal == '.' && l.byteAt(l.te) == '.' && l.scanDigits(l.te+1) > l.te+1:
+		i = l.scanDigits(l.te + 1)
+	default:
+		return false
+	}
+
+	l.te = l.scanExponent(i)
+	return true
+}
+
+// scanInteger returns where the integer part of a number starting at i ends,
+// which is i if there is none.
+func (l *calcLexer) scanInteger(i int) int {
+	j := l.scanDigits(i)
+
+	group := l.locale.Group
+	if group == 0 || group == ',' || l.locale.Grouping != GroupThousands || j == i || j-i > 3 {
+		return j
+	}
+
+	for {
+		c, width := utf8.DecodeRuneInString(l.program[j:])
+		if c != group || l.scanDigits(j+width)-(j+width) < 3 {
+			return j
+		}
+		j += width + 3
+	}
 }
 
-func (l *calcLexer) consumeWhiteSpace() {
+// scanExponent returns where the exponent of a number starting at i ends,
+// which is i if there is none.
+func (l *calcLexer) scanExponent(i int) int {
+	if c := l.byteAt(i); c != 'e' && c != 'E' {
+		return i
+	}
+
+	j := i + 1
+	if c := l.byteAt(j); c == '+' || c == '-' {
+		j++
+	}
+	if k := l.scanDigits(j); k > j {
+		return k
+	}
+
+	return i
+}
+
+// scanDigits returns where the ASCII digits starting at i end.
+func (l *calcLexer) scanDigits(i int) int {
+	for i < len(l.program) && '0' <= l.program[i] && l.program[i] <= '9' {
+		i++
+	}
+
+	return i
+}
+
+// symbolTokens are the operators that mobile keyboards and other apps write
+// with Unicode symbols.
+var symbolTokens = map[rune]int{
+	'×': '*',
+	'·': '*',
+	'⋅': '*',
+	'∗': '*',
+	'÷': '/',
+	'∕': '/',
+	'−': '-',
+	'≤': LE,
+	'≥': GE,
+	'≠': NE,
+	'√': SQRT,
+	'±': PM,
+}
+
+// symbolNames are the symbols that stand for constants. Like any other
+// letter, they only do so on their own: πr is a name, π r a product.
+var symbolNames = map[string]string{
+	"π": "pi",
+	"τ": "tau",
+	"ℯ": "e",
+}
+
+var vulgarFractions = map[rune]float64{
+	'½': 1.0 / 2, '⅓': 1.0 / 3, '⅔': 2.0 / 3, '¼': 1.0 / 4, '¾': 3.0 / 4,
+	'⅕': 1.0 / 5, '⅖': 2.0 / 5, '⅗': 3.0 / 5, '⅘': 4.0 / 5, '⅙': 1.0 / 6,
+	'⅚': 5.0 / 6, '⅐': 1.0 / 7, '⅛': 1.0 / 8, '⅜': 3.0 / 8, '⅝': 5.0 / 8,
+	'⅞': 7.0 / 8, '⅑': 1.0 / 9, '⅒': 1.0 / 10,
+}
+
+var superscriptDigits = strings.NewReplacer(
+	"⁻", "-", "⁰", "0", "¹", "1", "²", "2", "³", "3", "⁴", "4",
+	"⁵", "5", "⁶", "6", "⁷", "7", "⁸", "8", "⁹", "9",
+)
+
+// lexSymbol lexes the Unicode symbols that are tokens on their own:
+// operators, vulgar fractions and superscript exponents, as in x².
+func (l *calcLexer) lexSymbol(lval *yySymType) (int, bool) {
 	c := l.peekRune()
-	for unicode.IsSpace(c) {
+	if token, ok := symbolTokens[c]; ok {
+		l.nextRune()
+		return token, true
+	}
+	if f, ok := vulgarFractions[c]; ok {
+		l.nextRune()
+		lval.val, lval.num = f, nil
+		return NUMBER, true
+	}
+	if l.scanSuperscript() {
+		exp, _ := strconv.Atoi(superscriptDigits.Replace(l.currentToken()))
+		lval.val = float64(exp)
+		return SUPERSCRIPT, true
+	}
+
+	return 0, false
+}
+
+// scanSuperscript advances past a superscript integer, as in x⁻¹, if there
+// is one at the current position.
+func (l *calcLexer) scanSuperscript() bool {
+	i := l.te
+	if l.peekRune() == '⁻' {
 		l.nextRune()
-		c = l.peekRune()
+	}
+
+	digits := 0
+	for strings.ContainsRune("⁰¹²³⁴⁵⁶⁷⁸⁹", l.peekRune()) {
+		l.nextRune()
+		digits++
+	}
+	if digits == 0 {
+		l.te = i
+	}
+
+	return digits > 0
+}
+
+func (l *calcLexer) eof() bool {
+	return l.te == len(l.program)
+}
+
+// consumeWhiteSpace skips white space and comments, which run from # to the
+// end of the line, and tells if they held a line break.
+func (l *calcLexer) consumeWhiteSpace() bool {
+	newline := false
+	for {
+		switch c := l.peekRune(); {
+		case c == '#':
+			for !l.eof() && l.peekRune() != '\n' {
+				l.nextRune()
+			}
+		case unicode.IsSpace(c):
+			newline = newline || c == '\n'
+			l.nextRune()
+		default:
+			return newline
+		}
 	}
 }
 
@@ -129,25 +829,29 @@ func (l *calcLexer) nextRune() rune {
 	return c
 }
 
-// matchAndAdvance will check if the beginning of the remainder of the program
-// l.program matches the regular expression re. If it does, l.te is advanced by
-// the size of the match and true is returned. Else, nothing happens and false
-// is returned.
-func (l *calcLexer) matchAndAdvance(re *regexp.Regexp) bool {
-	if loc := re.FindStringIndex(l.program[l.te:]); loc != nil && loc[0] == 0 {
-		l.ts, l.te = l.te, l.te+loc[1]
-		return true
+// byteAt returns the byte at position i of the program, or 0 past its end.
+func (l *calcLexer) byteAt(i int) byte {
+	if i >= len(l.program) {
+		return 0
 	}
 
-	return false
+	return l.program[i]
 }
 
-func (l *calcLexer) parseFloat() float64 {
-	val, err := strconv.ParseFloat(l.currentToken(), 64)
+// parseNumber returns the value of the current token and, if it is an
+// integer a float64 can't hold exactly, its exact value.
+func (l *calcLexer) parseNumber() (float64, *big.Int) {
+	number := l.locale.canonical(l.currentToken())
+	val, err := strconv.ParseFloat(number, 64)
 	if err != nil {
-		l.Error(fmt.Sprintf("ParseFloat(%s, 64) failed: %s", l.currentToken(), err.Error()))
+		l.Error(fmt.Sprintf("ParseFloat(%s, 64) failed: %s", number, err.Error()))
 	}
-	return val
+
+	if i, ok := new(big.Int).SetString(number, 10); ok && !isExactFloat(i) {
+		return val, i
+	}
+
+	return val, nil
 }
 
 func (l *calcLexer) currentToken() string {
@@ -156,5 +860,6 @@ func (l *calcLexer) currentToken() string {
 
 // Error is called when something is wrong in the Lexer's program.
 func (l *calcLexer) Error(s string) {
+	l.failed = true
 	fmt.Printf("Syntax error: %s\n", s)
 }
@@ -1,52 +1,100 @@
 %{
 package calc
 
-import (
-    "math"
-)
+import "math/big"
+
 %}
 
 %union{
     val float64
+    num *big.Int // exact value of a NUMBER too large for val
     name string
+    names []string // parameters of a LAMBDA
+    node Node
+    nodes []Node
+    pos int // position of the token, counting bytes from 1
 }
 
-%type <val> expr
+%type <node> expr
+%type <nodes> args
 
 %token <val> NUMBER
 %token <name> IDENTIFIER
+%token <node> DICE
 %token LOG
 %token LOG10
 %token LOG2
 %token LN
 %token POW
 %token EXP
+%token IMPLICIT
+%token LE
+%token GE
+%token SQRT
+%token PM
+%token EQ
+%token NE
+%token FOR
+%token IN
+%token IF
+%token <names> LAMBDA
+%token <val> SUPERSCRIPT
 
+%right LAMBDA
+%nonassoc '<' '>' LE GE EQ NE
 %left '+' '-'
-%left '*' '/'
+%left '*' '/' '%'
 %right '='
+%left PM
+%left IMPLICIT
 %left UMINUS
+%right '^'
+%left SUPERSCRIPT
 
 %%
 
-prog : expr { yylex.(*calcLexer).result = $1 }
-     | prog ';' expr { yylex.(*calcLexer).result = $3 }
+prog : expr { yylex.(*calcLexer).stmts = append(yylex.(*calcLexer).stmts, $1) }
+     | prog ';' expr { yylex.(*calcLexer).stmts = append(yylex.(*calcLexer).stmts, $3) }
 
-expr : NUMBER { $$ = $1 }
-     | '-' expr %prec UMINUS { $$ = -$2 }
-     | expr '+' expr { $$ = $1 + $3 }
-     | expr '-' expr { $$ = $1 - $3 }
-     | expr '*' expr { $$ = $1 * $3 }
-     | expr '/' expr { $$ = $1 / $3 }
+expr : NUMBER { $$ = &NumberLit{Value: $1, Int: $<num>1} }
+     | DICE { $$ = $1 }
+     | '-' expr %prec UMINUS { $$ = &UnaryExpr{Op: "-", X: $2} }
+     | expr '+' expr { $$ = &BinaryExpr{Op: "+", X: $1, Y: $3, Pos: $<pos>2} }
+     | expr '-' expr { $$ = &BinaryExpr{Op: "-", X: $1, Y: $3, Pos: $<pos>2} }
+     | expr '*' expr { $$ = &BinaryExpr{Op: "*", X: $1, Y: $3, Pos: $<pos>2} }
+     | expr '/' expr { $$ = &BinaryExpr{Op: "/", X: $1, Y: $3, Pos: $<pos>2} }
+     | expr '%' expr { $$ = &BinaryExpr{Op: "%", X: $1, Y: $3, Pos: $<pos>2} }
+     | expr PM expr { $$ = &BinaryExpr{Op: "±", X: $1, Y: $3, Pos: $<pos>2} }
+     | expr IMPLICIT expr { $$ = &BinaryExpr{Op: "*", X: $1, Y: $3, Pos: $<pos>2} }
+     | expr '<' expr { $$ = &BinaryExpr{Op: "<", X: $1, Y: $3, Pos: $<pos>2} }
+     | expr '>' expr { $$ = &BinaryExpr{Op: ">", X: $1, Y: $3, Pos: $<pos>2} }
+     | expr LE expr { $$ = &BinaryExpr{Op: "<=", X: $1, Y: $3, Pos: $<pos>2} }
+     | expr GE expr { $$ = &BinaryExpr{Op: ">=", X: $1, Y: $3, Pos: $<pos>2} }
+     | expr EQ expr { $$ = &BinaryExpr{Op: "==", X: $1, Y: $3, Pos: $<pos>2} }
+     | expr NE expr { $$ = &BinaryExpr{Op: "!=", X: $1, Y: $3, Pos: $<pos>2} }
+     | expr '^' expr { $$ = &CallExpr{Func: "pow", Args: []Node{$1, $3}, Pos: $<pos>2} }
+     | expr SUPERSCRIPT { $$ = &CallExpr{Func: "pow", Args: []Node{$1, &NumberLit{Value: $2}}, Pos: $<pos>2} }
+     | SQRT expr %prec UMINUS { $$ = &CallExpr{Func: "sqrt", Args: []Node{$2}, Pos: $<pos>1} }
      | '(' expr ')' { $$ = $2 }
-     | LOG '(' expr ',' expr ')' { $$ = log($3, $5) }
-     | LOG10 '(' expr ')' { $$ = log(10, $3) }
-     | LOG2 '(' expr ')' { $$ = log(2, $3) }
-     | LN '(' expr ')' { $$ = log(math.E, $3) }
-     | POW '(' expr ',' expr ')' { $$ = pow($3, $5) }
-     | EXP '(' expr ')' { $$ = exp($3) }
-     | IDENTIFIER { $$ = yylex.(*calcLexer).symTab[$1] }
-     | IDENTIFIER '=' expr { yylex.(*calcLexer).symTab[$1] = $3; $$ = $3 }
+     | LOG '(' expr ',' expr ')' { $$ = &CallExpr{Func: "log", Args: []Node{$3, $5}, Pos: $<pos>1} }
+     | LOG10 '(' expr ')' { $$ = &CallExpr{Func: "log10", Args: []Node{$3}, Pos: $<pos>1} }
+     | LOG2 '(' expr ')' { $$ = &CallExpr{Func: "log2", Args: []Node{$3}, Pos: $<pos>1} }
+     | LN '(' expr ')' { $$ = &CallExpr{Func: "ln", Args: []Node{$3}, Pos: $<pos>1} }
+     | POW '(' expr ',' expr ')' { $$ = &CallExpr{Func: "pow", Args: []Node{$3, $5}, Pos: $<pos>1} }
+     | EXP '(' expr ')' { $$ = &CallExpr{Func: "exp", Args: []Node{$3}, Pos: $<pos>1} }
+     | IDENTIFIER '(' ')' { $$ = &CallExpr{Func: $1, Pos: $<pos>1} }
+     | IDENTIFIER '(' args ')' { $$ = &CallExpr{Func: $1, Args: $3, Pos: $<pos>1} }
+     | '[' ']' { $$ = &ListExpr{} }
+     | '[' args ']' { $$ = &ListExpr{Elems: $2} }
+     | '[' expr FOR IDENTIFIER IN expr ']' { $$ = &ComprehensionExpr{Elem: $2, Var: $4, Iter: $6} }
+     | '[' expr FOR IDENTIFIER IN expr IF expr ']' { $$ = &ComprehensionExpr{Elem: $2, Var: $4, Iter: $6, Cond: $8} }
+     | LAMBDA expr %prec LAMBDA { $$ = &LambdaExpr{Params: $1, Body: $2} }
+     | IDENTIFIER { $$ = &Ident{Name: $1} }
+     | IDENTIFIER '=' expr { $$ = &AssignExpr{Name: $1, Value: $3} }
+     ;
+
+args : expr { $$ = []Node{$1} }
+     | args ',' expr { $$ = append($1, $3) }
      ;
 
 %%
@@ -2,6 +2,8 @@ package calc
 
 import (
 	"math"
+	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -32,6 +34,26 @@ func TestInterpreter(t *testing.T) {
 			{"τὰφυσικά = 100; log10(τὰφυσικά)", 2.0},
 			{"-2", -2.0},
 			{"a = -1; a*a", 1.0},
+			// The finance builtins agree with LibreOffice Calc and Excel, whose
+			// formulas follow, to at least 6 decimal places.
+			{"pmt(0.08 / 12, 10, 10000)", -1037.0320893591606},                          // PMT(8%/12, 10, 10000)
+			{"pmt(0.06 / 12, 18 * 12, 0, 50000)", -129.08116086799728},                  // PMT(6%/12, 18*12, 0, 50000)
+			{"pmt(0, 12, 1200)", -100.0},                                                // PMT(0, 12, 1200)
+			{"pmt(0.05 / 12, 360, 200000, 0, 1)", -1069.1882947959598},                  // PMT(5%/12, 360, 200000, 0, 1)
+			{"fv(0.06 / 12, 10, -200, -500, 1)", 2581.4033740601185},                    // FV(6%/12, 10, -200, -500, 1)
+			{"fv(0.12 / 12, 12, -1000)", 12682.503013196976},                            // FV(12%/12, 12, -1000)
+			{"fv(0, 10, -100, -1000)", 2000.0},                                          // FV(0, 10, -100, -1000)
+			{"pv(0.08 / 12, 12 * 20, 500)", -59777.14585118782},                         // PV(8%/12, 12*20, 500)
+			{"pv(0.05, 10, 0, 1000)", -613.9132535407593},                               // PV(5%, 10, 0, 1000)
+			{"nper(0.12 / 12, -100, -1000, 10000, 1)", 59.67386567429457},               // NPER(12%/12, -100, -1000, 10000, 1)
+			{"nper(0.12 / 12, -100, -1000, 10000)", 60.08212285376166},                  // NPER(12%/12, -100, -1000, 10000)
+			{"nper(0, -100, 1000)", 10.0},                                               // NPER(0, -100, 1000)
+			{"npv(0.1, [-10000, 3000, 4200, 6800])", 1188.4434123352216},                // NPV(10%, -10000, 3000, 4200, 6800)
+			{"npv(0.08, [8000, 9200, 10000, 12000, 14500]) - 40000", 1922.061554932363}, // NPV(8%, 8000, 9200, 10000, 12000, 14500) - 40000
+			{"irr([-70000, 12000, 15000, 18000, 21000])", -0.02124484827341105},         // IRR({-70000, 12000, 15000, 18000, 21000})
+			{"irr([-70000, 12000, 15000, 18000, 21000, 26000])", 0.08663094803653161},   // IRR({-70000, 12000, 15000, 18000, 21000, 26000})
+			{"irr([-70000, 12000, 15000], -0.1)", -0.44350694133474056},                 // IRR({-70000, 12000, 15000}, -10%)
+			{"compound(1000, 0.05, 12, 10)", 1647.009497690286},                         // 1000*(1+5%/12)^(12*10)
 		}
 
 		for _, c := range testCases {
@@ -103,6 +125,12 @@ func TestLexer(t *testing.T) {
 			{"á", "á"},
 			{"maçã", "maçã"},
 			{"τὰφυσικά", "τὰφυσικά"},
+			{"lnx", "lnx"},
+			{"log2x", "log2x"},
+			{"log10_b", "log10_b"},
+			{"expand", "expand"},
+			{"power", "power"},
+			{"d20x", "d20x"},
 		}
 
 		for _, c := range testCases {
@@ -191,3 +219,88 @@ func floatEquals(a, b, eps float64) bool {
 
 	return false
 }
+
+// numberRegexp returns the regular expression the lexer used to match numbers
+// written following l, before it was written by hand.
+func numberRegexp(l Locale) *regexp.Regexp {
+	integer := `[0-9]+`
+	if l.Group != 0 && l.Group != ',' && l.Grouping == GroupThousands {
+		integer = `(?:[0-9]{1,3}(?:` + regexp.QuoteMeta(string(l.Group)) + `[0-9]{3})+|[0-9]+)`
+	}
+
+	// This scary-looking regex was taken from
+	// https://golang.org/ref/spec#Floating-point_literals
+	// with the added option to have no decimal point.
+	if l.Decimal == '.' {
+		return regexp.MustCompile(`^(?:` + integer + `\.([0-9]+)?([eE][+-]?[0-9]+)?|` + integer + `([eE][+-]?[0-9]+)|\.[0-9]+([eE][+-]?[0-9]+)?|` + integer + `)`)
+	}
+
+	decimal := regexp.QuoteMeta(string(l.Decimal))
+	return regexp.MustCompile(`^(?:` + integer + decimal + `[0-9]+([eE][+-]?[0-9]+)?|` + integer + `([eE][+-]?[0-9]+)|` + integer + `)`)
+}
+
+func FuzzScanNumber(f *testing.F) {
+	for _, seed := range []string{
+		"1", "100001", "1.5", ".1", "0.", "072.40", "1.e+0", "6.67428e-11",
+		"1E6", ".12345E+5", "1,5", "1.234,5", "1'234.5", "1\u202f234,5",
+		"12,34,567.8", "1.2345", "12.34.567", "2e", "3e+x", "1..2", "pow(1, 2)",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, program string) {
+		for _, locale := range Locales {
+			lexer := newCalcLexer(program)
+			lexer.locale = locale
+			ok := lexer.scanNumber()
+
+			loc := numberRegexp(locale).FindStringIndex(program)
+			if ok != (loc != nil) || ok && lexer.te != loc[1] {
+				t.Fatalf("Lexed %q as a number (%t) ending at %d, but the regexp matched %v in locale %s", program, ok, lexer.te, loc, locale.Name)
+			}
+		}
+	})
+}
+
+const benchmarkProgram = "r = 0.05; n = 12 * 30; p = 250000; pmt(r / 12, n, -p) + log10(1e6) * 3d6 - pow(π, 2)"
+
+func BenchmarkLexer(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		lexer := newCalcLexer(benchmarkProgram)
+		lval := &yySymType{}
+		for lexer.Lex(lval) > 0 {
+		}
+	}
+}
+
+// BenchmarkRegexpLexer tokenizes like the lexer did before it was written by
+// hand, compiling and trying regular expressions in turn for every token, to
+// compare with BenchmarkLexer.
+func BenchmarkRegexpLexer(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		program := benchmarkProgram
+		for program = strings.TrimSpace(program); program != ""; program = strings.TrimSpace(program) {
+			res := []*regexp.Regexp{
+				regexp.MustCompile(`ln`),
+				regexp.MustCompile(`log10`),
+				regexp.MustCompile(`log2`),
+				regexp.MustCompile(`log`),
+				regexp.MustCompile(`exp`),
+				regexp.MustCompile(`pow`),
+				regexp.MustCompile(`<=|>=|[;=,()[\]+/*<>-]`),
+				regexp.MustCompile(`([0-9]+)?d([0-9]+)(k([hl])?([0-9]+))?\b`),
+				regexp.MustCompile(`\pL(\pL|[0-9_])*`),
+				numberRegexp(Plain),
+			}
+
+			end := 1
+			for _, re := range res {
+				if loc := re.FindStringIndex(program); loc != nil && loc[0] == 0 {
+					end = loc[1]
+					break
+				}
+			}
+			program = program[end:]
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package calc
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// Constant is a read-only name an evaluator starts with.
+type Constant struct {
+	Name        string
+	Value       float64
+	Unit        string // SI unit of Value, empty if dimensionless
+	Description string
+}
+
+// DefaultConstants are the mathematical and physical constants evaluators
+// know unless told otherwise. Physical constants are the CODATA 2018 values.
+var DefaultConstants = []Constant{
+	{"pi", math.Pi, "", "ratio of a circle's circumference to its diameter"},
+	{"tau", 2 * math.Pi, "", "ratio of a circle's circumference to its radius"},
+	{"e", math.E, "", "base of the natural logarithm"},
+	{"phi", math.Phi, "", "golden ratio"},
+	{"c", 299792458, "m/s", "speed of light in vacuum"},
+	{"G", 6.67430e-11, "m^3/(kg s^2)", "Newtonian constant of gravitation"},
+	{"g_n", 9.80665, "m/s^2", "standard acceleration of gravity"},
+	{"h", 6.62607015e-34, "J s", "Planck constant"},
+	{"hbar", 1.054571817e-34, "J s", "reduced Planck constant"},
+	{"k_B", 1.380649e-23, "J/K", "Boltzmann constant"},
+	{"N_A", 6.02214076e23, "1/mol", "Avogadro constant"},
+	{"R", 8.314462618, "J/(mol K)", "molar gas constant"},
+	{"q_e", 1.602176634e-19, "C", "elementary charge"},
+	{"m_e", 9.1093837015e-31, "kg", "electron mass"},
+	{"m_p", 1.67262192369e-27, "kg", "proton mass"},
+	{"epsilon_0", 8.8541878128e-12, "F/m", "vacuum electric permittivity"},
+	{"mu_0", 1.25663706212e-6, "N/A^2", "vacuum magnetic permeability"},
+}
+
+// Text is a value that is only meant to be read, like the listing returned
+// by constants().
+type Text string
+
+func (t Text) String() string {
+	return string(t)
+}
+
+// WithConstants makes the evaluator start with consts instead of
+// DefaultConstants.
+func WithConstants(consts []Constant) Option {
+	return func(e *Evaluator) {
+		e.consts = consts
+	}
+}
+
+func (e *Evaluator) constant(name string) (Constant, bool) {
+	for _, c := range e.consts {
+		if c.Name == name {
+			return c, true
+		}
+	}
+
+	return Constant{}, false
+}
+
+// assign binds v to name, unless name is a constant or a previous result.
+func (e *Evaluator) assign(name string, v Value) error {
+	if _, ok := e.constant(name); ok {
+		return fmt.Errorf("%s is a constant and can't be reassigned", name)
+	}
+	if isResult(name) {
+		return fmt.Errorf("%s is a previous result and can't be reassigned", name)
+	}
+
+	e.vars[name] = v
+	return nil
+}
+
+// constants implements the constants() builtin, which lists the constants
+// the evaluator knows.
+func (e *Evaluator) constants(n *CallExpr) (Value, error) {
+	if len(n.Args) != 0 {
+		return nil, fmt.Errorf("constants takes 0 argument(s), got %d", len(n.Args))
+	}
+
+	var b bytes.Buffer
+	for _, c := range e.consts {
+		fmt.Fprintf(&b, "%s = %s", c.Name, formatFloat(c.Value))
+		if c.Unit != "" {
+			fmt.Fprintf(&b, " %s", c.Unit)
+		}
+		fmt.Fprintf(&b, " (%s)\n", c.Description)
+	}
+
+	return Text(b.String()), nil
+}
@@ -0,0 +1,92 @@
+package calc
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestConstants(t *testing.T) {
+	t.Run("Should know the default constants", func(t *testing.T) {
+		testCases := []struct {
+			Input    string
+			Expected float64
+		}{
+			{"pi", math.Pi},
+			{"tau / 2", math.Pi},
+			{"ln(e)", 1},
+			{"phi * phi - phi", 1},
+			{"c", 299792458},
+			{"h / (2 * pi) / hbar", 1},
+		}
+
+		for _, tc := range testCases {
+			actual, err := Evaluate(tc.Input)
+			if err != nil {
+				t.Fatalf("error (%s) not nil for %s", err, tc.Input)
+			}
+			if !floatEquals(actual, tc.Expected, 1e-9) {
+				t.Fatalf("%s: expected %f, got %f", tc.Input, tc.Expected, actual)
+			}
+		}
+	})
+
+	t.Run("Should not reassign constants", func(t *testing.T) {
+		testCases := []string{
+			"pi = 3",
+			"e = 2; e",
+			"simplify(c = x)",
+			"plot(e, e, 0, 1)",
+		}
+
+		for _, tc := range testCases {
+			_, err := NewEvaluator().Evaluate(tc)
+			if err == nil {
+				t.Fatalf("error is nil for %s", tc)
+			}
+		}
+	})
+
+	t.Run("Should keep constants symbolic when simplifying", func(t *testing.T) {
+		v, err := NewEvaluator().Evaluate("simplify(pi + pi)")
+		if err != nil {
+			t.Fatalf("error (%s) not nil", err)
+		}
+		if v.String() != "2 * pi" {
+			t.Fatalf("expected 2 * pi, got %s", v)
+		}
+	})
+
+	t.Run("Should use the constants it is given", func(t *testing.T) {
+		e := NewEvaluator(WithConstants([]Constant{{Name: "answer", Value: 42}}))
+
+		v, err := e.Evaluate("answer; pi = 3; pi")
+		if err != nil {
+			t.Fatalf("error (%s) not nil", err)
+		}
+		if v != Number(3) {
+			t.Fatalf("expected 3, got %s", v)
+		}
+
+		if _, err := e.Evaluate("answer = 1"); err == nil {
+			t.Fatalf("error is nil when reassigning answer")
+		}
+	})
+
+	t.Run("Should list the constants", func(t *testing.T) {
+		v, err := NewEvaluator().Evaluate("constants()")
+		if err != nil {
+			t.Fatalf("error (%s) not nil", err)
+		}
+
+		listing := v.String()
+		for _, line := range []string{
+			"pi = 3.141592653589793 (ratio of a circle's circumference to its diameter)",
+			"c = 2.99792458e+08 m/s (speed of light in vacuum)",
+		} {
+			if !strings.Contains(listing, line) {
+				t.Fatalf("%q not in listing:\n%s", line, listing)
+			}
+		}
+	})
+}
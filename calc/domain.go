@@ -0,0 +1,175 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+)
+
+// DomainPolicy is what the evaluator does when an operator or function is
+// applied outside of its domain, as in 1/0 or ln(-1).
+type DomainPolicy int
+
+const (
+	// DomainErrors makes the evaluation fail with a *DomainError.
+	DomainErrors DomainPolicy = iota
+	// SpecialValues returns the IEEE 754 infinities and NaNs the operation
+	// yields instead, as in 1/0 = +Inf.
+	SpecialValues
+)
+
+// WithDomainPolicy makes the evaluator follow policy instead of DomainErrors.
+func WithDomainPolicy(policy DomainPolicy) Option {
+	return func(e *Evaluator) {
+		e.domain = policy
+	}
+}
+
+// DomainErrorKind tells what is wrong in a DomainError.
+type DomainErrorKind int
+
+const (
+	// DivisionByZero is as in 1/0, 5 % 0, pow(0, -1) or log(1, 2).
+	DivisionByZero DomainErrorKind = iota
+	// NonPositiveLogarithm is as in ln(0) or log(-2, 8).
+	NonPositiveLogarithm
+	// FractionalPowerOfNegative is as in pow(-8, 1/3) or sqrt(-1).
+	FractionalPowerOfNegative
+	// InverseTrigOutOfRange is as in asin(2) or acos(-1.5).
+	InverseTrigOutOfRange
+	// NoRateFound is as in irr([-100, 110], -1), whose search for a rate
+	// doesn't converge.
+	NoRateFound
+)
+
+func (k DomainErrorKind) String() string {
+	switch k {
+	case DivisionByZero:
+		return "Division by zero"
+	case NonPositiveLogarithm:
+		return "Logarithm of a non-positive number"
+	case FractionalPowerOfNegative:
+		return "Fractional power of a negative number"
+	case InverseTrigOutOfRange:
+		return "Inverse sine or cosine of a number outside of [-1, 1]"
+	case NoRateFound:
+		return "No rate found"
+	}
+
+	return fmt.Sprintf("DomainErrorKind(%d)", int(k))
+}
+
+// DomainError is returned when an operator or function is applied outside
+// of its domain, unless the evaluator's policy is SpecialValues.
+type DomainError struct {
+	Kind DomainErrorKind
+	Expr string // the expression that failed, e.g. 1 / x
+	Pos  int    // position of its operator or function, as in BinaryExpr
+}
+
+func (err *DomainError) Error() string {
+	if err.Pos == 0 {
+		return fmt.Sprintf("%s in %s", err.Kind, err.Expr)
+	}
+
+	return fmt.Sprintf("%s in %s at position %d", err.Kind, err.Expr, err.Pos)
+}
+
+// checkDivision fails if n divides by y, or takes the remainder of dividing
+// by y, which is or contains zero, and the policy is DomainErrors.
+func (e *Evaluator) checkDivision(n *BinaryExpr, y Value) error {
+	if n.Op != "/" && n.Op != "%" || e.domain == SpecialValues || !containsZero(y) {
+		return nil
+	}
+
+	return &DomainError{DivisionByZero, n.String(), n.Pos}
+}
+
+// checkBuiltin fails if the arguments of n, args, are outside of the domain
+// of its function and the policy is DomainErrors.
+func (e *Evaluator) checkBuiltin(n *CallExpr, args []float64) error {
+	if e.domain == SpecialValues {
+		return nil
+	}
+	if kind, ok := builtinDomain(n.Func, args); !ok {
+		return &DomainError{kind, n.String(), n.Pos}
+	}
+
+	return nil
+}
+
+// builtinDomain tells if args are in the domain of the builtin fn and, if
+// they aren't, why.
+func builtinDomain(fn string, args []float64) (DomainErrorKind, bool) {
+	switch fn {
+	case "log":
+		if args[0] <= 0 || args[1] <= 0 {
+			return NonPositiveLogarithm, false
+		}
+		if args[0] == 1 {
+			return DivisionByZero, false
+		}
+	case "log10", "log2", "ln":
+		if args[0] <= 0 {
+			return NonPositiveLogarithm, false
+		}
+	case "pow":
+		if args[0] < 0 && args[1] != math.Trunc(args[1]) {
+			return FractionalPowerOfNegative, false
+		}
+		if args[0] == 0 && args[1] < 0 {
+			return DivisionByZero, false
+		}
+	case "sqrt":
+		if args[0] < 0 {
+			return FractionalPowerOfNegative, false
+		}
+	case "asin", "acos":
+		if args[0] < -1 || args[0] > 1 {
+			return InverseTrigOutOfRange, false
+		}
+	default:
+		return financeDomain(fn, args)
+	}
+
+	return 0, true
+}
+
+// financeDomain is builtinDomain for the finance builtins, whose arguments
+// are flattened as in numbers.
+func financeDomain(fn string, args []float64) (DomainErrorKind, bool) {
+	a := append(append([]float64(nil), args...), 0, 0, 0, 0, 0)
+	switch fn {
+	case "pmt", "fv", "pv":
+		// They raise 1 + rate to nper and pmt and pv divide by the result.
+		rate, nper := a[0], a[1]
+		if kind, ok := builtinDomain("pow", []float64{1 + rate, nper}); !ok {
+			return kind, false
+		}
+		if fn == "pmt" && nper == 0 || fn == "pv" && rate == -1 {
+			return DivisionByZero, false
+		}
+	case "nper":
+		rate, pmt, pv, fv := a[0], a[1], a[2], a[3]
+		if a[4] != 0 {
+			pmt *= 1 + rate
+		}
+		switch {
+		case rate == 0 && pmt == 0, rate != 0 && pmt+pv*rate == 0:
+			return DivisionByZero, false
+		case rate != 0 && (rate <= -1 || (pmt-fv*rate)/(pmt+pv*rate) <= 0):
+			return NonPositiveLogarithm, false
+		}
+	case "npv":
+		if a[0] == -1 {
+			return DivisionByZero, false
+		}
+	case "compound":
+		r, n, t := a[1], a[2], a[3]
+		if n == 0 {
+			return DivisionByZero, false
+		}
+		return builtinDomain("pow", []float64{1 + r/n, n * t})
+	}
+
+	return 0, true
+}
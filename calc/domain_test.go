@@ -0,0 +1,120 @@
+package calc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDomain(t *testing.T) {
+	t.Run("Should fail with domain errors pointing at the culprit", func(t *testing.T) {
+		testCases := []struct {
+			Input string
+			Error DomainError
+		}{
+			{"1/0", DomainError{DivisionByZero, "1 / 0", 2}},
+			{"x = 2; 3 + 1 / (x - 2)", DomainError{DivisionByZero, "1 / (x - 2)", 14}},
+			{"99999999999999999999 / 0", DomainError{DivisionByZero, "99999999999999999999 / 0", 22}},
+			{"ln(0)", DomainError{NonPositiveLogarithm, "ln(0)", 1}},
+			{"2 * log(-2, 8)", DomainError{NonPositiveLogarithm, "log(-2, 8)", 5}},
+			{"log(1, 8)", DomainError{DivisionByZero, "log(1, 8)", 1}},
+			{"log10(-1)", DomainError{NonPositiveLogarithm, "log10(-1)", 1}},
+			{"pow(-8, 1/3)", DomainError{FractionalPowerOfNegative, "(-8)^(1 / 3)", 1}},
+			{"pow(0, -1)", DomainError{DivisionByZero, "0^(-1)", 1}},
+			{"1 + sqrt(-1)", DomainError{FractionalPowerOfNegative, "sqrt(-1)", 5}},
+			{"x = -4; 1 + √x", DomainError{FractionalPowerOfNegative, "sqrt(x)", 13}},
+			{"asin(2)", DomainError{InverseTrigOutOfRange, "asin(2)", 1}},
+			{"1 + acos(-1.5)", DomainError{InverseTrigOutOfRange, "acos(-1.5)", 5}},
+			{"nper(0.1, -100, 2000)", DomainError{NonPositiveLogarithm, "nper(0.1, -100, 2000)", 1}},
+			{"nper(0, 0, 1000)", DomainError{DivisionByZero, "nper(0, 0, 1000)", 1}},
+			{"pmt(-2, 0.5, 100)", DomainError{FractionalPowerOfNegative, "pmt(-2, 0.5, 100)", 1}},
+			{"npv(-1, [100, 200])", DomainError{DivisionByZero, "npv(-1, [100, 200])", 1}},
+			{"compound(1000, -3, 1, 0.5)", DomainError{FractionalPowerOfNegative, "compound(1000, -3, 1, 0.5)", 1}},
+			{"irr([-100, 110], -1)", DomainError{NoRateFound, "irr([-100, 110], -1)", 1}},
+		}
+
+		for _, c := range testCases {
+			_, err := NewEvaluator().Evaluate(c.Input)
+			if err, ok := err.(*DomainError); !ok || *err != c.Error {
+				t.Fatalf("error (%v) != %+v in test case %q", err, c.Error, c.Input)
+			}
+		}
+	})
+
+	t.Run("Should evaluate inside the domains", func(t *testing.T) {
+		testCases := []struct {
+			Input string
+			Value float64
+		}{
+			{"0 / 5", 0},
+			{"pow(-2, 3)", -8},
+			{"pow(0, 0)", 1},
+			{"log(2, 8)", 3},
+			{"sqrt(0)", 0},
+			{"asin(1)", math.Pi / 2},
+			{"acos(-1)", math.Pi},
+			{"nper(0.1, -200, 1000)", 7.272540897341713},
+		}
+
+		for _, c := range testCases {
+			result, err := Evaluate(c.Input)
+			if err != nil || !floatEquals(result, c.Value, 1e-12) {
+				t.Fatalf("%f != %f or error (%s) not nil in test case %+v", result, c.Value, err, c)
+			}
+		}
+	})
+
+	t.Run("Should return special values when told to", func(t *testing.T) {
+		testCases := []struct {
+			Input string
+			Value float64
+		}{
+			{"1/0", math.Inf(1)},
+			{"-1/0", math.Inf(-1)},
+			{"ln(0)", math.Inf(-1)},
+			{"0/0", math.NaN()},
+			{"sqrt(-1)", math.NaN()},
+			{"asin(2)", math.NaN()},
+			{"acos(-1.5)", math.NaN()},
+			{"nper(0.1, -100, 2000)", math.NaN()},
+			{"pmt(-2, 0.5, 100)", math.NaN()},
+			{"npv(-1, [100, 200])", math.Inf(1)},
+			{"irr([-100, 110], -1)", math.NaN()},
+		}
+
+		e := NewEvaluator(WithDomainPolicy(SpecialValues))
+		for _, c := range testCases {
+			v, err := e.Evaluate(c.Input)
+			f, _ := v.(Number)
+			if err != nil || !(float64(f) == c.Value || math.IsNaN(float64(f)) && math.IsNaN(c.Value)) {
+				t.Fatalf("%v != %f or error (%s) not nil in test case %+v", v, c.Value, err, c)
+			}
+		}
+	})
+
+	t.Run("Should check domains in compiled programs", func(t *testing.T) {
+		p, err := Compile("a = (1 / x); ln(a)")
+		if err != nil {
+			t.Fatalf("error (%s) not nil", err)
+		}
+
+		if _, err := p.Eval(0); err == nil || err.Error() != "Division by zero in 1 / x at position 8" {
+			t.Fatalf("error (%v) is not the division by zero", err)
+		}
+		if _, err := p.Eval(-1); err == nil || err.Error() != "Logarithm of a non-positive number in ln(a) at position 14" {
+			t.Fatalf("error (%v) is not the logarithm of -1", err)
+		}
+
+		p, _ = NewEvaluator(WithDomainPolicy(SpecialValues)).Compile("1 / x")
+		if v, err := p.Eval(0); err != nil || !math.IsInf(v, 1) {
+			t.Fatalf("%f != +Inf or error (%s) not nil", v, err)
+		}
+	})
+
+	t.Run("Should plot functions with gaps in their domains", func(t *testing.T) {
+		for _, program := range []string{"plot(1/x, x, -1, 1)", "a = 0; plot(ln(x) + a, x, -1, 1)"} {
+			if _, err := NewEvaluator().Evaluate(program); err != nil {
+				t.Fatalf("error (%s) not nil for %q", err, program)
+			}
+		}
+	})
+}
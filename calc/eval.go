@@ -0,0 +1,544 @@
+package calc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Value is the result of evaluating an expression.
+type Value interface {
+	String() string
+}
+
+// Number is a plain floating point value.
+type Number float64
+
+// List is the value of a list expression such as [1, 2, 3].
+type List []Value
+
+// Symbolic is an expression that could not be reduced to a number, such as
+// the result of simplify(x + x).
+type Symbolic struct {
+	Expr Node
+}
+
+func (n Number) String() string {
+	return formatFloat(float64(n))
+}
+
+func (l List) String() string {
+	elems := make([]string, len(l))
+	for i, v := range l {
+		elems[i] = v.String()
+	}
+
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
+func (s Symbolic) String() string {
+	return s.Expr.String()
+}
+
+// Evaluator walks syntax trees and computes their values. Variables assigned
+// while evaluating a program are kept in the evaluator, so an Evaluator may
+// be reused to carry state from one program to the next.
+type Evaluator struct {
+	vars          map[string]Value
+	consts        []Constant
+	integerLimits IntegerLimits
+	limits        Limits
+	implicit      ImplicitProduct
+	locale        Locale
+	domain        DomainPolicy
+	plusMinus     PlusMinus
+
+	ctx        context.Context // of the program being evaluated
+	ops        int             // operations made evaluating it
+	iterations int             // iterations made evaluating it
+	calls      int             // lambda calls being evaluated
+
+	measurements int     // how many uncertain values were measured
+	history      []Value // value of every statement evaluated
+	recalled     bool    // whether a previous result was referred to
+
+	seed  int64
+	rng   *rand.Rand
+	rolls []Roll
+	drew  bool // whether random numbers were drawn
+}
+
+// Option configures an Evaluator.
+type Option func(*Evaluator)
+
+// NewEvaluator returns an evaluator with no variables defined, configured by
+// opts. Unless seeded with WithSeed, its random numbers differ every time.
+func NewEvaluator(opts ...Option) *Evaluator {
+	e := &Evaluator{
+		vars:          make(map[string]Value),
+		consts:        DefaultConstants,
+		integerLimits: DefaultIntegerLimits,
+		limits:        DefaultLimits,
+		locale:        Plain,
+		seed:          time.Now().UnixNano(),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+	e.rng = rand.New(rand.NewSource(e.seed))
+
+	return e
+}
+
+// Evaluate parses program and returns the value of its last statement.
+func (e *Evaluator) Evaluate(program string) (Value, error) {
+	return e.EvaluateContext(context.Background(), program)
+}
+
+// EvaluateContext is like Evaluate, but gives up with the context's error
+// once ctx is done. Programs that exceed the evaluator's Limits fail with
+// one of the errors of limits.go, even if only a statement before the last
+// one makes a value too long to print.
+func (e *Evaluator) EvaluateContext(ctx context.Context, program string) (Value, error) {
+	saved := e.save()
+	w, err := e.worksheet(ctx, program)
+	if err != nil {
+		e.rollback(saved)
+		return nil, err
+	}
+
+	return w.Value(), nil
+}
+
+// eval returns the value of n, counting the operation against the limits.
+func (e *Evaluator) eval(n Node) (Value, error) {
+	if err := e.tick(); err != nil {
+		return nil, err
+	}
+
+	v, err := e.evalNode(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return v, e.checkValue(v)
+}
+
+func (e *Evaluator) evalNode(n Node) (Value, error) {
+	switch n := n.(type) {
+	case *NumberLit:
+		if n.Int != nil {
+			return Integer{n.Int}, nil
+		}
+		return Number(n.Value), nil
+	case *valueNode:
+		return n.value, nil
+	case *Ident:
+		if v, ok, err := e.lookup(n.Name); ok {
+			return v, err
+		}
+		if c, ok := e.constant(n.Name); ok {
+			return Number(c.Value), nil
+		}
+		return Number(0), nil
+	case *UnaryExpr:
+		x, err := e.eval(n.X)
+		if err != nil {
+			return nil, err
+		}
+		return applyUnary(n.Op, x)
+	case *BinaryExpr:
+		x, err := e.eval(n.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := e.eval(n.Y)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.checkDivision(n, y); err != nil {
+			return nil, err
+		}
+		if n.Op == "±" && e.plusMinus == PlusMinusUncertainty {
+			return e.measure(x, y)
+		}
+		return applyBinary(n.Op, x, y)
+	case *CallExpr:
+		return e.call(n)
+	case *DiceExpr:
+		return e.roll(n)
+	case *ListExpr:
+		l := make(List, len(n.Elems))
+		for i, elem := range n.Elems {
+			v, err := e.eval(elem)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = v
+		}
+		return l, nil
+	case *AssignExpr:
+		v, err := e.eval(n.Value)
+		if err != nil {
+			return nil, err
+		}
+		return v, e.assign(n.Name, v)
+	case *LambdaExpr:
+		return Lambda{n}, nil
+	case *ComprehensionExpr:
+		return e.comprehension(n)
+	default:
+		return nil, fmt.Errorf("Unknown node %T", n)
+	}
+}
+
+func applyUnary(op string, x Value) (Value, error) {
+	switch x := x.(type) {
+	case Number:
+		if op == "-" {
+			return -x, nil
+		}
+	case Integer:
+		if op == "-" {
+			return Integer{new(big.Int).Neg(x.Int)}, nil
+		}
+	case Interval:
+		if op == "-" {
+			return Interval{-x.Hi, -x.Lo}, nil
+		}
+	case Uncertain:
+		if op == "-" {
+			return Uncertain{Value: -x.Value}.add(0, x, -1), nil
+		}
+	case Symbolic:
+		return fromNode(Simplify(&UnaryExpr{Op: op, X: x.Expr})), nil
+	}
+
+	return nil, fmt.Errorf("Operator %s is not defined for %s", op, x)
+}
+
+func applyBinary(op string, x, y Value) (Value, error) {
+	for _, v := range []Value{x, y} {
+		if _, ok := v.(List); ok {
+			return nil, fmt.Errorf("Operator %s is not defined for %s", op, v)
+		}
+	}
+
+	if v, ok := integerBinary(op, x, y); ok {
+		return v, nil
+	}
+	x, y = toFloat(x), toFloat(y)
+	if op == "±" || isInterval(x) || isInterval(y) {
+		return intervalBinary(op, x, y)
+	}
+	if isUncertain(x) || isUncertain(y) {
+		return uncertainBinary(op, x, y)
+	}
+
+	a, aok := x.(Number)
+	b, bok := y.(Number)
+	if !aok || !bok {
+		return symbolicBinary(op, x, y)
+	}
+
+	switch op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/":
+		return a / b, nil
+	case "%":
+		return Number(floorMod(float64(a), float64(b))), nil
+	case "<":
+		return truth(a < b), nil
+	case ">":
+		return truth(a > b), nil
+	case "<=":
+		return truth(a <= b), nil
+	case ">=":
+		return truth(a >= b), nil
+	case "==":
+		return truth(a == b), nil
+	case "!=":
+		return truth(a != b), nil
+	}
+
+	return nil, fmt.Errorf("Unknown operator %s", op)
+}
+
+// floorMod returns the remainder of a divided by b, which has the sign of
+// b, so that k % 2 is 0 or 1 even for negative k.
+func floorMod(a, b float64) float64 {
+	m := math.Mod(a, b)
+	if m != 0 && (m < 0) != (b < 0) {
+		m += b
+	}
+
+	return m
+}
+
+// truth returns 1 if b is true and 0 otherwise.
+func truth(b bool) Number {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+func symbolicBinary(op string, x, y Value) (Value, error) {
+	a, err := toNode(x)
+	if err != nil {
+		return nil, err
+	}
+	b, err := toNode(y)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromNode(Simplify(&BinaryExpr{Op: op, X: a, Y: b})), nil
+}
+
+// toNode converts a value back into an expression so it can take part in
+// symbolic computations.
+func toNode(v Value) (Node, error) {
+	switch v := v.(type) {
+	case Number:
+		return &NumberLit{Value: float64(v)}, nil
+	case Integer:
+		return newIntegerLit(v.Int), nil
+	case Symbolic:
+		return v.Expr, nil
+	case List:
+		elems := make([]Node, len(v))
+		for i, elem := range v {
+			n, err := toNode(elem)
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = n
+		}
+		return &ListExpr{Elems: elems}, nil
+	}
+
+	return nil, fmt.Errorf("%s can't be used in an expression", v)
+}
+
+// fromNode returns n as a number if it is a literal and as a Symbolic
+// expression otherwise.
+func fromNode(n Node) Value {
+	if lit, ok := n.(*NumberLit); ok {
+		if lit.Int != nil {
+			return Integer{lit.Int}
+		}
+		return Number(lit.Value)
+	}
+
+	return Symbolic{n}
+}
+
+func (e *Evaluator) call(n *CallExpr) (Value, error) {
+	switch n.Func {
+	case "simplify":
+		return e.simplify(n)
+	case "plot":
+		return e.plot(n)
+	case "constants":
+		return e.constants(n)
+	case "interval":
+		return e.interval(n)
+	}
+
+	if f, ok := e.vars[n.Func].(Lambda); ok {
+		return e.callLambda(n, f)
+	}
+
+	if f, ok := integerBuiltins[n.Func]; ok {
+		return e.callInteger(n, f)
+	}
+	if f, ok := numeralBuiltins[n.Func]; ok {
+		return e.callInteger(n, f)
+	}
+	if f, ok := financeBuiltins[n.Func]; ok {
+		return e.callFinance(n, f)
+	}
+	if f, ok := randomBuiltins[n.Func]; ok {
+		return e.callRandom(n, f)
+	}
+	if f, ok := lookupSequence(n.Func); ok {
+		return e.callSequence(n, f)
+	}
+
+	f, ok := builtins[n.Func]
+	if !ok {
+		return nil, fmt.Errorf("Unknown function %s", n.Func)
+	}
+	if len(n.Args) != f.arity {
+		return nil, fmt.Errorf("%s takes %d argument(s), got %d", n.Func, f.arity, len(n.Args))
+	}
+
+	args := make([]float64, len(n.Args))
+	symbolic, intervals, uncertain := false, false, false
+	values := make([]Value, len(n.Args))
+	for i, arg := range n.Args {
+		v, err := e.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+
+		switch v := v.(type) {
+		case Number:
+			args[i] = float64(v)
+		case Integer:
+			args[i] = v.float()
+		case Symbolic:
+			symbolic = true
+		case Interval:
+			intervals = true
+		case Uncertain:
+			uncertain = true
+		default:
+			return nil, fmt.Errorf("%s can't be passed to %s", v, n.Func)
+		}
+	}
+
+	if n.Func == "pow" {
+		if v, ok, err := e.integerPow(values[0], values[1]); ok {
+			return v, err
+		}
+	}
+	if intervals {
+		return e.callInterval(n, values)
+	}
+	if uncertain {
+		return e.callUncertain(n, values)
+	}
+
+	if symbolic {
+		nodes := make([]Node, len(values))
+		for i, v := range values {
+			nodes[i], _ = toNode(v)
+		}
+		return fromNode(Simplify(&CallExpr{Func: n.Func, Args: nodes})), nil
+	}
+
+	if err := e.checkBuiltin(n, args); err != nil {
+		return nil, err
+	}
+
+	return Number(f.fn(args...)), nil
+}
+
+// simplify implements the simplify(expr) builtin. Variables that are defined
+// are substituted by their values and the rest, constants included, are left
+// as symbols.
+func (e *Evaluator) simplify(n *CallExpr) (Value, error) {
+	if len(n.Args) != 1 {
+		return nil, fmt.Errorf("simplify takes 1 argument(s), got %d", len(n.Args))
+	}
+
+	expr, err := e.substitute(n.Args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return fromNode(Simplify(expr)), nil
+}
+
+// substitute replaces the defined variables in n by their values.
+func (e *Evaluator) substitute(n Node) (Node, error) {
+	switch n := n.(type) {
+	case *Ident:
+		v, ok, err := e.lookup(n.Name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return toNode(v)
+		}
+		return n, nil
+	case *UnaryExpr:
+		x, err := e.substitute(n.X)
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{Op: n.Op, X: x}, nil
+	case *BinaryExpr:
+		x, err := e.substitute(n.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := e.substitute(n.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &BinaryExpr{Op: n.Op, X: x, Y: y, Pos: n.Pos}, nil
+	case *CallExpr:
+		args := make([]Node, len(n.Args))
+		for i, arg := range n.Args {
+			a, err := e.substitute(arg)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = a
+		}
+		return &CallExpr{Func: n.Func, Args: args, Pos: n.Pos}, nil
+	case *ListExpr:
+		elems := make([]Node, len(n.Elems))
+		for i, elem := range n.Elems {
+			a, err := e.substitute(elem)
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = a
+		}
+		return &ListExpr{Elems: elems}, nil
+	case *AssignExpr:
+		// simplify(a = x + x) binds a to the simplified expression.
+		v, err := e.substitute(n.Value)
+		if err != nil {
+			return nil, err
+		}
+		v = Simplify(v)
+		return v, e.assign(n.Name, fromNode(v))
+	}
+
+	return n, nil
+}
+
+// isSpecialForm tells if the arguments of calls to fn are handed to it
+// unevaluated.
+func isSpecialForm(fn string) bool {
+	return fn == "simplify" || fn == "plot"
+}
+
+type builtin struct {
+	arity int
+	fn    func(args ...float64) float64
+}
+
+var builtins = map[string]builtin{
+	"log":   {2, func(a ...float64) float64 { return log(a[0], a[1]) }},
+	"log10": {1, func(a ...float64) float64 { return log(10, a[0]) }},
+	"log2":  {1, func(a ...float64) float64 { return log(2, a[0]) }},
+	"ln":    {1, func(a ...float64) float64 { return log(math.E, a[0]) }},
+	"pow":   {2, func(a ...float64) float64 { return pow(a[0], a[1]) }},
+	"exp":   {1, func(a ...float64) float64 { return exp(a[0]) }},
+	"sqrt":  {1, func(a ...float64) float64 { return math.Sqrt(a[0]) }},
+	"abs":   {1, func(a ...float64) float64 { return math.Abs(a[0]) }},
+	"sin":   {1, func(a ...float64) float64 { return math.Sin(a[0]) }},
+	"cos":   {1, func(a ...float64) float64 { return math.Cos(a[0]) }},
+	"tan":   {1, func(a ...float64) float64 { return math.Tan(a[0]) }},
+	"asin":  {1, func(a ...float64) float64 { return math.Asin(a[0]) }},
+	"acos":  {1, func(a ...float64) float64 { return math.Acos(a[0]) }},
+	"atan":  {1, func(a ...float64) float64 { return math.Atan(a[0]) }},
+}
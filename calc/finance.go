@@ -0,0 +1,309 @@
+package calc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// The time value of money builtins follow the spreadsheet conventions: money
+// paid out is negative and money received is positive, so the payment on a
+// loan of 1000 is negative. The optional type argument tells if payments are
+// due at the end (0, the default) or at the beginning (1) of each period.
+
+// maxSchedulePeriods bounds the length of amortization schedules.
+const maxSchedulePeriods = 1200
+
+type financeBuiltin struct {
+	minArgs, maxArgs int
+	fn               func(args []Value) (Value, error)
+}
+
+var financeBuiltins = map[string]financeBuiltin{
+	"pmt":      {3, 5, tvm(pmt)},
+	"fv":       {3, 5, tvm(fv)},
+	"pv":       {3, 5, tvm(pv)},
+	"nper":     {3, 5, tvm(nper)},
+	"npv":      {2, -1, npv},
+	"irr":      {1, 2, irr},
+	"compound": {4, 4, compound},
+	"amortize": {3, 3, amortize},
+}
+
+// errNoRate is returned by irr if its search for a rate doesn't converge,
+// which is a NoRateFound domain error.
+var errNoRate = errors.New("no rate found, try another guess")
+
+// callFinance evaluates the arguments of n and applies f to them, following
+// the evaluator's domain policy.
+func (e *Evaluator) callFinance(n *CallExpr, f financeBuiltin) (Value, error) {
+	if len(n.Args) < f.minArgs || f.maxArgs >= 0 && len(n.Args) > f.maxArgs {
+		return nil, fmt.Errorf("%s takes %s argument(s), got %d", n.Func, arityRange(f.minArgs, f.maxArgs), len(n.Args))
+	}
+
+	args := make([]Value, len(n.Args))
+	for i, arg := range n.Args {
+		v, err := e.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	v, err := f.fn(args)
+	if err == errNoRate {
+		if e.domain == SpecialValues {
+			return Number(math.NaN()), nil
+		}
+		return nil, &DomainError{NoRateFound, n.String(), n.Pos}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", n.Func, err)
+	}
+
+	if a, err := numbers(args); err == nil {
+		if err := e.checkBuiltin(n, a); err != nil {
+			return nil, err
+		}
+	}
+
+	return v, nil
+}
+
+func arityRange(min, max int) string {
+	switch {
+	case max < 0:
+		return fmt.Sprintf("at least %d", min)
+	case min == max:
+		return fmt.Sprintf("%d", min)
+	}
+
+	return fmt.Sprintf("%d to %d", min, max)
+}
+
+// numbers converts values to floats. Lists are flattened, so that
+// npv(r, [a, b], c) is npv(r, a, b, c) like in spreadsheets.
+func numbers(values []Value) ([]float64, error) {
+	var fs []float64
+	for _, v := range values {
+		switch v := toFloat(v).(type) {
+		case Number:
+			fs = append(fs, float64(v))
+		case List:
+			l, err := numbers(v)
+			if err != nil {
+				return nil, err
+			}
+			fs = append(fs, l...)
+		default:
+			return nil, fmt.Errorf("%s is not a number", v)
+		}
+	}
+
+	return fs, nil
+}
+
+// tvm adapts a time value of money function, which takes a rate, two
+// amounts, an optional amount and an optional payment type, to a builtin.
+func tvm(f func(rate, x, y, z float64, due bool) float64) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		for _, arg := range args {
+			if _, ok := arg.(List); ok {
+				return nil, fmt.Errorf("%s is not a number", arg)
+			}
+		}
+
+		a, err := numbers(args)
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, 0, 0)
+
+		return Number(f(a[0], a[1], a[2], a[3], a[4] != 0)), nil
+	}
+}
+
+// annuity returns the growth factor (1 + rate)^nper and the future value of
+// a payment of 1 per period.
+func annuity(rate, nper float64, due bool) (growth, payments float64) {
+	growth = math.Pow(1+rate, nper)
+	payments = (growth - 1) / rate
+	if due {
+		payments *= 1 + rate
+	}
+
+	return growth, payments
+}
+
+// pmt is the payment per period of a loan of pv paid off in nper periods,
+// leaving fv.
+func pmt(rate, nper, pv, fv float64, due bool) float64 {
+	if rate == 0 {
+		return -(pv + fv) / nper
+	}
+
+	growth, payments := annuity(rate, nper, due)
+	return -(pv*growth + fv) / payments
+}
+
+// fv is the future value of pv after nper periods with pmt paid in each.
+func fv(rate, nper, pmt, pv float64, due bool) float64 {
+	if rate == 0 {
+		return -(pv + pmt*nper)
+	}
+
+	growth, payments := annuity(rate, nper, due)
+	return -(pv*growth + pmt*payments)
+}
+
+// pv is the present value of nper payments of pmt followed by fv.
+func pv(rate, nper, pmt, fv float64, due bool) float64 {
+	if rate == 0 {
+		return -(fv + pmt*nper)
+	}
+
+	growth, payments := annuity(rate, nper, due)
+	return -(fv + pmt*payments) / growth
+}
+
+// nper is how many payments of pmt take pv to fv.
+func nper(rate, pmt, pv, fv float64, due bool) float64 {
+	if rate == 0 {
+		return -(pv + fv) / pmt
+	}
+
+	if due {
+		pmt *= 1 + rate
+	}
+
+	return math.Log((pmt-fv*rate)/(pmt+pv*rate)) / math.Log(1+rate)
+}
+
+// npv implements npv(rate, flows...), the present value of flows received at
+// the end of consecutive periods, the first one a period from now.
+func npv(args []Value) (Value, error) {
+	a, err := numbers(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return Number(presentValue(a[0], a[1:])), nil
+}
+
+func presentValue(rate float64, flows []float64) float64 {
+	v := 0.0
+	for i, f := range flows {
+		v += f / math.Pow(1+rate, float64(i+1))
+	}
+
+	return v
+}
+
+// irr implements irr(flows, guess), the rate at which the flows, the first
+// one made now, have a net present value of zero. It is found with Newton's
+// method starting from guess, 10% if not given.
+func irr(args []Value) (Value, error) {
+	flows, err := numbers(args[:1])
+	if err != nil {
+		return nil, err
+	}
+	guess := 0.1
+	if len(args) == 2 {
+		g, err := numbers(args[1:])
+		if err != nil {
+			return nil, err
+		}
+		guess = g[0]
+	}
+
+	positive, negative := false, false
+	for _, f := range flows {
+		positive = positive || f > 0
+		negative = negative || f < 0
+	}
+	if !positive || !negative {
+		return nil, fmt.Errorf("the flows need at least a positive and a negative value")
+	}
+
+	rate := guess
+	for i := 0; i < 100; i++ {
+		v, dv := 0.0, 0.0
+		for t, f := range flows {
+			v += f / math.Pow(1+rate, float64(t))
+			dv -= float64(t) * f / math.Pow(1+rate, float64(t+1))
+		}
+
+		next := rate - v/dv
+		if math.IsNaN(next) || math.IsInf(next, 0) || next <= -1 {
+			break
+		}
+		if math.Abs(next-rate) < 1e-12 {
+			return Number(next), nil
+		}
+		rate = next
+	}
+
+	return nil, errNoRate
+}
+
+// compound implements compound(p, r, n, t), the amount p grows to in t years
+// at a yearly rate r compounded n times a year.
+func compound(args []Value) (Value, error) {
+	a, err := numbers(args)
+	if err != nil {
+		return nil, err
+	}
+	p, r, n, t := a[0], a[1], a[2], a[3]
+
+	return Number(p * math.Pow(1+r/n, n*t)), nil
+}
+
+// Payment is a row of an amortization schedule. Amounts follow the
+// spreadsheet sign conventions of pmt, ipmt and ppmt.
+type Payment struct {
+	Period    int
+	Payment   float64
+	Interest  float64
+	Principal float64
+	Balance   float64 // owed after the payment
+}
+
+// Schedule is the value of amortize(rate, nper, pv), the payments that pay
+// off a loan of pv in nper periods.
+type Schedule []Payment
+
+func (s Schedule) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%6s %12s %12s %12s %14s\n", "period", "payment", "interest", "principal", "balance")
+	for _, p := range s {
+		fmt.Fprintf(&b, "%6d %12.2f %12.2f %12.2f %14.2f\n", p.Period, p.Payment, p.Interest, p.Principal, p.Balance)
+	}
+
+	return b.String()
+}
+
+// amortize implements amortize(rate, nper, pv).
+func amortize(args []Value) (Value, error) {
+	a, err := numbers(args)
+	if err != nil {
+		return nil, err
+	}
+	rate, n, balance := a[0], a[1], a[2]
+	if n != math.Trunc(n) || n < 1 || n > maxSchedulePeriods {
+		return nil, fmt.Errorf("the number of periods must be a whole number from 1 to %d", maxSchedulePeriods)
+	}
+
+	payment := pmt(rate, n, balance, 0, false)
+	s := make(Schedule, int(n))
+	for i := range s {
+		interest := -balance * rate
+		principal := payment - interest
+		balance += principal
+		s[i] = Payment{i + 1, payment, interest, principal, balance}
+	}
+	// Rounding errors shouldn't leave a tiny balance.
+	s[len(s)-1].Balance = 0
+
+	return s, nil
+}
@@ -0,0 +1,51 @@
+package calc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFinance(t *testing.T) {
+	t.Run("Should build amortization schedules", func(t *testing.T) {
+		v, err := NewEvaluator().Evaluate("amortize(0.1, 3, 1000)")
+		if err != nil {
+			t.Fatalf("error (%s) not nil", err)
+		}
+
+		s := v.(Schedule)
+		expected := Schedule{
+			{1, -402.11, -100, -302.11, 697.89},
+			{2, -402.11, -69.79, -332.33, 365.56},
+			{3, -402.11, -36.56, -365.56, 0},
+		}
+		for i, p := range s {
+			e := expected[i]
+			if p.Period != e.Period || !floatEquals(p.Payment, e.Payment, 0.005) || !floatEquals(p.Interest, e.Interest, 0.005) ||
+				!floatEquals(p.Principal, e.Principal, 0.005) || !floatEquals(p.Balance, e.Balance, 0.005) {
+				t.Fatalf("expected %+v, got %+v", e, p)
+			}
+		}
+
+		if !strings.Contains(s.String(), "     2      -402.11       -69.79      -332.33         365.56") {
+			t.Fatalf("unexpected table:\n%s", s)
+		}
+	})
+
+	t.Run("Should reject invalid arguments", func(t *testing.T) {
+		testCases := []string{
+			"pmt(0.1, 10)",
+			"pmt([0.1], 10, 1000)",
+			"npv(0.1)",
+			"irr([100, 200])",
+			"irr(100)",
+			"amortize(0.1, 2.5, 1000)",
+			"amortize(0.1, 100000, 1000)",
+		}
+
+		for _, tc := range testCases {
+			if _, err := NewEvaluator().Evaluate(tc); err == nil {
+				t.Fatalf("error is nil for %s", tc)
+			}
+		}
+	})
+}
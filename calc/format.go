@@ -0,0 +1,268 @@
+package calc
+
+import (
+	"math"
+	"strings"
+)
+
+// Operator precedences, from loosest to tightest, as declared in calc.y.
+// Note that '=' binds tighter than the arithmetic operators, so a = 1 + 2
+// parses as (a = 1) + 2.
+const (
+	precLambda = iota + 1
+	precCompare
+	precSum
+	precProduct
+	precAssign
+	precPlusMinus
+	precUnary
+	precPower
+	precAtom
+)
+
+// Printer formats syntax trees as text with normalized spacing and only the
+// parenthesis required by the operator precedences.
+type Printer struct {
+	// Unicode makes the printer use math symbols like ×, ÷ and √ and
+	// superscripts for integer powers.
+	Unicode bool
+}
+
+var defaultPrinter = &Printer{}
+
+// Format parses program and prints it back in canonical form, with statements
+// separated by "; ".
+func Format(program string) (string, error) {
+	return defaultPrinter.Format(program)
+}
+
+// Format parses program and prints it back in canonical form, with statements
+// separated by "; ".
+func (p *Printer) Format(program string) (string, error) {
+	stmts, err := Parse(program)
+	if err != nil {
+		return "", err
+	}
+
+	formatted := make([]string, len(stmts))
+	for i, stmt := range stmts {
+		formatted[i] = p.Print(stmt)
+	}
+
+	return strings.Join(formatted, "; "), nil
+}
+
+// Print returns the text of n.
+func (p *Printer) Print(n Node) string {
+	switch n := n.(type) {
+	case *NumberLit:
+		if n.Int != nil {
+			return n.Int.String()
+		}
+		return p.number(n.Value)
+	case *Ident:
+		return n.Name
+	case *UnaryExpr:
+		return p.operator(n.Op) + p.operand(n.X, precUnary+1)
+	case *BinaryExpr:
+		prec := binaryPrecedence(n.Op)
+		left := prec
+		if prec == precCompare {
+			// Comparisons don't associate: 1 < 2 < 3 doesn't parse.
+			left++
+		}
+		return p.operand(n.X, left) + " " + p.operator(n.Op) + " " + p.operand(n.Y, prec+1)
+	case *CallExpr:
+		if s, ok := p.power(n); ok {
+			return s
+		}
+		if n.Func == "pow" && len(n.Args) == 2 {
+			// ^ is right associative: x^y^z is x^(y^z).
+			return p.operand(n.Args[0], precPower+1) + "^" + p.operand(n.Args[1], precPower)
+		}
+		args := make([]string, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = p.Print(arg)
+		}
+		return n.Func + "(" + strings.Join(args, ", ") + ")"
+	case *DiceExpr:
+		return n.notation()
+	case *ListExpr:
+		elems := make([]string, len(n.Elems))
+		for i, elem := range n.Elems {
+			elems[i] = p.Print(elem)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case *AssignExpr:
+		return n.Name + " = " + p.operand(n.Value, precAssign)
+	case *LambdaExpr:
+		params := strings.Join(n.Params, ", ")
+		if len(n.Params) != 1 {
+			params = "(" + params + ")"
+		}
+		return params + " -> " + p.Print(n.Body)
+	case *ComprehensionExpr:
+		s := "[" + p.Print(n.Elem) + " for " + n.Var + " in " + p.Print(n.Iter)
+		if n.Cond != nil {
+			s += " if " + p.Print(n.Cond)
+		}
+		return s + "]"
+	case *valueNode:
+		if s, ok := n.value.(Symbolic); ok {
+			return p.Print(s.Expr)
+		}
+		if f, ok := n.value.(Number); ok {
+			return p.number(float64(f))
+		}
+		return n.value.String()
+	}
+
+	return n.String()
+}
+
+// operand prints n, wrapping it in parenthesis if it binds looser than prec.
+func (p *Printer) operand(n Node, prec int) string {
+	if p.precedence(n) < prec {
+		return "(" + p.Print(n) + ")"
+	}
+
+	return p.Print(n)
+}
+
+func (p *Printer) precedence(n Node) int {
+	switch n := n.(type) {
+	case *NumberLit:
+		if n.Value < 0 {
+			return precUnary
+		}
+	case *UnaryExpr:
+		return precUnary
+	case *BinaryExpr:
+		return binaryPrecedence(n.Op)
+	case *AssignExpr:
+		return precAssign
+	case *LambdaExpr:
+		return precLambda
+	case *CallExpr:
+		if s, ok := p.power(n); ok && strings.HasPrefix(s, "√") {
+			// √x^y reads as √(x^y).
+			return precUnary
+		} else if !ok && n.Func == "pow" && len(n.Args) == 2 {
+			return precPower
+		}
+	case *valueNode:
+		if s, ok := n.value.(Symbolic); ok {
+			return p.precedence(s.Expr)
+		}
+		if f, ok := n.value.(Number); ok && f < 0 {
+			return precUnary
+		}
+		if _, ok := n.value.(Uncertain); ok {
+			return precPlusMinus
+		}
+		if _, ok := n.value.(Lambda); ok {
+			return precLambda
+		}
+		if i, ok := n.value.(Integer); ok && i.Sign() < 0 {
+			return precUnary
+		}
+	}
+
+	return precAtom
+}
+
+func binaryPrecedence(op string) int {
+	switch op {
+	case "*", "/", "%":
+		return precProduct
+	case "<", ">", "<=", ">=", "==", "!=":
+		return precCompare
+	case "±":
+		return precPlusMinus
+	}
+
+	return precSum
+}
+
+func (p *Printer) operator(op string) string {
+	if !p.Unicode {
+		return op
+	}
+
+	switch op {
+	case "*":
+		return "×"
+	case "/":
+		return "÷"
+	case "-":
+		return "−"
+	case "<=":
+		return "≤"
+	case ">=":
+		return "≥"
+	case "!=":
+		return "≠"
+	}
+
+	return op
+}
+
+func (p *Printer) number(f float64) string {
+	s := formatFloat(f)
+	if p.Unicode && f < 0 {
+		return "−" + s[1:]
+	}
+
+	return s
+}
+
+var superscripts = strings.NewReplacer(
+	"-", "⁻", "0", "⁰", "1", "¹", "2", "²", "3", "³", "4", "⁴",
+	"5", "⁵", "6", "⁶", "7", "⁷", "8", "⁸", "9", "⁹",
+)
+
+// power prints sqrt(x) and pow(x, 1/2) as √x and powers to integers using
+// superscripts, if the printer is in Unicode mode.
+func (p *Printer) power(n *CallExpr) (string, bool) {
+	if p.Unicode && n.Func == "sqrt" && len(n.Args) == 1 {
+		return "√" + p.operand(n.Args[0], precAtom), true
+	}
+	if !p.Unicode || n.Func != "pow" || len(n.Args) != 2 {
+		return "", false
+	}
+
+	exp, ok := literalValue(n.Args[1])
+	if !ok {
+		return "", false
+	}
+
+	switch {
+	case exp == 0.5:
+		return "√" + p.operand(n.Args[0], precAtom), true
+	case exp == math.Trunc(exp) && math.Abs(exp) < 1e15:
+		base := p.operand(n.Args[0], precAtom)
+		if call, ok := n.Args[0].(*CallExpr); ok {
+			// Otherwise pow(pow(x, 2), 3) would print as x²³.
+			if _, ok := p.power(call); ok && p.precedence(call) == precAtom {
+				base = "(" + base + ")"
+			}
+		}
+		return base + superscripts.Replace(formatFloat(exp)), true
+	}
+
+	return "", false
+}
+
+// literalValue returns the value of n if it is a number, possibly negated.
+func literalValue(n Node) (float64, bool) {
+	switch n := n.(type) {
+	case *NumberLit:
+		return n.Value, true
+	case *UnaryExpr:
+		if v, ok := literalValue(n.X); ok && n.Op == "-" {
+			return -v, true
+		}
+	}
+
+	return 0, false
+}
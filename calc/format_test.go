@@ -0,0 +1,90 @@
+package calc
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	t.Run("Should normalize spacing and parenthesis", func(t *testing.T) {
+		testCases := []struct {
+			Input     string
+			Formatted string
+		}{
+			{"1+2", "1 + 2"},
+			{"  a=2 ;a+1", "a = 2; a + 1"},
+			{"(1 + 2) * 3", "(1 + 2) * 3"},
+			{"((1 * 2)) + 3", "1 * 2 + 3"},
+			{"1 - (2 - 3)", "1 - (2 - 3)"},
+			{"(1 - 2) - 3", "1 - 2 - 3"},
+			{"1 / (2 * 3)", "1 / (2 * 3)"},
+			{"-(x + 1)", "-(x + 1)"},
+			{"-(-x)", "-(-x)"},
+			{"(-x) * 2", "-x * 2"},
+			{"a = (x + 1)", "a = (x + 1)"},
+			{"(a = x) + 1", "a = x + 1"},
+			{"2 * (a = 3)", "2 * a = 3"},
+			{"log( 2,8 )+ln(x)", "log(2, 8) + ln(x)"},
+			{"pow(x,2)", "x^2"},
+			{"pow(-x, 2) + -x^2", "(-x)^2 + -x^2"},
+			{"pow(pow(x, y), z) * x^y^z", "(x^y)^z * x^y^z"},
+			{"pow(2 * x, 1 / 3)", "(2 * x)^(1 / 3)"},
+			{"npv(0.1,[ 1,(2+3) ])", "npv(0.1, [1, 2 + 3])"},
+			{"[]", "[]"},
+			{"(1 < 2) < 3", "(1 < 2) < 3"},
+			{"1 == (2 != 3)", "1 == (2 != 3)"},
+		}
+
+		for _, c := range testCases {
+			formatted, err := Format(c.Input)
+			if err != nil || formatted != c.Formatted {
+				t.Fatalf("%q != %q or error (%s) not nil in test case %+v", formatted, c.Formatted, err, c)
+			}
+		}
+	})
+
+	t.Run("Should format to programs that evaluate to the same value", func(t *testing.T) {
+		testCases := []string{
+			"1 - (2 - 3) * 4",
+			"a = 2; b = (a + 1); -(a - b) / (a * b)",
+			"x = 3; 2 * (x = 4) + x",
+			"log(2, 8 * (1 + 1)) - -2",
+			"(1 < 2) < 3",
+			"(3 >= 2) == (1 != 1)",
+		}
+
+		for _, c := range testCases {
+			formatted, err := Format(c)
+			if err != nil {
+				t.Fatalf("error (%s) not nil in test case %q", err, c)
+			}
+
+			expected, _ := Evaluate(c)
+			if actual, err := Evaluate(formatted); err != nil || actual != expected {
+				t.Fatalf("%f != %f or error (%s) not nil for %q formatted as %q", actual, expected, err, c, formatted)
+			}
+		}
+	})
+
+	t.Run("Should use math symbols in Unicode mode", func(t *testing.T) {
+		testCases := []struct {
+			Input     string
+			Formatted string
+		}{
+			{"2 * 3 / 4 - 1", "2 × 3 ÷ 4 − 1"},
+			{"-x", "−x"},
+			{"pow(x, 2) + pow(y, -1)", "x² + y⁻¹"},
+			{"pow(x + 1, 10)", "(x + 1)¹⁰"},
+			{"pow(pow(x, 2), 3)", "(x²)³"},
+			{"pow(2, 0.5) + pow(x + 1, 0.5)", "√2 + √(x + 1)"},
+			{"sqrt(x) + sqrt(2 * x)", "√x + √(2 × x)"},
+			{"pow(sqrt(x), 2) + pow(sqrt(x), y)", "(√x)² + (√x)^y"},
+			{"pow(x, 1.5)", "x^1.5"},
+		}
+
+		p := &Printer{Unicode: true}
+		for _, c := range testCases {
+			formatted, err := p.Format(c.Input)
+			if err != nil || formatted != c.Formatted {
+				t.Fatalf("%q != %q or error (%s) not nil in test case %+v", formatted, c.Formatted, err, c)
+			}
+		}
+	})
+}
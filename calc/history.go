@@ -0,0 +1,109 @@
+package calc
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+)
+
+// WithHistory makes results the values of the statements evaluated before,
+// as if they had been evaluated by this evaluator, so that ans and _1, _2
+// and so on refer to them. Bots use it to let a user build on the results of
+// previous messages.
+func WithHistory(results []Value) Option {
+	return func(e *Evaluator) {
+		e.history = append([]Value(nil), results...)
+	}
+}
+
+// History returns the value of every statement the evaluator evaluated, in
+// order, including the ones it was given with WithHistory. Programs that
+// fail leave no values behind, nor any of the variables they assigned.
+func (e *Evaluator) History() []Value {
+	return append([]Value(nil), e.history...)
+}
+
+// UsedHistory tells if the evaluator referred to previous results so far,
+// as ans or _1, so that evaluating the same program after others may give
+// another value.
+func (e *Evaluator) UsedHistory() bool {
+	return e.recalled
+}
+
+// checkpoint is the state of an evaluator a program may change: its
+// results and its variables.
+type checkpoint struct {
+	results int
+	vars    map[string]Value
+}
+
+// save returns the state to roll back to if the program about to be
+// evaluated fails.
+func (e *Evaluator) save() checkpoint {
+	return checkpoint{len(e.history), maps.Clone(e.vars)}
+}
+
+// rollback undoes the results and assignments made since c, those of a
+// program that failed.
+func (e *Evaluator) rollback(c checkpoint) {
+	e.history = e.history[:c.results]
+	clear(e.vars)
+	maps.Copy(e.vars, c.vars)
+}
+
+// result returns the previous result name refers to: ans and _ are the last
+// one, which is 0 before there are any, and _1, _2 and so on are the value
+// of the first statement evaluated, the second one, and so on.
+func (e *Evaluator) result(name string) (Value, bool, error) {
+	if isResult(name) {
+		e.recalled = true
+	}
+
+	if name == "ans" || name == "_" {
+		if len(e.history) == 0 {
+			return Number(0), true, nil
+		}
+		return e.history[len(e.history)-1], true, nil
+	}
+
+	i, ok := resultIndex(name)
+	if !ok {
+		return nil, false, nil
+	}
+	if i > len(e.history) {
+		return nil, true, fmt.Errorf("%s is not defined, there are only %d result(s) so far", name, len(e.history))
+	}
+
+	return e.history[i-1], true, nil
+}
+
+// resultIndex returns n if name is _n, the name of the nth result.
+func resultIndex(name string) (int, bool) {
+	if !strings.HasPrefix(name, "_") || strings.HasPrefix(name, "_0") {
+		return 0, false
+	}
+	i, err := strconv.Atoi(name[1:])
+	if err != nil || i < 1 {
+		return 0, false
+	}
+
+	return i, true
+}
+
+// isResult tells if name refers to a previous result.
+func isResult(name string) bool {
+	_, ok := resultIndex(name)
+	return ok || name == "ans" || name == "_"
+}
+
+// lookup returns the value of the variable or previous result name.
+// Variables come first, so lambda parameters and comprehension variables
+// may be called _ or ans.
+func (e *Evaluator) lookup(name string) (Value, bool, error) {
+	if v, ok := e.vars[name]; ok {
+		return v, true, nil
+	}
+
+	return e.result(name)
+}
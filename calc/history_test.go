@@ -0,0 +1,169 @@
+package calc
+
+import "testing"
+
+func TestHistory(t *testing.T) {
+	t.Run("Should refer to previous results", func(t *testing.T) {
+		testCases := []struct {
+			Input string
+			Value float64
+		}{
+			{"3*4; ans + 1", 13},
+			{"3*4; _ + 1", 13},
+			{"ans", 0},
+			{"2; 3; _1 * _2", 6},
+			{"2; 3; _1 * _2; _3 + ans", 12},
+			{"a = 5; _1 + 1", 6},
+			{"10\nans / 2\nans / 2", 2.5},
+			{"simplify(ans + 1)", 1},
+			{"[_ * 2 for _ in [1, 2]]; reduce((a, b) -> a + b, _1)", 6},
+		}
+
+		for _, c := range testCases {
+			v, err := Evaluate(c.Input)
+			if err != nil || !floatEquals(v, c.Value, 1e-12) {
+				t.Fatalf("%v != %v or error (%s) not nil in test case %+v", v, c.Value, err, c)
+			}
+		}
+	})
+
+	t.Run("Should fail to reassign previous results", func(t *testing.T) {
+		testCases := []string{
+			"ans = 4",
+			"_ = 4",
+			"1; _1 = 2",
+		}
+
+		for _, c := range testCases {
+			if v, err := Evaluate(c); err == nil {
+				t.Fatalf("Expected error, got %v in test case %q", v, c)
+			}
+		}
+	})
+
+	t.Run("Should keep results across programs", func(t *testing.T) {
+		e := NewEvaluator()
+		programs := []struct {
+			Input  string
+			Result string
+		}{
+			{"1 + 1", "2"},
+			{"ans * 10; [1, 2]", "[1, 2]"},
+			{"_1 + _2", "22"},
+			{"_", "22"},
+		}
+
+		for _, p := range programs {
+			v, err := e.Evaluate(p.Input)
+			if err != nil || v.String() != p.Result {
+				t.Fatalf("%v != %v or error (%s) not nil in program %+v", v, p.Result, err, p)
+			}
+		}
+
+		if h := e.History(); len(h) != 5 || h[2].String() != "[1, 2]" {
+			t.Fatalf("Unexpected history %v", h)
+		}
+	})
+
+	t.Run("Should forget the results of failed programs", func(t *testing.T) {
+		e := NewEvaluator()
+		if _, err := e.Evaluate("7; 8; _5"); err == nil {
+			t.Fatalf("Expected error referring to _5")
+		}
+		if h := e.History(); len(h) != 0 {
+			t.Fatalf("Expected no history, got %v", h)
+		}
+	})
+
+	t.Run("Should undo the assignments of failed programs", func(t *testing.T) {
+		failing := []func(e *Evaluator) error{
+			func(e *Evaluator) error {
+				_, err := e.Evaluate("x = 2; y = 3; sqrt(-1)")
+				return err
+			},
+			func(e *Evaluator) error {
+				_, err := e.Worksheet("x = 2; y = 3; sqrt(-1)")
+				return err
+			},
+			func(e *Evaluator) error {
+				_, err := e.Trace("x = 2; y = 3; sqrt(-1)")
+				return err
+			},
+			func(e *Evaluator) error {
+				_, err := e.Worksheet("x = 2; y = range(100); 1")
+				return err
+			},
+		}
+
+		for i, fail := range failing {
+			e := NewEvaluator(WithLimits(Limits{MaxOutputLength: 10}))
+			if _, err := e.Evaluate("x = 1"); err != nil {
+				t.Fatalf("error (%s) not nil", err)
+			}
+			if err := fail(e); err == nil {
+				t.Fatalf("Expected error in test case %d", i)
+			}
+			if v, err := e.Evaluate("[x, y]"); err != nil || v.String() != "[1, 0]" {
+				t.Fatalf("%v != [1, 0] or error (%s) not nil in test case %d", v, err, i)
+			}
+			if h := e.History(); len(h) != 2 {
+				t.Fatalf("Unexpected history %v in test case %d", h, i)
+			}
+		}
+	})
+
+	t.Run("Should start from a given history", func(t *testing.T) {
+		e := NewEvaluator(WithHistory([]Value{Number(6), Number(7)}))
+		v, err := e.Evaluate("_1 * ans")
+		if err != nil || v.String() != "42" {
+			t.Fatalf("%v != 42 or error (%s) not nil", v, err)
+		}
+	})
+
+	t.Run("Should plot previous results", func(t *testing.T) {
+		history := []Value{Number(3), Number(5)}
+		for _, c := range []string{"plot(ans * x, x, 0, 1)", "plot(_2 * x, x, 0, 1)", "plot(_ * x + _1 - 3, x, 0, 1)"} {
+			v, err := NewEvaluator(WithHistory(history)).Evaluate(c)
+			if err != nil {
+				t.Fatalf("error (%s) not nil in test case %q", err, c)
+			}
+			points := v.(*Plot).Series[0].Points
+			if y := points[len(points)-1].Y; !floatEquals(y, 5, 1e-12) {
+				t.Fatalf("%v != 5 in test case %q", y, c)
+			}
+		}
+
+		if _, err := NewEvaluator(WithHistory(history)).Evaluate("plot(_9 * x, x, 0, 1)"); err == nil {
+			t.Fatalf("Expected error plotting _9")
+		}
+	})
+
+	t.Run("Should tell when previous results were used", func(t *testing.T) {
+		testCases := []struct {
+			Input       string
+			UsedHistory bool
+		}{
+			{"ans + 1", true},
+			{"_1 * 2", true},
+			{"plot(_ * x, x, 0, 1)", true},
+			{"ans = 3", false},
+			{"1 + 2", false},
+			{"[_ * 2 for _ in [1, 2]]", false},
+		}
+
+		for _, c := range testCases {
+			e := NewEvaluator(WithHistory([]Value{Number(3)}))
+			e.Evaluate(c.Input)
+			if e.UsedHistory() != c.UsedHistory {
+				t.Fatalf("UsedHistory() != %v for %q", c.UsedHistory, c.Input)
+			}
+		}
+	})
+
+	t.Run("Should trace references to previous results", func(t *testing.T) {
+		trace, err := NewEvaluator().Trace("3 * 4; ans + 1")
+		if err != nil || trace.Statements[1].Value.String() != "13" {
+			t.Fatalf("Unexpected trace %v or error (%s) not nil", trace, err)
+		}
+	})
+}
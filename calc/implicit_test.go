@@ -0,0 +1,123 @@
+package calc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestImplicitProduct(t *testing.T) {
+	t.Run("Should multiply juxtaposed operands", func(t *testing.T) {
+		testCases := []struct {
+			Input    string
+			Expected float64
+		}{
+			{"x = 3; 2x", 6},
+			{"3(4+5)", 27},
+			{"a = 5; b = 2; (a+b)(a-b)", 21},
+			{"r = 2; 2pi r", 4 * math.Pi},
+			{"x = 3; y = 4; x y", 12},
+			{"2ln(e)", 2},
+			{"x = 3; -2x", -6},
+			{"x = 3; a = 2x; a", 6},
+			{"x = 3; 2x + 1", 7},
+			{"x = 3; 2x * 2", 12},
+			{"2e", 2 * math.E},
+			{"2e3", 2000},
+		}
+
+		for _, tc := range testCases {
+			actual, err := Evaluate(tc.Input)
+			if err != nil {
+				t.Fatalf("error (%s) not nil for %s", err, tc.Input)
+			}
+			if !floatEquals(actual, tc.Expected, 1e-9) {
+				t.Fatalf("%s: expected %f, got %f", tc.Input, tc.Expected, actual)
+			}
+		}
+	})
+
+	t.Run("Should keep identifiers with digits and calls apart", func(t *testing.T) {
+		testCases := []struct {
+			Input     string
+			Formatted string
+		}{
+			{"x2", "x2"},
+			{"2x2", "2 * x2"},
+			{"x2y", "x2y"},
+			{"x2 y", "x2 * y"},
+			{"f(x)", "f(x)"},
+			{"f (x)", "f(x)"},
+			{"(f)(x)", "f * x"},
+			{"3d6(2)", "3d6 * 2"},
+			{"2 3", ""},
+			{"x 2", ""},
+		}
+
+		for _, c := range testCases {
+			formatted, err := Format(c.Input)
+			if c.Formatted == "" {
+				if err == nil {
+					t.Fatalf("error is nil for %s", c.Input)
+				}
+				continue
+			}
+			if err != nil || formatted != c.Formatted {
+				t.Fatalf("%q != %q or error (%s) not nil", formatted, c.Formatted, err)
+			}
+		}
+	})
+
+	t.Run("Should follow the chosen convention for 1/2x", func(t *testing.T) {
+		testCases := []struct {
+			Convention ImplicitProduct
+			Input      string
+			Expected   float64
+		}{
+			{ImplicitBindsTighter, "x = 4; 1/2x", 0.125},
+			{ImplicitLikeExplicit, "x = 4; 1/2x", 2},
+			{ImplicitBindsTighter, "x = 4; 1/2 * x", 2},
+			{ImplicitLikeExplicit, "x = 4; 1/2 * x", 2},
+			{ImplicitBindsTighter, "x = 4; 8/2(x)", 1},
+			{ImplicitLikeExplicit, "x = 4; 8/2(x)", 16},
+		}
+
+		for _, tc := range testCases {
+			v, err := NewEvaluator(WithImplicitProduct(tc.Convention)).Evaluate(tc.Input)
+			if err != nil {
+				t.Fatalf("error (%s) not nil for %s", err, tc.Input)
+			}
+			if v != Number(tc.Expected) {
+				t.Fatalf("%s with convention %d: expected %f, got %s", tc.Input, tc.Convention, tc.Expected, v)
+			}
+		}
+
+		formatted, _ := Format("1/2x")
+		if formatted != "1 / (2 * x)" {
+			t.Fatalf("1/2x formatted as %s", formatted)
+		}
+	})
+
+	t.Run("Should insert implicit product tokens", func(t *testing.T) {
+		testCases := []struct {
+			Input       string
+			TokenStream []int
+		}{
+			{"2x", []int{NUMBER, IMPLICIT, IDENTIFIER}},
+			{"2(x)", []int{NUMBER, IMPLICIT, '(', IDENTIFIER, ')'}},
+			{"x(2)", []int{IDENTIFIER, '(', NUMBER, ')'}},
+			{"(1)log(2, 8)", []int{'(', NUMBER, ')', IMPLICIT, LOG, '(', NUMBER, ',', NUMBER, ')'}},
+		}
+
+		for _, c := range testCases {
+			lexer := newCalcLexer(c.Input)
+			lval := &yySymType{}
+
+			for i, expected := range c.TokenStream {
+				actual := lexer.Lex(lval)
+				if actual != expected {
+					t.Fatalf("%s != %s for token number %d in %v", tokname(actual), tokname(expected), i+1, c.Input)
+				}
+			}
+		}
+	})
+}
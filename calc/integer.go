@@ -0,0 +1,614 @@
+package calc
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+)
+
+// Integer is an exact integer, the value of the number theory builtins and
+// of integer literals too large for a float64.
+type Integer struct {
+	*big.Int
+}
+
+func (i Integer) float() float64 {
+	f, _ := new(big.Float).SetInt(i.Int).Float64()
+	return f
+}
+
+// PrimePower is a prime raised to a positive power, a part of a
+// Factorization.
+type PrimePower struct {
+	Prime *big.Int
+	Exp   int
+}
+
+// Factorization is the value of factor(n), the prime powers whose product is
+// n in increasing order of the primes.
+type Factorization []PrimePower
+
+func (f Factorization) String() string {
+	if len(f) == 0 {
+		return "1"
+	}
+
+	var b bytes.Buffer
+	for i, pp := range f {
+		if i > 0 {
+			b.WriteString(" * ")
+		}
+		b.WriteString(pp.Prime.String())
+		if pp.Exp > 1 {
+			fmt.Fprintf(&b, "^%d", pp.Exp)
+		}
+	}
+
+	return b.String()
+}
+
+// IntegerLimits bounds the size of the numbers the number theory builtins
+// work with, so that a single call can't keep the evaluator busy for long.
+type IntegerLimits struct {
+	// MaxDigits is how many decimal digits the arguments and results of
+	// the number theory builtins may have.
+	MaxDigits int
+	// MaxFactorDigits is how many decimal digits the argument of factor
+	// may have. Factoring is much slower than the other builtins.
+	MaxFactorDigits int
+}
+
+// DefaultIntegerLimits are the limits evaluators use unless told otherwise.
+var DefaultIntegerLimits = IntegerLimits{
+	MaxDigits:       500,
+	MaxFactorDigits: 24,
+}
+
+// WithIntegerLimits makes the evaluator enforce limits instead of
+// DefaultIntegerLimits.
+func WithIntegerLimits(limits IntegerLimits) Option {
+	return func(e *Evaluator) {
+		e.integerLimits = limits
+	}
+}
+
+type integerBuiltin struct {
+	arity    int
+	variadic bool // takes arity or more arguments
+	fn       func(e *Evaluator, args []*big.Int) (Value, error)
+}
+
+var integerBuiltins = map[string]integerBuiltin{
+	"gcd":       {2, true, gcd},
+	"lcm":       {2, true, lcm},
+	"isprime":   {1, false, isPrime},
+	"factor":    {1, false, primeFactors},
+	"nextprime": {1, false, nextPrime},
+	"ncr":       {2, false, ncr},
+	"npr":       {2, false, npr},
+	"fib":       {1, false, fib},
+	"modpow":    {3, false, modPow},
+	"modinv":    {2, false, modInv},
+}
+
+// cheapBuiltins are fast on any integer the evaluator's Limits allow, so
+// their arguments aren't held to IntegerLimits.MaxDigits.
+var cheapBuiltins = map[string]bool{
+	"gcd":    true,
+	"modinv": true,
+}
+
+// callInteger evaluates the arguments of n, which must be integers, and
+// applies f to them.
+func (e *Evaluator) callInteger(n *CallExpr, f integerBuiltin) (Value, error) {
+	if f.variadic && len(n.Args) < f.arity {
+		return nil, fmt.Errorf("%s takes at least %d argument(s), got %d", n.Func, f.arity, len(n.Args))
+	}
+	if !f.variadic && len(n.Args) != f.arity {
+		return nil, fmt.Errorf("%s takes %d argument(s), got %d", n.Func, f.arity, len(n.Args))
+	}
+
+	args := make([]*big.Int, len(n.Args))
+	for i, arg := range n.Args {
+		v, err := e.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		x, err := toInt(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s takes exact integers, but %s", n.Func, err)
+		}
+		if cheapBuiltins[n.Func] {
+			args[i] = x
+			continue
+		}
+		if err := e.checkDigits(n.Func, x, e.integerLimits.MaxDigits); err != nil {
+			return nil, err
+		}
+		args[i] = x
+	}
+
+	v, err := f.fn(e, args)
+	if err != nil {
+		return nil, err
+	}
+	if i, ok := v.(Integer); ok && !cheapBuiltins[n.Func] {
+		if err := e.checkDigits(n.Func, i.Int, e.integerLimits.MaxDigits); err != nil {
+			return nil, err
+		}
+	}
+
+	return v, nil
+}
+
+func (e *Evaluator) checkDigits(fn string, x *big.Int, max int) error {
+	if digits(x) > max {
+		return fmt.Errorf("Numbers in %s are limited to %d digits", fn, max)
+	}
+
+	return nil
+}
+
+// digits returns how many decimal digits x has.
+func digits(x *big.Int) int {
+	return len(new(big.Int).Abs(x).Text(10))
+}
+
+// maxExactFloat is the largest magnitude under which every integer is a
+// float64, 2^53. Larger floats may have lost digits to rounding.
+const maxExactFloat = 1 << 53
+
+// toInt returns the exact value of v if it is an integer, failing if it is a
+// float too large to tell which integer it stands for.
+func toInt(v Value) (*big.Int, error) {
+	switch v := v.(type) {
+	case Integer:
+		return v.Int, nil
+	case Number:
+		f := float64(v)
+		if math.IsInf(f, 0) || math.IsNaN(f) || f != math.Trunc(f) {
+			return nil, fmt.Errorf("%s is not an integer", v)
+		}
+		if math.Abs(f) > maxExactFloat {
+			return nil, fmt.Errorf("%s is too large to be exact", v)
+		}
+		i, _ := big.NewFloat(f).Int(nil)
+		return i, nil
+	}
+
+	return nil, fmt.Errorf("%s is not an integer", v)
+}
+
+// toFloat returns v as a Number if it is an Integer, and v itself otherwise.
+func toFloat(v Value) Value {
+	if i, ok := v.(Integer); ok {
+		return Number(i.float())
+	}
+
+	return v
+}
+
+// integerBinary applies op to x and y exactly if both are integral and one
+// of them is an Integer, the result is an integer too. Sums, differences and
+// products of integral numbers are exact as well once they outgrow the
+// floats that could hold them.
+func integerBinary(op string, x, y Value) (Value, bool) {
+	_, xok := x.(Integer)
+	_, yok := y.(Integer)
+	if !xok && !yok && !outgrowsFloat(op, x, y) {
+		return nil, false
+	}
+
+	a, aerr := toInt(x)
+	b, berr := toInt(y)
+	if aerr != nil || berr != nil {
+		return nil, false
+	}
+
+	z := new(big.Int)
+	switch op {
+	case "+":
+		z.Add(a, b)
+	case "-":
+		z.Sub(a, b)
+	case "*":
+		z.Mul(a, b)
+	case "/":
+		var m big.Int
+		if b.Sign() == 0 {
+			return nil, false
+		}
+		if z.QuoRem(a, b, &m); m.Sign() != 0 {
+			return nil, false
+		}
+	case "%":
+		if b.Sign() == 0 {
+			return nil, false
+		}
+		// Like floorMod, the remainder has the sign of b.
+		if z.Rem(a, b); z.Sign() != 0 && z.Sign() != b.Sign() {
+			z.Add(z, b)
+		}
+	case "==":
+		return truth(a.Cmp(b) == 0), true
+	case "!=":
+		return truth(a.Cmp(b) != 0), true
+	case "<":
+		return truth(a.Cmp(b) < 0), true
+	case ">":
+		return truth(a.Cmp(b) > 0), true
+	case "<=":
+		return truth(a.Cmp(b) <= 0), true
+	case ">=":
+		return truth(a.Cmp(b) >= 0), true
+	default:
+		return nil, false
+	}
+
+	return Integer{z}, true
+}
+
+// outgrowsFloat tells if x op y, for numbers x and y, is a sum, difference
+// or product too large to be computed exactly with floats.
+func outgrowsFloat(op string, x, y Value) bool {
+	a, aok := x.(Number)
+	b, bok := y.(Number)
+	if !aok || !bok {
+		return false
+	}
+
+	var r Number
+	switch op {
+	case "+":
+		r = a + b
+	case "-":
+		r = a - b
+	case "*":
+		r = a * b
+	default:
+		return false
+	}
+
+	// Results under 2^53 in magnitude were computed exactly.
+	return math.Abs(float64(r)) >= maxExactFloat
+}
+
+// integerPow returns base^exp exactly if base is integral, exp is a
+// non-negative integer and the power is too large to compute exactly with
+// floats, or an Integer is involved.
+func (e *Evaluator) integerPow(base, exp Value) (Value, bool, error) {
+	b, err := toInt(base)
+	if err != nil {
+		return nil, false, nil
+	}
+	x, err := toInt(exp)
+	if err != nil || x.Sign() < 0 || !x.IsInt64() {
+		return nil, false, nil
+	}
+
+	_, bok := base.(Integer)
+	_, xok := exp.(Integer)
+	if f := math.Pow(Integer{b}.float(), Integer{x}.float()); !bok && !xok && math.Abs(f) < maxExactFloat {
+		return nil, false, nil
+	}
+
+	// Powers far beyond the limit aren't computed: b^x has at least
+	// (len(b) - 1) * x + 1 bits, where len(b) is the bit length of b.
+	if max := int64(e.limits.MaxBits); max > 0 && b.BitLen() > 1 {
+		bits := x.Int64()
+		if bits <= max {
+			bits = int64(b.BitLen()-1)*bits + 1
+		}
+		if bits > max {
+			return nil, true, &NumberTooLargeError{int(bits), int(max)}
+		}
+	}
+
+	return Integer{new(big.Int).Exp(b, x, nil)}, true, nil
+}
+
+// isExactFloat tells if x can be converted to a float64 without losing
+// precision.
+func isExactFloat(x *big.Int) bool {
+	return x.IsInt64() && math.Abs(float64(x.Int64())) <= 1<<53
+}
+
+func newIntegerLit(x *big.Int) *NumberLit {
+	lit := &NumberLit{Value: Integer{x}.float()}
+	if !isExactFloat(x) {
+		lit.Int = x
+	}
+
+	return lit
+}
+
+var (
+	bigOne = big.NewInt(1)
+	bigTwo = big.NewInt(2)
+)
+
+// primeRounds is how many Miller-Rabin rounds primality tests make.
+const primeRounds = 20
+
+func gcd(e *Evaluator, args []*big.Int) (Value, error) {
+	z := new(big.Int).Abs(args[0])
+	for _, x := range args[1:] {
+		z.GCD(nil, nil, z, new(big.Int).Abs(x))
+	}
+
+	return Integer{z}, nil
+}
+
+func lcm(e *Evaluator, args []*big.Int) (Value, error) {
+	z := new(big.Int).Abs(args[0])
+	for _, x := range args[1:] {
+		if z.Sign() == 0 || x.Sign() == 0 {
+			return Integer{new(big.Int)}, nil
+		}
+
+		x = new(big.Int).Abs(x)
+		g := new(big.Int).GCD(nil, nil, z, x)
+		z.Mul(z.Quo(z, g), x)
+		if err := e.checkDigits("lcm", z, e.integerLimits.MaxDigits); err != nil {
+			return nil, err
+		}
+	}
+
+	return Integer{z}, nil
+}
+
+func isPrime(e *Evaluator, args []*big.Int) (Value, error) {
+	if args[0].ProbablyPrime(primeRounds) {
+		return Integer{big.NewInt(1)}, nil
+	}
+
+	return Integer{big.NewInt(0)}, nil
+}
+
+func nextPrime(e *Evaluator, args []*big.Int) (Value, error) {
+	if args[0].Cmp(bigTwo) < 0 {
+		return Integer{big.NewInt(2)}, nil
+	}
+
+	// Candidates are the odd numbers after args[0].
+	p := new(big.Int).Add(args[0], bigOne)
+	if p.Bit(0) == 0 {
+		p.Add(p, bigOne)
+	}
+	for !p.ProbablyPrime(primeRounds) {
+		p.Add(p, bigTwo)
+	}
+
+	return Integer{p}, nil
+}
+
+func primeFactors(e *Evaluator, args []*big.Int) (Value, error) {
+	n := args[0]
+	if n.Sign() <= 0 {
+		return nil, fmt.Errorf("factor takes a positive integer, got %s", n)
+	}
+	if err := e.checkDigits("factor", n, e.integerLimits.MaxFactorDigits); err != nil {
+		return nil, err
+	}
+
+	primes := factorize(new(big.Int).Set(n))
+	sort.Slice(primes, func(i, j int) bool {
+		return primes[i].Cmp(primes[j]) < 0
+	})
+
+	var f Factorization
+	for _, p := range primes {
+		if len(f) > 0 && f[len(f)-1].Prime.Cmp(p) == 0 {
+			f[len(f)-1].Exp++
+		} else {
+			f = append(f, PrimePower{p, 1})
+		}
+	}
+
+	return f, nil
+}
+
+// factorize returns the prime factors of n, repeated as many times as they
+// divide n. Small factors are found by trial division and the rest with
+// Pollard's rho algorithm.
+func factorize(n *big.Int) []*big.Int {
+	var primes []*big.Int
+
+	var m big.Int
+	for d := int64(2); d < 1000; d++ {
+		bd := big.NewInt(d)
+		for n.Cmp(bd) >= 0 {
+			if m.Mod(n, bd); m.Sign() != 0 {
+				break
+			}
+			n.Quo(n, bd)
+			primes = append(primes, bd)
+		}
+	}
+
+	return append(primes, factorizeLarge(n)...)
+}
+
+// factorizeLarge factorizes n, which has no factors under 1000.
+func factorizeLarge(n *big.Int) []*big.Int {
+	if n.Cmp(bigOne) == 0 {
+		return nil
+	}
+	if n.ProbablyPrime(primeRounds) {
+		return []*big.Int{n}
+	}
+
+	d := rho(n)
+	return append(factorizeLarge(d), factorizeLarge(new(big.Int).Quo(n, d))...)
+}
+
+// rho returns a nontrivial divisor of the composite n.
+func rho(n *big.Int) *big.Int {
+	const batch = 100
+
+	for c := int64(1); ; c++ {
+		bc := big.NewInt(c)
+		next := func(z *big.Int) {
+			z.Mul(z, z)
+			z.Add(z, bc)
+			z.Mod(z, n)
+		}
+
+		x, y := big.NewInt(2), big.NewInt(2)
+		d := big.NewInt(1)
+		diff := new(big.Int)
+		for d.Cmp(bigOne) == 0 {
+			// The differences are multiplied together so that a gcd is
+			// only computed once per batch.
+			x0, y0 := new(big.Int).Set(x), new(big.Int).Set(y)
+			q := big.NewInt(1)
+			for i := 0; i < batch; i++ {
+				next(x)
+				next(y)
+				next(y)
+				q.Mul(q, diff.Sub(x, y))
+				q.Mod(q, n)
+			}
+			d.GCD(nil, nil, q.Abs(q), n)
+
+			if d.Cmp(n) == 0 {
+				// The batch overshot, repeat it one step at a time.
+				x, y = x0, y0
+				for d.Cmp(bigOne) == 0 {
+					next(x)
+					next(y)
+					next(y)
+					d.GCD(nil, nil, diff.Abs(diff.Sub(x, y)), n)
+				}
+			}
+		}
+
+		if d.Cmp(n) != 0 {
+			return d
+		}
+	}
+}
+
+// smallArgs returns args as ints, failing if any is negative or huge.
+func smallArgs(fn string, args []*big.Int) ([]int64, error) {
+	small := make([]int64, len(args))
+	for i, x := range args {
+		if x.Sign() < 0 || !x.IsInt64() {
+			return nil, fmt.Errorf("%s takes non-negative integers that fit in 64 bits, got %s", fn, x)
+		}
+		small[i] = x.Int64()
+	}
+
+	return small, nil
+}
+
+// factorialDigits returns about how many decimal digits n! has.
+func factorialDigits(n int64) float64 {
+	lg, _ := math.Lgamma(float64(n) + 1)
+	return lg / math.Ln10
+}
+
+func (e *Evaluator) checkEstimate(fn string, estimate float64) error {
+	if estimate > float64(e.integerLimits.MaxDigits) {
+		return fmt.Errorf("Numbers in %s are limited to %d digits", fn, e.integerLimits.MaxDigits)
+	}
+
+	return nil
+}
+
+func ncr(e *Evaluator, args []*big.Int) (Value, error) {
+	a, err := smallArgs("ncr", args)
+	if err != nil {
+		return nil, err
+	}
+	n, k := a[0], a[1]
+	if k > n {
+		return Integer{new(big.Int)}, nil
+	}
+
+	if err := e.checkEstimate("ncr", factorialDigits(n)-factorialDigits(k)-factorialDigits(n-k)); err != nil {
+		return nil, err
+	}
+
+	return Integer{new(big.Int).Binomial(n, k)}, nil
+}
+
+func npr(e *Evaluator, args []*big.Int) (Value, error) {
+	a, err := smallArgs("npr", args)
+	if err != nil {
+		return nil, err
+	}
+	n, k := a[0], a[1]
+	if k > n {
+		return Integer{new(big.Int)}, nil
+	}
+
+	if err := e.checkEstimate("npr", factorialDigits(n)-factorialDigits(n-k)); err != nil {
+		return nil, err
+	}
+
+	return Integer{new(big.Int).MulRange(n-k+1, n)}, nil
+}
+
+func fib(e *Evaluator, args []*big.Int) (Value, error) {
+	a, err := smallArgs("fib", args)
+	if err != nil {
+		return nil, err
+	}
+	n := a[0]
+
+	if err := e.checkEstimate("fib", float64(n)*math.Log10(math.Phi)); err != nil {
+		return nil, err
+	}
+
+	// Fast doubling: F(2k) = F(k) (2 F(k+1) - F(k)) and
+	// F(2k+1) = F(k)^2 + F(k+1)^2.
+	f0, f1 := big.NewInt(0), big.NewInt(1)
+	for i := 62; i >= 0; i-- {
+		t := new(big.Int).Lsh(f1, 1)
+		t.Sub(t, f0).Mul(t, f0)
+		u := new(big.Int).Mul(f0, f0)
+		u.Add(u, new(big.Int).Mul(f1, f1))
+		f0, f1 = t, u
+
+		if n>>uint(i)&1 == 1 {
+			f0, f1 = f1, f0.Add(f0, f1)
+		}
+	}
+
+	return Integer{f0}, nil
+}
+
+func modPow(e *Evaluator, args []*big.Int) (Value, error) {
+	b, x, m := args[0], args[1], args[2]
+	if m.Sign() <= 0 {
+		return nil, fmt.Errorf("modpow takes a positive modulus, got %s", m)
+	}
+
+	if x.Sign() < 0 {
+		inv := new(big.Int).ModInverse(new(big.Int).Mod(b, m), m)
+		if inv == nil {
+			return nil, fmt.Errorf("%s has no inverse modulo %s", b, m)
+		}
+		b, x = inv, new(big.Int).Neg(x)
+	}
+
+	return Integer{new(big.Int).Exp(b, x, m)}, nil
+}
+
+func modInv(e *Evaluator, args []*big.Int) (Value, error) {
+	a, m := args[0], args[1]
+	if m.Sign() <= 0 {
+		return nil, fmt.Errorf("modinv takes a positive modulus, got %s", m)
+	}
+
+	inv := new(big.Int).ModInverse(new(big.Int).Mod(a, m), m)
+	if inv == nil {
+		return nil, fmt.Errorf("%s has no inverse modulo %s", a, m)
+	}
+
+	return Integer{inv}, nil
+}
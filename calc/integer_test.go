@@ -0,0 +1,170 @@
+package calc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIntegers(t *testing.T) {
+	t.Run("Should compute number theory builtins exactly", func(t *testing.T) {
+		testCases := []struct {
+			Input    string
+			Expected string
+		}{
+			{"gcd(12, 18)", "6"},
+			{"gcd(12, 18, 8)", "2"},
+			{"gcd(-4, 6)", "2"},
+			{"lcm(4, 6)", "12"},
+			{"lcm(4, 0)", "0"},
+			{"isprime(97)", "1"},
+			{"isprime(1)", "0"},
+			{"isprime(2305843009213693951)", "1"},
+			{"factor(24)", "2^3 * 3"},
+			{"factor(1)", "1"},
+			{"factor(97)", "97"},
+			{"factor(600851475143)", "71 * 839 * 1471 * 6857"},
+			{"factor(1000000016000000063)", "1000000007 * 1000000009"},
+			{"nextprime(100)", "101"},
+			{"nextprime(-5)", "2"},
+			{"ncr(5, 2)", "10"},
+			{"ncr(2, 5)", "0"},
+			{"ncr(100, 50)", "100891344545564193334812497256"},
+			{"npr(5, 2)", "20"},
+			{"fib(0)", "0"},
+			{"fib(10)", "55"},
+			{"fib(100)", "354224848179261915075"},
+			{"modpow(2, 10, 1000)", "24"},
+			{"modpow(3, -1, 7)", "5"},
+			{"modinv(3, 7)", "5"},
+		}
+
+		for _, tc := range testCases {
+			v, err := NewEvaluator().Evaluate(tc.Input)
+			if err != nil {
+				t.Fatalf("error (%s) not nil for %s", err, tc.Input)
+			}
+			if v.String() != tc.Expected {
+				t.Fatalf("%s: expected %s, got %s", tc.Input, tc.Expected, v)
+			}
+		}
+	})
+
+	t.Run("Should keep large integers exact", func(t *testing.T) {
+		testCases := []struct {
+			Input    string
+			Expected string
+		}{
+			{"12345678901234567890 + 1", "12345678901234567891"},
+			{"fib(100) - fib(99) - fib(98)", "0"},
+			{"ncr(100, 50) / 2", "50445672272782096667406248628"},
+			{"-fib(90)", "-2880067194370816120"},
+			{"999999999989 * 999999999959", "999999999948000000000451"},
+			{"factor(999999999989 * 999999999959)", "999999999959 * 999999999989"},
+			{"9007199254740992 + 1", "9007199254740993"},
+			{"-9007199254740992 - 3", "-9007199254740995"},
+			{"2^64", "18446744073709551616"},
+			{"3^40 - 1", "12157665459056928800"},
+			{"(-3)^41", "-36472996377170786403"},
+			{"gcd(2^1000, 6)", "2"},
+			{"gcd(2^1000 + 1, 2^1000 - 1)", "1"},
+			{"gcd(2^8000, 3)", "1"},
+			{"modinv(2, 2^8000 + 1) == 2^7999 + 1", "1"},
+			{"2^64 < 2^64 + 1", "1"},
+			{"100000000000000000000 < 100000000000000000001", "1"},
+			{"100000000000000000001 > 100000000000000000000", "1"},
+			{"2^60 + 1 <= 2^60", "0"},
+			{"2^60 + 1 >= 2^60 + 1", "1"},
+			{"-(2^70) > -(2^70) - 1", "1"},
+			{"nextprime(10^499) - 10^499", "153"},
+			{"2^10", "1024"},
+			{"2^0.5 * 2^0.5 < 2.01", "1"},
+			{"simplify(12345678901234567890 + 0)", "12345678901234567890"},
+			{"simplify(12345678901234567890 == 12345678901234567891)", "0"},
+		}
+
+		for _, tc := range testCases {
+			v, err := NewEvaluator().Evaluate(tc.Input)
+			if err != nil {
+				t.Fatalf("error (%s) not nil for %s", err, tc.Input)
+			}
+			if v.String() != tc.Expected {
+				t.Fatalf("%s: expected %s, got %s", tc.Input, tc.Expected, v)
+			}
+		}
+
+		formatted, err := Format("12345678901234567890 * x")
+		if err != nil || formatted != "12345678901234567890 * x" {
+			t.Fatalf("formatted as %s, error %v", formatted, err)
+		}
+
+		stmts, err := Parse("12345678901234567891 + x")
+		if err != nil {
+			t.Fatalf("error (%s) not nil", err)
+		}
+		if latex := ToLaTeX(stmts[0]); latex != "12345678901234567891 + x" {
+			t.Fatalf("LaTeX is %s", latex)
+		}
+		if mathml := ToMathML(stmts[0]); !strings.Contains(mathml, "<mn>12345678901234567891</mn>") {
+			t.Fatalf("MathML is %s", mathml)
+		}
+		if simplified := Simplify(stmts[0]).String(); simplified != "12345678901234567891 + x" && simplified != "x + 12345678901234567891" {
+			t.Fatalf("simplified to %s", simplified)
+		}
+	})
+
+	t.Run("Should mix integers with floats", func(t *testing.T) {
+		actual, err := Evaluate("gcd(12, 18) / 4 + 0.5")
+		if err != nil {
+			t.Fatalf("error (%s) not nil", err)
+		}
+		if !floatEquals(actual, 2, 1e-12) {
+			t.Fatalf("expected 2, got %f", actual)
+		}
+	})
+
+	t.Run("Should reject invalid arguments", func(t *testing.T) {
+		testCases := []string{
+			"gcd(1.5, 2)",
+			"gcd(3)",
+			"factor(0)",
+			"factor(x)",
+			"ncr(-1, 2)",
+			"modinv(2, 4)",
+			"modpow(2, 3, 0)",
+			"factor(1000000000000000000000000000061)",
+			"factor(1e20)",
+			"gcd(2^70 * 1.5, 3)",
+			"2^100000",
+			"isprime(2^8000 + 1)",
+		}
+
+		for _, tc := range testCases {
+			if _, err := NewEvaluator().Evaluate(tc); err == nil {
+				t.Fatalf("error is nil for %s", tc)
+			}
+		}
+	})
+
+	t.Run("Should enforce the configured limits", func(t *testing.T) {
+		e := NewEvaluator(WithIntegerLimits(IntegerLimits{MaxDigits: 10, MaxFactorDigits: 5}))
+
+		testCases := []string{
+			"fib(100)",
+			"ncr(100, 50)",
+			"npr(100, 50)",
+			"nextprime(123456789012)",
+			"factor(1234567)",
+			"lcm(100003, 100019, 100043)",
+		}
+
+		for _, tc := range testCases {
+			if _, err := e.Evaluate(tc); err == nil {
+				t.Fatalf("error is nil for %s", tc)
+			}
+		}
+
+		if _, err := e.Evaluate("factor(12345)"); err != nil {
+			t.Fatalf("error (%s) not nil for factor(12345)", err)
+		}
+	})
+}
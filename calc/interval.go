@@ -0,0 +1,460 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Interval is a closed range of numbers, the value of 2 ± 0.1 or of
+// interval(1.9, 2.1). Operators and builtins applied to intervals return
+// intervals that contain every result of applying them to numbers within
+// their operands. Bounds are rounded outwards: exactly for arithmetic and
+// square roots, whose rounding errors can be computed, and by two units in
+// the last place for the functions the math package only approximates.
+type Interval struct {
+	Lo, Hi float64
+}
+
+// String writes i as the interval call that evaluates to it, since [lo, hi]
+// would be a list.
+func (i Interval) String() string {
+	return "interval(" + formatFloat(i.Lo) + ", " + formatFloat(i.Hi) + ")"
+}
+
+// contains tells if x is within i.
+func (i Interval) contains(x float64) bool {
+	return i.Lo <= x && x <= i.Hi
+}
+
+// isPoint tells if i holds a single number.
+func (i Interval) isPoint() bool {
+	return i.Lo == i.Hi
+}
+
+// mag returns the largest absolute value within i.
+func (i Interval) mag() float64 {
+	return math.Max(math.Abs(i.Lo), math.Abs(i.Hi))
+}
+
+var (
+	// entire is the interval of every number.
+	entire = Interval{math.Inf(-1), math.Inf(1)}
+	// undefinedInterval is the value of functions applied outside of their
+	// domain, if the evaluator's policy is SpecialValues.
+	undefinedInterval = Interval{math.NaN(), math.NaN()}
+)
+
+// interval implements the interval(lo, hi) builtin.
+func (e *Evaluator) interval(n *CallExpr) (Value, error) {
+	if len(n.Args) != 2 {
+		return nil, fmt.Errorf("interval takes 2 argument(s), got %d", len(n.Args))
+	}
+
+	var bounds [2]Interval
+	for i, arg := range n.Args {
+		v, err := e.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		b, ok := toInterval(v)
+		if !ok {
+			return nil, fmt.Errorf("%s can't be passed to interval", v)
+		}
+		bounds[i] = b
+	}
+
+	if !(bounds[0].Lo <= bounds[1].Hi) {
+		return nil, fmt.Errorf("interval(%s, %s) is empty", formatFloat(bounds[0].Lo), formatFloat(bounds[1].Hi))
+	}
+
+	return Interval{bounds[0].Lo, bounds[1].Hi}, nil
+}
+
+// toInterval converts numbers to the narrowest intervals containing them.
+// Integers a float64 can't hold exactly lie between two bounds.
+func toInterval(v Value) (Interval, bool) {
+	switch v := v.(type) {
+	case Number:
+		return Interval{float64(v), float64(v)}, true
+	case Integer:
+		f, acc := new(big.Float).SetInt(v.Int).Float64()
+		switch acc {
+		case big.Below:
+			return Interval{f, math.Nextafter(f, math.Inf(1))}, true
+		case big.Above:
+			return Interval{math.Nextafter(f, math.Inf(-1)), f}, true
+		}
+		return Interval{f, f}, true
+	case Interval:
+		return v, true
+	}
+
+	return Interval{}, false
+}
+
+func isInterval(v Value) bool {
+	_, ok := v.(Interval)
+	return ok
+}
+
+// containsZero tells if dividing by v would be dividing by zero.
+func containsZero(v Value) bool {
+	switch v := v.(type) {
+	case Interval:
+		return v.contains(0)
+	case Uncertain:
+		return v.Value == 0
+	}
+
+	return toFloat(v) == Number(0)
+}
+
+// intervalBinary applies op to x and y, one of which is an interval, or
+// makes an interval out of them if op is ±.
+func intervalBinary(op string, x, y Value) (Value, error) {
+	a, aok := toInterval(x)
+	b, bok := toInterval(y)
+	if !aok || !bok {
+		v := x
+		if aok {
+			v = y
+		}
+		return nil, fmt.Errorf("Operator %s is not defined for %s", op, v)
+	}
+
+	switch op {
+	case "±":
+		r := b.mag()
+		return Interval{subDown(a.Lo, r), addUp(a.Hi, r)}, nil
+	case "+":
+		return Interval{addDown(a.Lo, b.Lo), addUp(a.Hi, b.Hi)}, nil
+	case "-":
+		return Interval{subDown(a.Lo, b.Hi), subUp(a.Hi, b.Lo)}, nil
+	case "*":
+		return mulInterval(a, b), nil
+	case "/":
+		return divInterval(a, b), nil
+	case "%":
+		return modInterval(a, b), nil
+	case "<":
+		return certainly(a.Hi < b.Lo, a.Lo >= b.Hi), nil
+	case ">":
+		return certainly(a.Lo > b.Hi, a.Hi <= b.Lo), nil
+	case "<=":
+		return certainly(a.Hi <= b.Lo, a.Lo > b.Hi), nil
+	case ">=":
+		return certainly(a.Lo >= b.Hi, a.Hi < b.Lo), nil
+	case "==":
+		return certainly(a.isPoint() && a == b, a.Hi < b.Lo || b.Hi < a.Lo), nil
+	case "!=":
+		return certainly(a.Hi < b.Lo || b.Hi < a.Lo, a.isPoint() && a == b), nil
+	}
+
+	return nil, fmt.Errorf("Unknown operator %s", op)
+}
+
+// certainly returns 1 if a comparison holds for every pair of numbers in
+// the intervals compared, 0 if it holds for none and [0, 1] otherwise.
+func certainly(always, never bool) Value {
+	switch {
+	case always:
+		return Number(1)
+	case never:
+		return Number(0)
+	}
+
+	return Interval{0, 1}
+}
+
+func mulInterval(a, b Interval) Interval {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, x := range []float64{a.Lo, a.Hi} {
+		for _, y := range []float64{b.Lo, b.Hi} {
+			lo = math.Min(lo, mulDown(x, y))
+			hi = math.Max(hi, mulUp(x, y))
+		}
+	}
+
+	return Interval{lo, hi}
+}
+
+// divInterval divides a by b, which contains every number if b contains 0.
+func divInterval(a, b Interval) Interval {
+	if b.contains(0) {
+		return entire
+	}
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, x := range []float64{a.Lo, a.Hi} {
+		for _, y := range []float64{b.Lo, b.Hi} {
+			lo = math.Min(lo, divDown(x, y))
+			hi = math.Max(hi, divUp(x, y))
+		}
+	}
+
+	return Interval{lo, hi}
+}
+
+// modInterval returns the remainders of dividing a by b, which have the sign
+// of b as those of numbers do. They are a - k * b if the quotients are
+// between k and k + 1 throughout, and between 0 and b otherwise.
+func modInterval(a, b Interval) Interval {
+	if b.contains(0) {
+		return undefinedInterval
+	}
+
+	bound := Interval{0, b.Hi}
+	if b.Hi < 0 {
+		bound = Interval{b.Lo, 0}
+	}
+
+	q := divInterval(a, b)
+	k := math.Floor(q.Lo)
+	if math.Floor(q.Hi) != k || math.IsInf(k, 0) {
+		return bound
+	}
+
+	kb := mulInterval(Interval{k, k}, b)
+	return Interval{math.Max(subDown(a.Lo, kb.Hi), bound.Lo), math.Min(subUp(a.Hi, kb.Lo), bound.Hi)}
+}
+
+// down and up round x, the result of an operation whose exact result is
+// x + err, towards minus and plus infinity.
+func down(x, err float64) float64 {
+	if err < 0 {
+		return math.Nextafter(x, math.Inf(-1))
+	}
+
+	return x
+}
+
+func up(x, err float64) float64 {
+	if err > 0 {
+		return math.Nextafter(x, math.Inf(1))
+	}
+
+	return x
+}
+
+// twoSum returns a + b and its rounding error.
+func twoSum(a, b float64) (float64, float64) {
+	s := a + b
+	bb := s - a
+	return s, (a - (s - bb)) + (b - bb)
+}
+
+func addDown(a, b float64) float64 { return down(twoSum(a, b)) }
+func addUp(a, b float64) float64   { return up(twoSum(a, b)) }
+func subDown(a, b float64) float64 { return down(twoSum(a, -b)) }
+func subUp(a, b float64) float64   { return up(twoSum(a, -b)) }
+
+// twoProduct returns a * b and its rounding error. Zero times infinity is
+// zero, as the bound of a product.
+func twoProduct(a, b float64) (float64, float64) {
+	if a == 0 || b == 0 {
+		return 0, 0
+	}
+
+	p := a * b
+	return p, math.FMA(a, b, -p)
+}
+
+func mulDown(a, b float64) float64 { return down(twoProduct(a, b)) }
+func mulUp(a, b float64) float64   { return up(twoProduct(a, b)) }
+
+// quotient returns a / b and the sign of its rounding error.
+func quotient(a, b float64) (float64, float64) {
+	q := a / b
+	r := math.FMA(-q, b, a)
+	if b < 0 {
+		r = -r
+	}
+
+	return q, r
+}
+
+func divDown(a, b float64) float64 { return down(quotient(a, b)) }
+func divUp(a, b float64) float64   { return up(quotient(a, b)) }
+
+// widen returns [lo, hi] with both bounds moved two units in the last
+// place outwards, to contain the exact results of approximate functions.
+func widen(lo, hi float64) Interval {
+	for i := 0; i < 2; i++ {
+		lo, hi = math.Nextafter(lo, math.Inf(-1)), math.Nextafter(hi, math.Inf(1))
+	}
+
+	return Interval{lo, hi}
+}
+
+// callInterval applies the builtin of n to args, one or more of which are
+// intervals.
+func (e *Evaluator) callInterval(n *CallExpr, args []Value) (Value, error) {
+	f, ok := intervalBuiltins[n.Func]
+	if !ok {
+		return nil, fmt.Errorf("%s can't be applied to intervals", n.Func)
+	}
+
+	intervals := make([]Interval, len(args))
+	for i, arg := range args {
+		if intervals[i], ok = toInterval(arg); !ok {
+			return nil, fmt.Errorf("%s can't be passed to %s", arg, n.Func)
+		}
+	}
+
+	if kind, ok := intervalDomain(n.Func, intervals); !ok {
+		if e.domain == SpecialValues {
+			return undefinedInterval, nil
+		}
+		return nil, &DomainError{kind, n.String(), n.Pos}
+	}
+
+	return f(intervals), nil
+}
+
+// intervalDomain is builtinDomain for intervals: args are outside of the
+// domain of fn if any of the numbers within them is.
+func intervalDomain(fn string, args []Interval) (DomainErrorKind, bool) {
+	switch fn {
+	case "log":
+		if args[0].Lo <= 0 || args[1].Lo <= 0 {
+			return NonPositiveLogarithm, false
+		}
+		if args[0].contains(1) {
+			return DivisionByZero, false
+		}
+	case "log10", "log2", "ln":
+		if args[0].Lo <= 0 {
+			return NonPositiveLogarithm, false
+		}
+	case "pow":
+		x, y := args[0], args[1]
+		integral := y.isPoint() && y.Lo == math.Trunc(y.Lo)
+		if x.Lo < 0 && !integral {
+			return FractionalPowerOfNegative, false
+		}
+		if x.contains(0) && y.Lo < 0 {
+			return DivisionByZero, false
+		}
+	case "sqrt":
+		if args[0].Lo < 0 {
+			return FractionalPowerOfNegative, false
+		}
+	case "asin", "acos":
+		if args[0].Lo < -1 || args[0].Hi > 1 {
+			return InverseTrigOutOfRange, false
+		}
+	}
+
+	return 0, true
+}
+
+var intervalBuiltins = map[string]func(args []Interval) Interval{
+	"log": func(a []Interval) Interval {
+		return divInterval(logInterval(math.Log, a[1]), logInterval(math.Log, a[0]))
+	},
+	"log10": func(a []Interval) Interval { return logInterval(math.Log10, a[0]) },
+	"log2":  func(a []Interval) Interval { return logInterval(math.Log2, a[0]) },
+	"ln":    func(a []Interval) Interval { return logInterval(math.Log, a[0]) },
+	"pow":   func(a []Interval) Interval { return powInterval(a[0], a[1]) },
+	"exp":   func(a []Interval) Interval { return increasing(math.Exp, a[0]) },
+	"sqrt": func(a []Interval) Interval {
+		lo, hi := math.Sqrt(a[0].Lo), math.Sqrt(a[0].Hi)
+		return Interval{down(lo, math.FMA(-lo, lo, a[0].Lo)), up(hi, math.FMA(-hi, hi, a[0].Hi))}
+	},
+	"abs": func(a []Interval) Interval {
+		x := a[0]
+		switch {
+		case x.Lo >= 0:
+			return x
+		case x.Hi <= 0:
+			return Interval{-x.Hi, -x.Lo}
+		}
+		return Interval{0, x.mag()}
+	},
+	"sin": func(a []Interval) Interval { return periodic(math.Sin, a[0], math.Pi/2) },
+	"cos": func(a []Interval) Interval { return periodic(math.Cos, a[0], 0) },
+	"tan": func(a []Interval) Interval {
+		// tan is increasing between its poles, at π/2 + kπ.
+		k := math.Ceil((a[0].Lo - math.Pi/2) / math.Pi)
+		if a[0].Hi-a[0].Lo >= math.Pi || math.Pi/2+k*math.Pi <= a[0].Hi {
+			return entire
+		}
+		return increasing(math.Tan, a[0])
+	},
+	"asin": func(a []Interval) Interval { return increasing(math.Asin, a[0]) },
+	"acos": func(a []Interval) Interval { return widen(math.Acos(a[0].Hi), math.Acos(a[0].Lo)) },
+	"atan": func(a []Interval) Interval { return increasing(math.Atan, a[0]) },
+}
+
+// increasing applies the increasing function f to x.
+func increasing(f func(float64) float64, x Interval) Interval {
+	return widen(f(x.Lo), f(x.Hi))
+}
+
+// logInterval applies the logarithm f to x, which is positive. The bounds
+// of logarithms of 1 are exact.
+func logInterval(f func(float64) float64, x Interval) Interval {
+	i := increasing(f, x)
+	if x.Lo == 1 {
+		i.Lo = 0
+	}
+	if x.Hi == 1 {
+		i.Hi = 0
+	}
+
+	return i
+}
+
+// periodic applies f, sin or cos, to x. f has period 2π, is 1 at peak and
+// -1 half a period later.
+func periodic(f func(float64) float64, x Interval, peak float64) Interval {
+	if x.Hi-x.Lo >= 2*math.Pi {
+		return Interval{-1, 1}
+	}
+
+	reaches := func(at float64) bool {
+		k := math.Ceil((x.Lo - at) / (2 * math.Pi))
+		return at+k*2*math.Pi <= x.Hi
+	}
+
+	i := widen(math.Min(f(x.Lo), f(x.Hi)), math.Max(f(x.Lo), f(x.Hi)))
+	if reaches(peak) {
+		i.Hi = 1
+	}
+	if reaches(peak + math.Pi) {
+		i.Lo = -1
+	}
+
+	return Interval{math.Max(i.Lo, -1), math.Min(i.Hi, 1)}
+}
+
+// powInterval raises x to y. If x has negative numbers, y is an integer.
+func powInterval(x, y Interval) Interval {
+	if y.isPoint() && y.Lo == math.Trunc(y.Lo) {
+		n := y.Lo
+		switch {
+		case n == 0:
+			return Interval{1, 1}
+		case n < 0:
+			return divInterval(Interval{1, 1}, powInterval(x, Interval{-n, -n}))
+		case math.Mod(n, 2) == 1 || x.Lo >= 0:
+			return increasing(func(b float64) float64 { return math.Pow(b, n) }, x)
+		case x.Hi <= 0:
+			return widen(math.Pow(x.Hi, n), math.Pow(x.Lo, n))
+		}
+		return Interval{0, widen(0, math.Pow(x.mag(), n)).Hi}
+	}
+
+	// x^y is monotonic in x and in y for positive x, so its bounds are at
+	// the corners.
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, b := range []float64{x.Lo, x.Hi} {
+		for _, p := range []float64{y.Lo, y.Hi} {
+			v := math.Pow(b, p)
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+
+	return widen(lo, hi)
+}
@@ -0,0 +1,149 @@
+package calc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInterval(t *testing.T) {
+	t.Run("Should evaluate to intervals", func(t *testing.T) {
+		testCases := []struct {
+			Input    string
+			Interval Interval
+		}{
+			{"2 ± 0.1", Interval{1.9, 2.1}},
+			{"interval(1.9, 2.1)", Interval{1.9, 2.1}},
+			{"-(1 ± 1)", Interval{-2, 0}},
+			{"(1 ± 1) + (10 ± 2)", Interval{8, 14}},
+			{"(1 ± 1) - (10 ± 2)", Interval{-12, -6}},
+			{"(1 ± 1) * (-3 ± 1)", Interval{-8, 0}},
+			{"1 / interval(2, 4)", Interval{0.25, 0.5}},
+			{"2 * 3 ± 1", Interval{4, 8}},
+			{"x = 1 ± 1; x * x", Interval{0, 4}},
+			{"pow(interval(-2, 1), 2)", Interval{0, 4}},
+			{"pow(interval(-2, 1), 3)", Interval{-8, 1}},
+			{"sqrt(interval(4, 9))", Interval{2, 3}},
+			{"abs(interval(-3, 2))", Interval{0, 3}},
+			{"sin(interval(0, 2))", Interval{0, 1}},
+			{"cos(interval(-1, 4))", Interval{-1, 1}},
+			{"ln(interval(1, 1))", Interval{0, 0}},
+			{"1 / interval(-1, 1)", Interval{math.Inf(-1), math.Inf(1)}},
+			{"(1 ± 1) < 3", Interval{1, 1}},
+			{"(1 ± 1) < 1.5", Interval{0, 1}},
+			{"interval(7, 8) % 3", Interval{1, 2}},
+			{"interval(5, 7) % 3", Interval{0, 3}},
+			{"interval(-2, -1) % 3", Interval{1, 2}},
+			{"interval(7, 8) % -3", Interval{-2, -1}},
+			{"5 % interval(2, 3)", Interval{0, 3}},
+			{"asin(interval(0, 1))", Interval{0, math.Pi / 2}},
+			{"acos(interval(-1, 1))", Interval{0, math.Pi}},
+		}
+
+		for _, c := range testCases {
+			e := NewEvaluator(WithDomainPolicy(SpecialValues))
+			v, err := e.Evaluate(c.Input)
+			if err != nil {
+				t.Fatalf("error (%s) not nil in test case %+v", err, c)
+			}
+
+			i, ok := toInterval(v)
+			if !ok || i != c.Interval && (!floatEquals(i.Lo, c.Interval.Lo, 1e-9) || !floatEquals(i.Hi, c.Interval.Hi, 1e-9)) {
+				t.Fatalf("%v != %v in test case %q", v, c.Interval, c.Input)
+			}
+		}
+	})
+
+	t.Run("Should round bounds outwards", func(t *testing.T) {
+		testCases := []struct {
+			Input string
+			Exact float64 // the nearest float64 to the exact result
+		}{
+			{"interval(0.1, 0.1) * 3", 0.3},
+			{"interval(0.1, 0.1) + 0.2", 0.3},
+			{"1 / interval(3, 3)", 1.0 / 3},
+			{"sqrt(interval(2, 2))", math.Sqrt2},
+			{"exp(interval(1, 1))", math.E},
+			{"sin(interval(1, 1))", 0.8414709848078965},
+		}
+
+		for _, c := range testCases {
+			v, err := NewEvaluator().Evaluate(c.Input)
+			if err != nil {
+				t.Fatalf("error (%s) not nil in test case %+v", err, c)
+			}
+
+			i := v.(Interval)
+			if i.Lo > c.Exact || i.Hi < c.Exact || i.Hi-i.Lo > 1e-15*math.Max(1, c.Exact) {
+				t.Fatalf("%v doesn't tightly enclose %v in test case %q", i, c.Exact, c.Input)
+			}
+		}
+	})
+
+	t.Run("Should print exact bounds as written", func(t *testing.T) {
+		if v, _ := NewEvaluator().Evaluate("2 ± 0.1"); v.String() != "interval(1.9, 2.1)" {
+			t.Fatalf("%s != interval(1.9, 2.1)", v)
+		}
+		if v, _ := NewEvaluator().Evaluate("interval(1, 2) + 1"); v.String() != "interval(2, 3)" {
+			t.Fatalf("%s != interval(2, 3)", v)
+		}
+		if v, _ := NewEvaluator().Evaluate("[1.9, 2.1]"); isInterval(v) {
+			t.Fatalf("%s is an interval rather than a list", v)
+		}
+	})
+
+	t.Run("Should fail outside of the domains", func(t *testing.T) {
+		testCases := []string{
+			"1 / (0 ± 1)",
+			"ln(interval(-1, 2))",
+			"sqrt(interval(-1, 4))",
+			"pow(interval(-1, 4), 0.5)",
+			"gcd(1 ± 1, 2)",
+			"interval(2, 1)",
+			"[1] ± 1",
+			"asin(interval(0.5, 1.5))",
+			"acos(interval(-2, 0))",
+			"interval(1, 2) % interval(-1, 1)",
+		}
+
+		for _, c := range testCases {
+			if _, err := NewEvaluator().Evaluate(c); err == nil {
+				t.Fatalf("error is nil for %q", c)
+			}
+		}
+	})
+
+	t.Run("Should be undefined outside of the domains with special values", func(t *testing.T) {
+		testCases := []string{
+			"asin(interval(0.5, 1.5))",
+			"acos(interval(-2, 0))",
+			"interval(1, 2) % interval(-1, 1)",
+		}
+
+		for _, c := range testCases {
+			v, err := NewEvaluator(WithDomainPolicy(SpecialValues)).Evaluate(c)
+			if i, ok := v.(Interval); err != nil || !ok || !math.IsNaN(i.Lo) || !math.IsNaN(i.Hi) {
+				t.Fatalf("%v is not undefined or error (%s) not nil for %q", v, err, c)
+			}
+		}
+	})
+
+	t.Run("Should format plus-minus", func(t *testing.T) {
+		testCases := []struct {
+			Input     string
+			Formatted string
+		}{
+			{"2±0.1", "2 ± 0.1"},
+			{"(2 ± 0.1) * 3", "2 ± 0.1 * 3"},
+			{"2 ± (0.1 * 3)", "2 ± (0.1 * 3)"},
+			{"-1 ± 0.1", "-1 ± 0.1"},
+			{"-(1 ± 0.1)", "-(1 ± 0.1)"},
+		}
+
+		for _, c := range testCases {
+			formatted, err := Format(c.Input)
+			if err != nil || formatted != c.Formatted {
+				t.Fatalf("%q != %q or error (%s) not nil in test case %+v", formatted, c.Formatted, err, c)
+			}
+		}
+	})
+}
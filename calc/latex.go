@@ -0,0 +1,274 @@
+package calc
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Precedences of typeset math, from loosest to tightest, which differ from
+// the ones of the language: fractions and powers group their operands
+// visually, so only their own placement may need parenthesis. The LaTeX and
+// MathML renderers and the images of package render share them.
+const (
+	MathAssign = iota
+	MathCompare
+	MathSum
+	MathProduct
+	MathFrac
+	MathAtom
+)
+
+// ToLaTeX renders n as a LaTeX math expression. Divisions become fractions,
+// powers superscripts and log(b, x) is written as \log_{b} x.
+func ToLaTeX(n Node) string {
+	switch n := n.(type) {
+	case *NumberLit:
+		if n.Int != nil {
+			return n.Int.String()
+		}
+		return latexNumber(n.Value)
+	case *Ident:
+		return latexIdent(n.Name)
+	case *UnaryExpr:
+		return n.Op + latexOperand(n.X, MathProduct+1)
+	case *BinaryExpr:
+		switch n.Op {
+		case "/":
+			return `\frac{` + ToLaTeX(n.X) + `}{` + ToLaTeX(n.Y) + `}`
+		case "*":
+			return latexOperand(n.X, MathProduct) + ` \cdot ` + latexOperand(n.Y, MathProduct)
+		case "%":
+			return latexOperand(n.X, MathProduct) + ` \bmod ` + latexOperand(n.Y, MathProduct+1)
+		case "<", ">", "<=", ">=", "==", "!=":
+			return latexOperand(n.X, MathCompare+1) + " " + latexComparison[n.Op] + " " + latexOperand(n.Y, MathCompare+1)
+		case "±":
+			return latexOperand(n.X, MathSum) + ` \pm ` + latexOperand(n.Y, MathSum+1)
+		}
+		return latexOperand(n.X, MathSum) + " " + n.Op + " " + latexOperand(n.Y, MathSum+1)
+	case *CallExpr:
+		return latexCall(n)
+	case *ListExpr:
+		elems := make([]string, len(n.Elems))
+		for i, elem := range n.Elems {
+			elems[i] = ToLaTeX(elem)
+		}
+		return `\left[` + strings.Join(elems, ", ") + `\right]`
+	case *AssignExpr:
+		return latexIdent(n.Name) + " = " + ToLaTeX(n.Value)
+	case *LambdaExpr:
+		params := make([]string, len(n.Params))
+		for i, p := range n.Params {
+			params[i] = latexIdent(p)
+		}
+		s := strings.Join(params, ", ")
+		if len(params) != 1 {
+			s = `\left(` + s + `\right)`
+		}
+		return s + ` \mapsto ` + ToLaTeX(n.Body)
+	case *ComprehensionExpr:
+		s := `\left[` + ToLaTeX(n.Elem) + ` \mid ` + latexIdent(n.Var) + ` \in ` + ToLaTeX(n.Iter)
+		if n.Cond != nil {
+			s += ", " + ToLaTeX(n.Cond)
+		}
+		return s + `\right]`
+	case *valueNode:
+		if s, ok := n.value.(Symbolic); ok {
+			return ToLaTeX(s.Expr)
+		}
+		if f, ok := n.value.(Number); ok {
+			return latexNumber(float64(f))
+		}
+	}
+
+	return n.String()
+}
+
+func latexCall(n *CallExpr) string {
+	switch {
+	case n.Func == "pow" && len(n.Args) == 2:
+		if exp, ok := literalValue(n.Args[1]); ok && exp == 0.5 {
+			return `\sqrt{` + ToLaTeX(n.Args[0]) + `}`
+		}
+		base := latexOperand(n.Args[0], MathAtom)
+		if IsSuperscripted(n.Args[0]) {
+			// Otherwise pow(pow(x, y), z) would be x^{y}^{z}, which LaTeX
+			// rejects.
+			base = `\left(` + base + `\right)`
+		}
+		return base + `^{` + ToLaTeX(n.Args[1]) + `}`
+	case n.Func == "sqrt" && len(n.Args) == 1:
+		return `\sqrt{` + ToLaTeX(n.Args[0]) + `}`
+	case n.Func == "exp" && len(n.Args) == 1:
+		return `e^{` + ToLaTeX(n.Args[0]) + `}`
+	case n.Func == "log" && len(n.Args) == 2:
+		return `\log_{` + ToLaTeX(n.Args[0]) + `} ` + latexOperand(n.Args[1], MathAtom)
+	case n.Func == "log10" && len(n.Args) == 1:
+		return `\log_{10} ` + latexOperand(n.Args[0], MathAtom)
+	case n.Func == "log2" && len(n.Args) == 1:
+		return `\log_{2} ` + latexOperand(n.Args[0], MathAtom)
+	case n.Func == "ln" && len(n.Args) == 1:
+		return `\ln ` + latexOperand(n.Args[0], MathAtom)
+	}
+
+	args := make([]string, len(n.Args))
+	for i, arg := range n.Args {
+		args[i] = ToLaTeX(arg)
+	}
+
+	return `\operatorname{` + latexEscape(n.Func) + `}\left(` + strings.Join(args, ", ") + `\right)`
+}
+
+// IsSuperscripted tells if n is typeset with a superscript of its own, as
+// powers, exponentials and numbers in scientific notation are.
+func IsSuperscripted(n Node) bool {
+	switch n := n.(type) {
+	case *NumberLit:
+		_, _, ok := splitExponent(n.Value)
+		return n.Int == nil && ok
+	case *CallExpr:
+		switch {
+		case n.Func == "exp" && len(n.Args) == 1:
+			return true
+		case n.Func == "pow" && len(n.Args) == 2:
+			exp, ok := literalValue(n.Args[1])
+			return !ok || exp != 0.5
+		}
+	}
+
+	return false
+}
+
+var latexComparison = map[string]string{
+	"<":  "<",
+	">":  ">",
+	"<=": `\leq`,
+	">=": `\geq`,
+	"==": "=",
+	"!=": `\neq`,
+}
+
+// latexOperand renders n, wrapping it in parenthesis if it binds looser than
+// prec.
+func latexOperand(n Node, prec int) string {
+	if MathPrecedence(n) < prec {
+		return `\left(` + ToLaTeX(n) + `\right)`
+	}
+
+	return ToLaTeX(n)
+}
+
+// MathSymbols are the symbols operators are typeset with when they aren't
+// written as they are typed, e.g. ≤ for <=.
+var MathSymbols = map[string]string{
+	"-":  "−",
+	"*":  "⋅",
+	"%":  "mod",
+	"<=": "≤",
+	">=": "≥",
+	"==": "=",
+	"!=": "≠",
+}
+
+// MathSymbol returns the symbol op is typeset with.
+func MathSymbol(op string) string {
+	if s, ok := MathSymbols[op]; ok {
+		return s
+	}
+
+	return op
+}
+
+// MathPrecedence tells how tightly n groups when typeset.
+func MathPrecedence(n Node) int {
+	switch n := n.(type) {
+	case *NumberLit:
+		if n.Value < 0 {
+			return MathProduct
+		}
+	case *UnaryExpr:
+		return MathProduct
+	case *BinaryExpr:
+		switch n.Op {
+		case "*", "%":
+			return MathProduct
+		case "/":
+			return MathFrac
+		case "<", ">", "<=", ">=", "==", "!=":
+			return MathCompare
+		}
+		return MathSum
+	case *CallExpr:
+		switch n.Func {
+		case "log", "log10", "log2", "ln":
+			// \ln x + 1 reads fine, but (\ln x)^{2} needs the parenthesis.
+			return MathFrac
+		}
+	case *AssignExpr, *LambdaExpr:
+		return MathAssign
+	case *valueNode:
+		if s, ok := n.value.(Symbolic); ok {
+			return MathPrecedence(s.Expr)
+		}
+		if f, ok := n.value.(Number); ok && f < 0 {
+			return MathProduct
+		}
+	}
+
+	return MathAtom
+}
+
+// latexNumber writes numbers in scientific notation as m \times 10^{e}.
+func latexNumber(f float64) string {
+	if mantissa, exp, ok := splitExponent(f); ok {
+		return mantissa + ` \times 10^{` + exp + `}`
+	}
+
+	return formatFloat(f)
+}
+
+// splitExponent returns the mantissa and exponent of f if it is formatted in
+// scientific notation.
+func splitExponent(f float64) (string, string, bool) {
+	s := formatFloat(f)
+	i := strings.IndexAny(s, "eE")
+	if i < 0 {
+		return "", "", false
+	}
+
+	exp, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return "", "", false
+	}
+
+	return s[:i], strconv.Itoa(exp), true
+}
+
+// greekLetters maps the names of greek letters to the letters themselves.
+var greekLetters = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ε",
+	"zeta": "ζ", "eta": "η", "theta": "θ", "iota": "ι", "kappa": "κ",
+	"lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ", "pi": "π", "rho": "ρ",
+	"sigma": "σ", "tau": "τ", "upsilon": "υ", "phi": "φ", "chi": "χ",
+	"psi": "ψ", "omega": "ω", "Gamma": "Γ", "Delta": "Δ", "Theta": "Θ",
+	"Lambda": "Λ", "Xi": "Ξ", "Pi": "Π", "Sigma": "Σ", "Phi": "Φ",
+	"Psi": "Ψ", "Omega": "Ω",
+}
+
+// latexIdent writes single letter variables in italics, like math does, and
+// longer names upright so that they don't read as products of letters.
+func latexIdent(name string) string {
+	if _, ok := greekLetters[name]; ok {
+		return `\` + name
+	}
+	if len([]rune(name)) == 1 {
+		return name
+	}
+
+	return `\mathrm{` + latexEscape(name) + `}`
+}
+
+var latexEscaper = strings.NewReplacer(`_`, `\_`)
+
+func latexEscape(s string) string {
+	return latexEscaper.Replace(s)
+}
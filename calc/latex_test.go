@@ -0,0 +1,47 @@
+package calc
+
+import "testing"
+
+func TestToLaTeX(t *testing.T) {
+	t.Run("Should typeset expressions", func(t *testing.T) {
+		testCases := []struct {
+			Input string
+			LaTeX string
+		}{
+			{"1 + x", `1 + x`},
+			{"1 / (x + 1)", `\frac{1}{x + 1}`},
+			{"2 * (x - y)", `2 \cdot \left(x - y\right)`},
+			{"x - (y - z)", `x - \left(y - z\right)`},
+			{"-(a + b)", `-\left(a + b\right)`},
+			{"pow(x, 2)", `x^{2}`},
+			{"pow(x + 1, y / 2)", `\left(x + 1\right)^{\frac{y}{2}}`},
+			{"pow(x, 0.5)", `\sqrt{x}`},
+			{"exp(-x)", `e^{-x}`},
+			{"log(b, x)", `\log_{b} x`},
+			{"log(2, x + 1)", `\log_{2} \left(x + 1\right)`},
+			{"log10(100) + ln(x)", `\log_{10} 100 + \ln x`},
+			{"pow(ln(x), 2)", `\left(\ln x\right)^{2}`},
+			{"6.67e-11 * mass", `6.67 \times 10^{-11} \cdot \mathrm{mass}`},
+			{"alpha * a_b", `\alpha \cdot \mathrm{a\_b}`},
+			{"f(x, 1)", `\operatorname{f}\left(x, 1\right)`},
+			{"a = (1 + 2)", `a = 1 + 2`},
+			{"pow(pow(x, y), z)", `\left(x^{y}\right)^{z}`},
+			{"pow(exp(x), 2)", `\left(e^{x}\right)^{2}`},
+			{"pow(6.67e-11, 2)", `\left(6.67 \times 10^{-11}\right)^{2}`},
+			{"pow(pow(x, 0.5), 3)", `\sqrt{x}^{3}`},
+			{"sqrt(x + 1)", `\sqrt{x + 1}`},
+			{"pow(sqrt(x), 3)", `\sqrt{x}^{3}`},
+		}
+
+		for _, c := range testCases {
+			stmts, err := Parse(c.Input)
+			if err != nil {
+				t.Fatalf("error (%s) not nil in test case %+v", err, c)
+			}
+
+			if latex := ToLaTeX(stmts[0]); latex != c.LaTeX {
+				t.Fatalf("%q != %q in test case %+v", latex, c.LaTeX, c)
+			}
+		}
+	})
+}
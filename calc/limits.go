@@ -0,0 +1,192 @@
+package calc
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Limits bound the resources evaluating a single program may take, so that
+// one inline query can't keep a CPU busy. Limits that are 0 aren't enforced.
+type Limits struct {
+	// MaxInputLength is how many characters a program may have.
+	MaxInputLength int
+	// MaxDepth is how deeply the syntax tree of a statement may nest.
+	MaxDepth int
+	// MaxOperations is how many expressions may be evaluated in a program.
+	MaxOperations int
+	// MaxBits is how large, in bits, integers may grow.
+	MaxBits int
+	// MaxOutputLength is how many characters the value of a program may
+	// take to print.
+	MaxOutputLength int
+	// MaxIterations is how many elements a program may generate with range
+	// or go through with comprehensions, map, filter and reduce, in total.
+	MaxIterations int
+}
+
+// DefaultLimits are the limits evaluators use unless told otherwise. The
+// output fits in a Telegram message.
+var DefaultLimits = Limits{
+	MaxInputLength:  4096,
+	MaxDepth:        1000,
+	MaxOperations:   1000000,
+	MaxBits:         8192,
+	MaxOutputLength: 4096,
+	MaxIterations:   10000,
+}
+
+// WithLimits makes the evaluator enforce limits instead of DefaultLimits.
+func WithLimits(limits Limits) Option {
+	return func(e *Evaluator) {
+		e.limits = limits
+	}
+}
+
+// InputTooLongError is returned for programs longer than
+// Limits.MaxInputLength.
+type InputTooLongError struct {
+	Length, Max int
+}
+
+func (err *InputTooLongError) Error() string {
+	return fmt.Sprintf("Program is %d characters long, the limit is %d", err.Length, err.Max)
+}
+
+// TooDeepError is returned for statements nested deeper than
+// Limits.MaxDepth.
+type TooDeepError struct {
+	Depth, Max int
+}
+
+func (err *TooDeepError) Error() string {
+	return fmt.Sprintf("Program nests %d levels deep, the limit is %d", err.Depth, err.Max)
+}
+
+// TooManyOperationsError is returned when evaluating a program takes more
+// than Limits.MaxOperations.
+type TooManyOperationsError struct {
+	Max int
+}
+
+func (err *TooManyOperationsError) Error() string {
+	return fmt.Sprintf("Program takes more than %d operations", err.Max)
+}
+
+// NumberTooLargeError is returned when an integer grows larger than
+// Limits.MaxBits.
+type NumberTooLargeError struct {
+	Bits, Max int
+}
+
+func (err *NumberTooLargeError) Error() string {
+	return fmt.Sprintf("Integer of %d bits is larger than the limit of %d", err.Bits, err.Max)
+}
+
+// OutputTooLongError is returned for values that print longer than
+// Limits.MaxOutputLength.
+type OutputTooLongError struct {
+	Length, Max int
+}
+
+func (err *OutputTooLongError) Error() string {
+	return fmt.Sprintf("Result is %d characters long, the limit is %d", err.Length, err.Max)
+}
+
+// TooManyIterationsError is returned when a program iterates more than
+// Limits.MaxIterations times.
+type TooManyIterationsError struct {
+	Max int
+}
+
+func (err *TooManyIterationsError) Error() string {
+	return fmt.Sprintf("Program iterates more than %d times", err.Max)
+}
+
+// ctxCheckInterval is how many operations the evaluator makes between
+// checks of its context.
+const ctxCheckInterval = 1024
+
+// start prepares the evaluator to evaluate a program within ctx.
+func (e *Evaluator) start(ctx context.Context) {
+	e.ctx, e.ops, e.iterations = ctx, 0, 0
+}
+
+// tick counts an operation, failing if there were too many or the context
+// of the evaluation is done.
+func (e *Evaluator) tick() error {
+	e.ops++
+	if max := e.limits.MaxOperations; max > 0 && e.ops > max {
+		return &TooManyOperationsError{max}
+	}
+	if e.ops%ctxCheckInterval == 0 && e.ctx != nil {
+		return e.ctx.Err()
+	}
+
+	return nil
+}
+
+// iterate counts n iterations, failing if there were too many.
+func (e *Evaluator) iterate(n int) error {
+	e.iterations += n
+	if max := e.limits.MaxIterations; max > 0 && e.iterations > max {
+		return &TooManyIterationsError{max}
+	}
+
+	return nil
+}
+
+// checkInput fails if program is longer than the limit.
+func (e *Evaluator) checkInput(program string) error {
+	if n, max := utf8.RuneCountInString(program), e.limits.MaxInputLength; max > 0 && n > max {
+		return &InputTooLongError{n, max}
+	}
+
+	return nil
+}
+
+// checkDepth fails if any of stmts nests deeper than the limit.
+func (e *Evaluator) checkDepth(stmts []Node) error {
+	for _, stmt := range stmts {
+		if d, max := depth(stmt), e.limits.MaxDepth; max > 0 && d > max {
+			return &TooDeepError{d, max}
+		}
+	}
+
+	return nil
+}
+
+// checkValue fails if v is an integer larger than the limit.
+func (e *Evaluator) checkValue(v Value) error {
+	if i, ok := v.(Integer); ok {
+		if bits, max := i.BitLen(), e.limits.MaxBits; max > 0 && bits > max {
+			return &NumberTooLargeError{bits, max}
+		}
+	}
+
+	return nil
+}
+
+// checkOutput fails if v prints longer than the limit.
+func (e *Evaluator) checkOutput(v Value) error {
+	if v == nil {
+		return nil
+	}
+	if n, max := utf8.RuneCountInString(v.String()), e.limits.MaxOutputLength; max > 0 && n > max {
+		return &OutputTooLongError{n, max}
+	}
+
+	return nil
+}
+
+// depth returns how many levels the syntax tree n nests.
+func depth(n Node) int {
+	d := 0
+	for _, child := range children(n) {
+		if c := depth(child); c > d {
+			d = c
+		}
+	}
+
+	return d + 1
+}
@@ -0,0 +1,72 @@
+package calc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimits(t *testing.T) {
+	t.Run("Should fail with the error of the limit exceeded", func(t *testing.T) {
+		limits := Limits{
+			MaxInputLength:  200,
+			MaxDepth:        10,
+			MaxOperations:   50,
+			MaxBits:         100,
+			MaxOutputLength: 10,
+		}
+
+		testCases := []struct {
+			Program string
+			Error   error
+		}{
+			{strings.Repeat("1", 201), &InputTooLongError{201, 200}},
+			{strings.Repeat("(", 20) + "1" + strings.Repeat(")", 20), nil},
+			{strings.Repeat("-", 10) + "1", &TooDeepError{11, 10}},
+			{strings.Repeat("1; ", 50) + "1", &TooManyOperationsError{50}},
+			{"99999999999999999999 * 99999999999999999999", &NumberTooLargeError{133, 100}},
+			{"[1, 2, 3, 4, 5]", &OutputTooLongError{15, 10}},
+		}
+
+		for _, c := range testCases {
+			_, err := NewEvaluator(WithLimits(limits)).Evaluate(c.Program)
+			if c.Error == nil && err != nil || c.Error != nil && (err == nil || err.Error() != c.Error.Error()) {
+				t.Fatalf("error (%v) != (%v) in test case %q", err, c.Error, c.Program)
+			}
+		}
+	})
+
+	t.Run("Should report limits as typed errors", func(t *testing.T) {
+		_, err := NewEvaluator(WithLimits(Limits{MaxOperations: 5})).Evaluate("1 + 2 + 3 + 4")
+
+		var ops *TooManyOperationsError
+		if !errors.As(err, &ops) || ops.Max != 5 {
+			t.Fatalf("error (%v) is not a TooManyOperationsError", err)
+		}
+	})
+
+	t.Run("Should not enforce limits set to 0", func(t *testing.T) {
+		program := strings.Repeat("1 + ", 5000) + "1"
+		if v, err := NewEvaluator(WithLimits(Limits{})).Evaluate(program); err != nil || v != Number(5001) {
+			t.Fatalf("%v != 5001 or error (%s) not nil", v, err)
+		}
+	})
+
+	t.Run("Should give up once the context is done", func(t *testing.T) {
+		program := strings.Repeat("1+", 600) + "1"
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := NewEvaluator().EvaluateContext(ctx, program); err != context.Canceled {
+			t.Fatalf("error (%v) != %v", err, context.Canceled)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if v, err := EvaluateContext(ctx, program); err != nil || v != 601 {
+			t.Fatalf("%f != 601 or error (%s) not nil", v, err)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package calc
+
+import "strings"
+
+// Grouping is how the digits of the integer part of numbers are grouped.
+type Grouping int
+
+const (
+	// GroupThousands groups digits by three, as in 1,234,567.
+	GroupThousands Grouping = iota
+	// GroupIndian groups the last three digits and then digits by two, as in
+	// 12,34,567 (lakh and crore).
+	GroupIndian
+)
+
+// Locale is a convention for writing numbers.
+//
+// Programs are parsed with the decimal separator of their locale. If it is
+// a comma, a comma between two digits is a decimal separator, so arguments
+// must be separated by a comma and a space, as in pow(1,5, 2). Group
+// separators are accepted in programs unless they are commas, which always
+// separate arguments when they don't separate decimals.
+type Locale struct {
+	Name     string
+	Decimal  rune
+	Group    rune // 0 for no grouping
+	Grouping Grouping
+}
+
+var (
+	// English writes 1,234,567.89.
+	English = Locale{"en", '.', ',', GroupThousands}
+	// German writes 1.234.567,89.
+	German = Locale{"de", ',', '.', GroupThousands}
+	// French writes 1 234 567,89, with narrow no-break spaces.
+	French = Locale{"fr", ',', '\u202f', GroupThousands}
+	// Swiss writes 1'234'567.89.
+	Swiss = Locale{"ch", '.', '\'', GroupThousands}
+	// Indian writes 12,34,567.89.
+	Indian = Locale{"in", '.', ',', GroupIndian}
+	// Plain writes 1234567.89, like programs do.
+	Plain = Locale{"plain", '.', 0, GroupThousands}
+)
+
+// Locales are the locales that can be looked up by name.
+var Locales = []Locale{English, German, French, Swiss, Indian, Plain}
+
+// LookupLocale returns the locale in Locales with the given name.
+func LookupLocale(name string) (Locale, bool) {
+	for _, l := range Locales {
+		if l.Name == name {
+			return l, true
+		}
+	}
+
+	return Locale{}, false
+}
+
+// WithLocale makes the evaluator parse numbers written following l instead
+// of Plain.
+func WithLocale(l Locale) Option {
+	return func(e *Evaluator) {
+		e.locale = l
+	}
+}
+
+// Localize rewrites number, formatted like strconv.FormatFloat does, with
+// the separators of l.
+func (l Locale) Localize(number string) string {
+	sign := ""
+	if strings.HasPrefix(number, "-") {
+		sign, number = "-", number[1:]
+	}
+
+	exp := ""
+	if i := strings.IndexAny(number, "eE"); i >= 0 {
+		number, exp = number[:i], number[i:]
+	}
+
+	integer, fraction := number, ""
+	if i := strings.IndexByte(number, '.'); i >= 0 {
+		integer, fraction = number[:i], string(l.Decimal)+number[i+1:]
+	}
+
+	return sign + l.group(integer) + fraction + exp
+}
+
+// group inserts group separators in the digits of integer.
+func (l Locale) group(integer string) string {
+	if l.Group == 0 || len(integer) < 4 || strings.Trim(integer, "0123456789") != "" {
+		return integer
+	}
+
+	size := 3
+	var groups []string
+	for len(integer) > size {
+		groups = append([]string{integer[len(integer)-size:]}, groups...)
+		integer = integer[:len(integer)-size]
+		if l.Grouping == GroupIndian {
+			size = 2
+		}
+	}
+	groups = append([]string{integer}, groups...)
+
+	return strings.Join(groups, string(l.Group))
+}
+
+// canonical rewrites number, written following l, like strconv.ParseFloat
+// expects it.
+func (l Locale) canonical(number string) string {
+	if l.Group != 0 {
+		number = strings.Replace(number, string(l.Group), "", -1)
+	}
+
+	return strings.Replace(number, string(l.Decimal), ".", 1)
+}
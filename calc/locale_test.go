@@ -0,0 +1,95 @@
+package calc
+
+import (
+	"testing"
+)
+
+func TestLocale(t *testing.T) {
+	t.Run("Should localize numbers", func(t *testing.T) {
+		testCases := []struct {
+			Locale   Locale
+			Number   string
+			Expected string
+		}{
+			{English, "1234567.89", "1,234,567.89"},
+			{English, "-1234", "-1,234"},
+			{English, "123", "123"},
+			{English, "1.5e+20", "1.5e+20"},
+			{English, "12345e-20", "12,345e-20"},
+			{English, "NaN", "NaN"},
+			{German, "1234567.89", "1.234.567,89"},
+			{German, "0.5", "0,5"},
+			{French, "1234567.89", "1 234 567,89"},
+			{Swiss, "1234567.89", "1'234'567.89"},
+			{Indian, "1234567.89", "12,34,567.89"},
+			{Indian, "123456789", "12,34,56,789"},
+			{Indian, "1234", "1,234"},
+			{Plain, "1234567.89", "1234567.89"},
+		}
+
+		for _, tc := range testCases {
+			if actual := tc.Locale.Localize(tc.Number); actual != tc.Expected {
+				t.Fatalf("%s in %s: expected %s, got %s", tc.Number, tc.Locale.Name, tc.Expected, actual)
+			}
+		}
+	})
+
+	t.Run("Should parse numbers written in the locale", func(t *testing.T) {
+		testCases := []struct {
+			Locale   Locale
+			Input    string
+			Expected float64
+		}{
+			{German, "1.234,56 + 1", 1235.56},
+			{German, "1.234.567", 1234567},
+			{German, "0,5 * 2", 1},
+			{German, "pow(1,5, 2)", 2.25},
+			{German, "pow(2, 3)", 8},
+			{German, "1,5e3", 1500},
+			{French, "1 234,5", 1234.5},
+			{Swiss, "1'234.5", 1234.5},
+			{English, "1234.5", 1234.5},
+			{English, "pow(2,3)", 8},
+			{Indian, "max = 12; max", 12},
+		}
+
+		for _, tc := range testCases {
+			v, err := NewEvaluator(WithLocale(tc.Locale)).Evaluate(tc.Input)
+			if err != nil {
+				t.Fatalf("error (%s) not nil for %s in %s", err, tc.Input, tc.Locale.Name)
+			}
+			if !floatEquals(float64(v.(Number)), tc.Expected, 1e-9) {
+				t.Fatalf("%s in %s: expected %f, got %s", tc.Input, tc.Locale.Name, tc.Expected, v)
+			}
+		}
+	})
+
+	t.Run("Should reject numbers in other conventions", func(t *testing.T) {
+		testCases := []struct {
+			Locale Locale
+			Input  string
+		}{
+			{English, "1,5"},
+			{English, "1,234.5"},
+			{German, "1.5"},
+			{German, "1.2345"},
+			// 10,100 is a single number, so log gets one argument.
+			{German, "log(10,100)"},
+		}
+
+		for _, tc := range testCases {
+			if _, err := NewEvaluator(WithLocale(tc.Locale)).Evaluate(tc.Input); err == nil {
+				t.Fatalf("error is nil for %s in %s", tc.Input, tc.Locale.Name)
+			}
+		}
+	})
+
+	t.Run("Should look locales up by name", func(t *testing.T) {
+		if l, ok := LookupLocale("de"); !ok || l != German {
+			t.Fatalf("de is %+v", l)
+		}
+		if _, ok := LookupLocale("xx"); ok {
+			t.Fatalf("found locale xx")
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package calc
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// ToMathML renders n as a presentation MathML <math> element, typeset like
+// ToLaTeX does.
+func ToMathML(n Node) string {
+	return `<math xmlns="http://www.w3.org/1998/Math/MathML">` + mathml(n) + `</math>`
+}
+
+func mathml(n Node) string {
+	switch n := n.(type) {
+	case *NumberLit:
+		if n.Int != nil {
+			return mn(n.Int.String())
+		}
+		return mathmlNumber(n.Value)
+	case *Ident:
+		return mathmlIdent(n.Name)
+	case *UnaryExpr:
+		return mrow(mathmlOperator(n.Op) + mathmlOperand(n.X, MathProduct+1))
+	case *BinaryExpr:
+		switch n.Op {
+		case "/":
+			return "<mfrac>" + mathml(n.X) + mathml(n.Y) + "</mfrac>"
+		case "*":
+			return mrow(mathmlOperand(n.X, MathProduct) + mathmlOperator(n.Op) + mathmlOperand(n.Y, MathProduct))
+		case "%":
+			return mrow(mathmlOperand(n.X, MathProduct) + mathmlOperator(n.Op) + mathmlOperand(n.Y, MathProduct+1))
+		case "<", ">", "<=", ">=", "==", "!=":
+			return mrow(mathmlOperand(n.X, MathCompare+1) + mathmlOperator(n.Op) + mathmlOperand(n.Y, MathCompare+1))
+		}
+		return mrow(mathmlOperand(n.X, MathSum) + mathmlOperator(n.Op) + mathmlOperand(n.Y, MathSum+1))
+	case *CallExpr:
+		return mathmlCall(n)
+	case *ListExpr:
+		elems := make([]string, len(n.Elems))
+		for i, elem := range n.Elems {
+			elems[i] = mathml(elem)
+		}
+		return mrow(mo("[") + strings.Join(elems, mo(",")) + mo("]"))
+	case *AssignExpr:
+		return mrow(mathmlIdent(n.Name) + mo("=") + mathml(n.Value))
+	case *valueNode:
+		if s, ok := n.value.(Symbolic); ok {
+			return mathml(s.Expr)
+		}
+		if f, ok := n.value.(Number); ok {
+			return mathmlNumber(float64(f))
+		}
+	}
+
+	return "<mtext>" + html.EscapeString(n.String()) + "</mtext>"
+}
+
+func mathmlCall(n *CallExpr) string {
+	// U+2061 FUNCTION APPLICATION tells renderers that log x isn't a product.
+	const apply = "<mo>&#x2061;</mo>"
+
+	switch {
+	case n.Func == "pow" && len(n.Args) == 2:
+		if exp, ok := literalValue(n.Args[1]); ok && exp == 0.5 {
+			return "<msqrt>" + mathml(n.Args[0]) + "</msqrt>"
+		}
+		base := mathmlOperand(n.Args[0], MathAtom)
+		if IsSuperscripted(n.Args[0]) {
+			// Otherwise pow(pow(x, y), z) would look like x^(y^z).
+			base = mrow(mo("(") + base + mo(")"))
+		}
+		return "<msup>" + base + mathml(n.Args[1]) + "</msup>"
+	case n.Func == "sqrt" && len(n.Args) == 1:
+		return "<msqrt>" + mathml(n.Args[0]) + "</msqrt>"
+	case n.Func == "exp" && len(n.Args) == 1:
+		return "<msup><mi>e</mi>" + mathml(n.Args[0]) + "</msup>"
+	case n.Func == "log" && len(n.Args) == 2:
+		return mrow("<msub><mi>log</mi>" + mathml(n.Args[0]) + "</msub>" + apply + mathmlOperand(n.Args[1], MathAtom))
+	case n.Func == "log10" && len(n.Args) == 1:
+		return mrow("<msub><mi>log</mi><mn>10</mn></msub>" + apply + mathmlOperand(n.Args[0], MathAtom))
+	case n.Func == "log2" && len(n.Args) == 1:
+		return mrow("<msub><mi>log</mi><mn>2</mn></msub>" + apply + mathmlOperand(n.Args[0], MathAtom))
+	case n.Func == "ln" && len(n.Args) == 1:
+		return mrow("<mi>ln</mi>" + apply + mathmlOperand(n.Args[0], MathAtom))
+	}
+
+	args := make([]string, len(n.Args))
+	for i, arg := range n.Args {
+		args[i] = mathml(arg)
+	}
+
+	return mrow(`<mi mathvariant="normal">` + html.EscapeString(n.Func) + "</mi>" + apply +
+		mrow(mo("(")+strings.Join(args, mo(","))+mo(")")))
+}
+
+// mathmlOperator renders the symbol of op, writing the runes outside of
+// ASCII as character references.
+func mathmlOperator(op string) string {
+	var b strings.Builder
+	for _, r := range html.EscapeString(MathSymbol(op)) {
+		if r < utf8.RuneSelf {
+			b.WriteRune(r)
+		} else {
+			fmt.Fprintf(&b, "&#x%X;", r)
+		}
+	}
+
+	return mo(b.String())
+}
+
+// mathmlOperand renders n, wrapping it in parenthesis if it binds looser than
+// prec.
+func mathmlOperand(n Node, prec int) string {
+	if MathPrecedence(n) < prec {
+		return mrow(mo("(") + mathml(n) + mo(")"))
+	}
+
+	return mathml(n)
+}
+
+func mathmlNumber(f float64) string {
+	if mantissa, exp, ok := splitExponent(f); ok {
+		return mrow(mn(mantissa) + mo("&#xD7;") + "<msup><mn>10</mn>" + mn(exp) + "</msup>")
+	}
+
+	return mn(formatFloat(f))
+}
+
+// mn marks up a formatted number, taking its sign out as an operator.
+func mn(s string) string {
+	if strings.HasPrefix(s, "-") {
+		return mrow(mo("-") + "<mn>" + s[1:] + "</mn>")
+	}
+
+	return "<mn>" + s + "</mn>"
+}
+
+func mathmlIdent(name string) string {
+	if letter, ok := greekLetters[name]; ok {
+		return "<mi>" + letter + "</mi>"
+	}
+	if len([]rune(name)) > 1 {
+		return `<mi mathvariant="normal">` + html.EscapeString(name) + "</mi>"
+	}
+
+	return "<mi>" + html.EscapeString(name) + "</mi>"
+}
+
+func mrow(s string) string {
+	return "<mrow>" + s + "</mrow>"
+}
+
+func mo(s string) string {
+	return "<mo>" + s + "</mo>"
+}
@@ -0,0 +1,69 @@
+package calc
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestToMathML(t *testing.T) {
+	t.Run("Should mark up expressions", func(t *testing.T) {
+		testCases := []struct {
+			Input  string
+			MathML string
+		}{
+			{"x + 1", `<mrow><mi>x</mi><mo>+</mo><mn>1</mn></mrow>`},
+			{"1 / x", `<mfrac><mn>1</mn><mi>x</mi></mfrac>`},
+			{"pow(x + 1, 2)", `<msup><mrow><mo>(</mo><mrow><mi>x</mi><mo>+</mo><mn>1</mn></mrow><mo>)</mo></mrow><mn>2</mn></msup>`},
+			{"pow(x, 0.5)", `<msqrt><mi>x</mi></msqrt>`},
+			{"sqrt(x)", `<msqrt><mi>x</mi></msqrt>`},
+			{"pow(pow(x, y), z)", `<msup><mrow><mo>(</mo><msup><mi>x</mi><mi>y</mi></msup><mo>)</mo></mrow><mi>z</mi></msup>`},
+			{"pow(exp(x), 2)", `<msup><mrow><mo>(</mo><msup><mi>e</mi><mi>x</mi></msup><mo>)</mo></mrow><mn>2</mn></msup>`},
+			{"pow(x, pow(y, z))", `<msup><mi>x</mi><msup><mi>y</mi><mi>z</mi></msup></msup>`},
+			{"log(b, x)", `<mrow><msub><mi>log</mi><mi>b</mi></msub><mo>&#x2061;</mo><mi>x</mi></mrow>`},
+			{"pi * r", `<mrow><mi>π</mi><mo>&#x22C5;</mo><mi>r</mi></mrow>`},
+			{"a != b - 1", `<mrow><mi>a</mi><mo>&#x2260;</mo><mrow><mi>b</mi><mo>&#x2212;</mo><mn>1</mn></mrow></mrow>`},
+			{"x < 2", `<mrow><mi>x</mi><mo>&lt;</mo><mn>2</mn></mrow>`},
+			{"1e-9", `<mrow><mn>1</mn><mo>&#xD7;</mo><msup><mn>10</mn><mrow><mo>-</mo><mn>9</mn></mrow></msup></mrow>`},
+		}
+
+		for _, c := range testCases {
+			stmts, err := Parse(c.Input)
+			if err != nil {
+				t.Fatalf("error (%s) not nil in test case %+v", err, c)
+			}
+
+			expected := `<math xmlns="http://www.w3.org/1998/Math/MathML">` + c.MathML + `</math>`
+			if mathml := ToMathML(stmts[0]); mathml != expected {
+				t.Fatalf("%q != %q in test case %+v", mathml, expected, c)
+			}
+		}
+	})
+
+	t.Run("Should produce well formed XML", func(t *testing.T) {
+		testCases := []string{
+			"a = -(x - 2) * f(y, 3) / log2(z)",
+			"exp(ln(x)) + pow(2, -3) - 1e20",
+			"τὰφυσικά * a_1",
+		}
+
+		for _, c := range testCases {
+			stmts, err := Parse(c)
+			if err != nil {
+				t.Fatalf("error (%s) not nil in test case %q", err, c)
+			}
+
+			d := xml.NewDecoder(strings.NewReader(ToMathML(stmts[0])))
+			for {
+				_, err := d.Token()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("error (%s) not nil in test case %q", err, c)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package calc
+
+import "testing"
+
+func TestMultiline(t *testing.T) {
+	t.Run("Should evaluate multi-line programs", func(t *testing.T) {
+		testCases := []struct {
+			Input string
+			Value float64
+		}{
+			{"a = 4\nb = a\na + b", 8},
+			{"a = 4\r\na * 2\r\n", 8},
+			{"\n\n  a = 1\n\n\n  a + 1\n\n", 2},
+			{"a = 1;\nb = 2; a + b", 3},
+			{"# rent\nrent = 1200 # per month\nrent * 12 # per year", 14400},
+			{"2 +\n  3", 5},
+			{"double = x ->\n  x * 2\ndouble(4)", 8},
+			{"reduce(\n  (a, b) -> a + b,\n  [1, 2,\n   3]\n)", 6},
+			{"(1\n+ 2)", 3},
+			{"twenty\none", 1},
+			{"2\nx = 3", 3},
+			{"1 # just a comment with ; and \\n", 1},
+		}
+
+		for _, c := range testCases {
+			v, err := Evaluate(c.Input)
+			if err != nil || v != c.Value {
+				t.Fatalf("%v != %v or error (%s) not nil in test case %+v", v, c.Value, err, c)
+			}
+		}
+	})
+
+	t.Run("Should format multi-line programs", func(t *testing.T) {
+		s, err := Format("a = 4 # the side\nb = a *\n  a")
+		if err != nil || s != "a = 4; b = a * a" {
+			t.Fatalf("%v != a = 4; b = a * a or error (%s) not nil", s, err)
+		}
+	})
+
+	t.Run("Should fail on invalid multi-line programs", func(t *testing.T) {
+		testCases := []string{
+			"# only a comment",
+			"1 +\n",
+			"(1\n",
+			"1\n+ 2",
+		}
+
+		for _, c := range testCases {
+			if v, err := Evaluate(c); err == nil {
+				t.Fatalf("Expected error, got %v in test case %q", v, c)
+			}
+		}
+	})
+}
@@ -0,0 +1,258 @@
+package calc
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// NumberMode is a notation FormatNumber can write numbers in.
+type NumberMode int
+
+const (
+	// Auto writes numbers of everyday magnitudes in positional notation and
+	// the rest in scientific notation, both with up to Digits significant
+	// figures and no trailing zeros.
+	Auto NumberMode = iota
+	// Shortest writes the fewest digits that read back as the same float64.
+	Shortest
+	// Fixed writes Digits decimals.
+	Fixed
+	// Significant writes Digits significant figures in positional notation.
+	Significant
+	// Scientific writes Digits significant figures times a power of ten,
+	// as in 1.5e-9.
+	Scientific
+	// Engineering writes Digits significant figures with an SI prefix, as
+	// in 15k or 1.5n, or with an exponent multiple of three if no prefix is
+	// large or small enough.
+	Engineering
+	// Roman writes integers from 1 to 3999 in Roman numerals, as in MCMXCIV,
+	// and other numbers like Auto.
+	Roman
+	// Words spells numbers in the language of Locale, as in one thousand
+	// two hundred, with up to Digits significant figures like Auto.
+	Words
+	// Base writes integers in Base, with the base as a subscript, as in
+	// FF₁₆, and other numbers like Auto.
+	Base
+)
+
+// autoDigits is how many significant figures Auto writes unless told
+// otherwise. It is less than a float64 holds, so that rounding errors like
+// in 0.1 + 0.2 don't show.
+const autoDigits = 12
+
+// NumberFormat tells FormatNumber how to write numbers.
+type NumberFormat struct {
+	Mode NumberMode
+	// Digits is the number of decimals in Fixed mode and of significant
+	// figures in the others. If 0, Fixed writes no decimals, Auto writes up
+	// to 12 significant figures and the others as many as Shortest does.
+	Digits int
+	// Locale is how numbers are written, Plain if left unset.
+	Locale Locale
+	// Base is the base of Base mode, between 2 and 36, or 16 if 0.
+	Base int
+}
+
+func (nf NumberFormat) base() int {
+	if nf.Base == 0 {
+		return 16
+	}
+
+	return nf.Base
+}
+
+// siPrefixes are the SI prefixes from 10^-24 to 10^24, three powers of ten
+// apart.
+var siPrefixes = []string{"y", "z", "a", "f", "p", "n", "µ", "m", "", "k", "M", "G", "T", "P", "E", "Z", "Y"}
+
+// FormatNumber writes f as nf says.
+func FormatNumber(f float64, nf NumberFormat) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	if nf.Locale.Decimal == 0 {
+		nf.Locale = Plain
+	}
+
+	var s string
+	switch nf.Mode {
+	case Shortest:
+		s = strconv.FormatFloat(f, 'g', -1, 64)
+	case Fixed:
+		s = strconv.FormatFloat(f, 'f', nf.Digits, 64)
+	case Significant:
+		neg, digits, exp := decimalDigits(f, nf.Digits)
+		s = sign(neg) + positional(digits, exp)
+	case Scientific:
+		neg, digits, exp := decimalDigits(f, nf.Digits)
+		s = sign(neg) + scientific(digits, exp)
+	case Engineering:
+		return engineering(f, nf)
+	case Roman:
+		if f == math.Trunc(f) && 1 <= f && f <= maxRoman {
+			return formatRoman(int(f))
+		}
+		s = auto(f, nf.Digits)
+	case Words:
+		if s, ok := spell(f, nf); ok {
+			return s
+		}
+		s = auto(f, nf.Digits)
+	case Base:
+		if f == math.Trunc(f) && math.Abs(f) <= 1<<53 {
+			return formatBase(big.NewInt(int64(f)), nf.base())
+		}
+		s = auto(f, nf.Digits)
+	default:
+		s = auto(f, nf.Digits)
+	}
+
+	return nf.Locale.Localize(s)
+}
+
+// FormatInteger writes the exact integer x as nf says. Modes other than
+// Roman, Words and Base write all of its digits.
+func FormatInteger(x *big.Int, nf NumberFormat) string {
+	if nf.Locale.Decimal == 0 {
+		nf.Locale = Plain
+	}
+
+	switch nf.Mode {
+	case Roman:
+		if x.IsInt64() && 1 <= x.Int64() && x.Int64() <= maxRoman {
+			return formatRoman(int(x.Int64()))
+		}
+	case Words:
+		if s, ok := formatWords(x, nf.Locale); ok {
+			return s
+		}
+	case Base:
+		return formatBase(x, nf.base())
+	}
+
+	return nf.Locale.Localize(x.String())
+}
+
+func auto(f float64, digits int) string {
+	if digits == 0 {
+		digits = autoDigits
+	}
+
+	neg, d, exp := decimalDigits(f, digits)
+	d = trimZeros(d)
+	if f == 0 || -4 <= exp && exp < 15 {
+		return sign(neg) + positional(d, exp)
+	}
+
+	return sign(neg) + scientific(d, exp)
+}
+
+// spell spells f in words, with its decimals one by one, as in three point
+// one four, or fails if it is too large to.
+func spell(f float64, nf NumberFormat) (string, bool) {
+	digits := nf.Digits
+	if digits == 0 {
+		digits = autoDigits
+	}
+
+	neg, d, exp := decimalDigits(f, digits)
+	integer, fraction := positional(trimZeros(d), exp), ""
+	if i := strings.IndexByte(integer, '.'); i >= 0 {
+		integer, fraction = integer[:i], integer[i+1:]
+	}
+
+	x, _ := new(big.Int).SetString(integer, 10)
+	s, ok := formatWords(x, nf.Locale)
+	if !ok {
+		return "", false
+	}
+
+	lang := languageOf(nf.Locale)
+	if neg {
+		s = lang.minus + " " + s
+	}
+	for i, c := range fraction {
+		if i == 0 {
+			s += " " + lang.point
+		}
+		s += " " + lang.digit(int(c-'0'))
+	}
+
+	return s, true
+}
+
+func engineering(f float64, nf NumberFormat) string {
+	neg, digits, exp := decimalDigits(f, nf.Digits)
+
+	exp3 := exp - mod(exp, 3)
+	if f == 0 {
+		exp3 = 0
+	}
+	s := sign(neg) + nf.Locale.Localize(positional(digits, exp-exp3))
+
+	if i := exp3/3 + 8; 0 <= i && i < len(siPrefixes) {
+		return s + siPrefixes[i]
+	}
+
+	return s + "e" + strconv.Itoa(exp3)
+}
+
+// decimalDigits returns the significant digits of f, rounded to sig digits
+// or as many as needed to read back as f if sig is 0, and the exponent of
+// the first one.
+func decimalDigits(f float64, sig int) (neg bool, digits string, exp int) {
+	s := strconv.FormatFloat(math.Abs(f), 'e', sig-1, 64)
+	i := strings.IndexByte(s, 'e')
+	exp, _ = strconv.Atoi(s[i+1:])
+
+	return math.Signbit(f) && f != 0, strings.Replace(s[:i], ".", "", 1), exp
+}
+
+// positional writes digits, the first of which is multiplied by 10^exp,
+// without an exponent.
+func positional(digits string, exp int) string {
+	point := exp + 1
+	switch {
+	case point <= 0:
+		return "0." + strings.Repeat("0", -point) + digits
+	case point >= len(digits):
+		return digits + strings.Repeat("0", point-len(digits))
+	}
+
+	return digits[:point] + "." + digits[point:]
+}
+
+func scientific(digits string, exp int) string {
+	s := digits[:1]
+	if len(digits) > 1 {
+		s += "." + digits[1:]
+	}
+
+	return s + "e" + strconv.Itoa(exp)
+}
+
+func trimZeros(digits string) string {
+	if d := strings.TrimRight(digits, "0"); d != "" {
+		return d
+	}
+
+	return "0"
+}
+
+func sign(neg bool) string {
+	if neg {
+		return "-"
+	}
+
+	return ""
+}
+
+// mod returns the remainder of a divided by b, which is positive even if a
+// is negative.
+func mod(a, b int) int {
+	return (a%b + b) % b
+}
@@ -0,0 +1,84 @@
+package calc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFormatNumber(t *testing.T) {
+	t.Run("Should write numbers in each mode", func(t *testing.T) {
+		testCases := []struct {
+			Number    float64
+			Format    NumberFormat
+			Formatted string
+		}{
+			{0.30000000000000004, NumberFormat{}, "0.3"},
+			{1234567.891, NumberFormat{}, "1234567.891"},
+			{-0.00012, NumberFormat{}, "-0.00012"},
+			{1e15, NumberFormat{}, "1e15"},
+			{6.02214076e23, NumberFormat{}, "6.02214076e23"},
+			{1.5e-9, NumberFormat{}, "1.5e-9"},
+			{math.Pi, NumberFormat{Digits: 4}, "3.142"},
+			{0, NumberFormat{}, "0"},
+			{0.30000000000000004, NumberFormat{Mode: Shortest}, "0.30000000000000004"},
+			{math.Pi, NumberFormat{Mode: Fixed, Digits: 2}, "3.14"},
+			{2.5, NumberFormat{Mode: Fixed}, "2"},
+			{2.5, NumberFormat{Mode: Significant, Digits: 3}, "2.50"},
+			{123456, NumberFormat{Mode: Significant, Digits: 3}, "123000"},
+			{0.000123456, NumberFormat{Mode: Significant, Digits: 2}, "0.00012"},
+			{123456, NumberFormat{Mode: Scientific}, "1.23456e5"},
+			{123456, NumberFormat{Mode: Scientific, Digits: 2}, "1.2e5"},
+			{-0.00042, NumberFormat{Mode: Scientific}, "-4.2e-4"},
+			{15000, NumberFormat{Mode: Engineering}, "15k"},
+			{1.5e-9, NumberFormat{Mode: Engineering}, "1.5n"},
+			{-4.7e-6, NumberFormat{Mode: Engineering}, "-4.7µ"},
+			{999.96, NumberFormat{Mode: Engineering, Digits: 4}, "1.000k"},
+			{123, NumberFormat{Mode: Engineering}, "123"},
+			{0, NumberFormat{Mode: Engineering}, "0"},
+			{2e27, NumberFormat{Mode: Engineering}, "2e27"},
+			{1.5e-30, NumberFormat{Mode: Engineering}, "1.5e-30"},
+			{math.Inf(-1), NumberFormat{}, "-Inf"},
+			{math.NaN(), NumberFormat{Mode: Engineering}, "NaN"},
+		}
+
+		for _, c := range testCases {
+			if formatted := FormatNumber(c.Number, c.Format); formatted != c.Formatted {
+				t.Fatalf("%q != %q in test case %+v", formatted, c.Formatted, c)
+			}
+		}
+	})
+
+	t.Run("Should write numbers in the given locale", func(t *testing.T) {
+		testCases := []struct {
+			Number    float64
+			Format    NumberFormat
+			Formatted string
+		}{
+			{1234567.891, NumberFormat{Locale: German}, "1.234.567,891"},
+			{1234.5, NumberFormat{Mode: Fixed, Digits: 2, Locale: English}, "1,234.50"},
+			{1.5e-9, NumberFormat{Mode: Scientific, Locale: French}, "1,5e-9"},
+			{1.5e18, NumberFormat{Mode: Engineering, Locale: German}, "1,5E"},
+			{1234567, NumberFormat{Mode: Significant, Digits: 2, Locale: Indian}, "12,00,000"},
+		}
+
+		for _, c := range testCases {
+			if formatted := FormatNumber(c.Number, c.Format); formatted != c.Formatted {
+				t.Fatalf("%q != %q in test case %+v", formatted, c.Formatted, c)
+			}
+		}
+	})
+
+	t.Run("Should write numbers that read back the same in Shortest and Auto modes", func(t *testing.T) {
+		testCases := []float64{0.1, 1.0 / 3, 1e-7, 123456789012, -2.5e100}
+
+		for _, c := range testCases {
+			for _, mode := range []NumberMode{Shortest, Auto} {
+				nf := NumberFormat{Mode: mode, Digits: 17}
+				v, err := Evaluate(FormatNumber(c, nf))
+				if err != nil || v != c {
+					t.Fatalf("%v != %v or error (%s) not nil in mode %d", v, c, err, mode)
+				}
+			}
+		}
+	})
+}
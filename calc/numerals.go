@@ -0,0 +1,457 @@
+package calc
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// romanNumerals are the numerals Roman numbers are written with, largest
+// first, including the subtractive pairs like CM for 900.
+var romanNumerals = []struct {
+	value   int
+	numeral string
+}{
+	{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"}, {100, "C"}, {90, "XC"},
+	{50, "L"}, {40, "XL"}, {10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+}
+
+// maxRoman is the largest number Roman numerals can write without the bars
+// that multiply by a thousand.
+const maxRoman = 3999
+
+// formatRoman writes n, between 1 and maxRoman, in Roman numerals.
+func formatRoman(n int) string {
+	var b strings.Builder
+	for _, r := range romanNumerals {
+		for ; n >= r.value; n -= r.value {
+			b.WriteString(r.numeral)
+		}
+	}
+
+	return b.String()
+}
+
+// parseRoman returns the number s writes in Roman numerals. Only the
+// standard form is accepted, so IIII and IC aren't numbers.
+func parseRoman(s string) (int, bool) {
+	n, rest := 0, s
+	for _, r := range romanNumerals {
+		for strings.HasPrefix(rest, r.numeral) {
+			n, rest = n+r.value, rest[len(r.numeral):]
+		}
+	}
+
+	return n, s != "" && n <= maxRoman && formatRoman(n) == s
+}
+
+var subscriptDigits = strings.NewReplacer(
+	"0", "₀", "1", "₁", "2", "₂", "3", "₃", "4", "₄",
+	"5", "₅", "6", "₆", "7", "₇", "8", "₈", "9", "₉",
+)
+
+// formatBase writes x in base, between 2 and 36, with letters for the
+// digits from 10 on and the base as a subscript, as in FF₁₆.
+func formatBase(x *big.Int, base int) string {
+	return strings.ToUpper(x.Text(base)) + subscriptDigits.Replace(fmt.Sprint(base))
+}
+
+// language spells numbers in words.
+type language struct {
+	integer            func(x *big.Int) string // x is positive
+	zero, minus, point string
+}
+
+// maxWords is the number of digits languages spell integers up to, up to
+// the decillion in English.
+const maxWords = 36
+
+// languages are the languages numbers are spelled in, by the name of the
+// locale using them. Other locales spell in English.
+var languages = map[string]language{
+	"en": {englishWords, "zero", "minus", "point"},
+	"de": {germanWords, "null", "minus", "Komma"},
+	"ch": {germanWords, "null", "minus", "Komma"},
+	"fr": {frenchWords, "zéro", "moins", "virgule"},
+}
+
+func languageOf(l Locale) language {
+	if lang, ok := languages[l.Name]; ok {
+		return lang
+	}
+
+	return languages["en"]
+}
+
+// formatWords spells x in the language of l, or fails if x has more than
+// maxWords digits.
+func formatWords(x *big.Int, l Locale) (string, bool) {
+	if digits(x) > maxWords {
+		return "", false
+	}
+
+	lang := languageOf(l)
+	switch x.Sign() {
+	case 0:
+		return lang.digit(0), true
+	case -1:
+		return lang.minus + " " + lang.integer(new(big.Int).Neg(x)), true
+	}
+
+	return lang.integer(x), true
+}
+
+// digit spells the digit d, as in the decimals of 3.14, three point one four.
+func (lang language) digit(d int) string {
+	if d == 0 {
+		return lang.zero
+	}
+
+	return lang.integer(big.NewInt(int64(d)))
+}
+
+// groups splits x into groups of three digits, least significant first.
+func groups(x *big.Int) []int {
+	var gs []int
+	x, g, thousand := new(big.Int).Set(x), new(big.Int), big.NewInt(1000)
+	for x.Sign() > 0 {
+		x.DivMod(x, thousand, g)
+		gs = append(gs, int(g.Int64()))
+	}
+
+	return gs
+}
+
+var (
+	englishOnes = []string{
+		"", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+		"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen",
+		"seventeen", "eighteen", "nineteen",
+	}
+	englishTens = []string{
+		"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety",
+	}
+	englishScales = []string{
+		"", "thousand", "million", "billion", "trillion", "quadrillion", "quintillion",
+		"sextillion", "septillion", "octillion", "nonillion", "decillion",
+	}
+)
+
+// englishWords spells x in English, as in one thousand two hundred
+// thirty-four.
+func englishWords(x *big.Int) string {
+	var words []string
+	gs := groups(x)
+	for i := len(gs) - 1; i >= 0; i-- {
+		if gs[i] == 0 {
+			continue
+		}
+		words = append(words, englishHundreds(gs[i]))
+		if i > 0 {
+			words = append(words, englishScales[i])
+		}
+	}
+
+	return strings.Join(words, " ")
+}
+
+// englishHundreds spells n, between 1 and 999.
+func englishHundreds(n int) string {
+	var words []string
+	if n >= 100 {
+		words = append(words, englishOnes[n/100], "hundred")
+		n %= 100
+	}
+	switch {
+	case n >= 20 && n%10 != 0:
+		words = append(words, englishTens[n/10]+"-"+englishOnes[n%10])
+	case n >= 20:
+		words = append(words, englishTens[n/10])
+	case n > 0:
+		words = append(words, englishOnes[n])
+	}
+
+	return strings.Join(words, " ")
+}
+
+// englishNumbers are the values of the English number words, with scales
+// as powers of a thousand.
+var englishNumbers = func() map[string]int {
+	numbers := map[string]int{"zero": 0, "hundred": 100}
+	for i, w := range englishOnes[1:] {
+		numbers[w] = i + 1
+	}
+	for i, w := range englishTens[2:] {
+		numbers[w] = (i + 2) * 10
+	}
+	for i, w := range englishScales[1:] {
+		numbers[w] = -(i + 1)
+	}
+
+	return numbers
+}()
+
+// isNumberWord tells if w is an English number word.
+func isNumberWord(w string) bool {
+	_, ok := englishNumbers[strings.ToLower(w)]
+	return ok
+}
+
+// isHyphenated tells if the English number words tens and ones are written
+// joined by a hyphen, as in twenty-one.
+func isHyphenated(tens, ones string) bool {
+	t, tok := englishNumbers[strings.ToLower(tens)]
+	o, ook := englishNumbers[strings.ToLower(ones)]
+	return tok && ook && t >= 20 && t < 100 && t%10 == 0 && 1 <= o && o <= 9
+}
+
+// parseWords returns the number spelled by English words, as in one
+// thousand two hundred. Tens and ones may be joined by a hyphen, as in
+// twenty-one, and "and" is ignored, as in one hundred and five.
+func parseWords(words []string) (*big.Int, bool) {
+	total, group := new(big.Int), 0
+	lastScale := len(englishScales)
+	thousand := big.NewInt(1000)
+	for i, w := range words {
+		n, ok := englishNumbers[strings.ToLower(w)]
+		switch {
+		case !ok:
+			return nil, false
+		case n == 0:
+			if len(words) > 1 {
+				return nil, false
+			}
+		case n == 100:
+			if group >= 10 {
+				return nil, false
+			}
+			if group == 0 {
+				group = 1
+			}
+			group *= 100
+		case n < 0:
+			scale := -n
+			if scale >= lastScale || group == 0 && i > 0 {
+				return nil, false
+			}
+			if group == 0 {
+				group = 1
+			}
+			g := new(big.Int).Exp(thousand, big.NewInt(int64(scale)), nil)
+			total.Add(total, g.Mul(g, big.NewInt(int64(group))))
+			group, lastScale = 0, scale
+		case n < 10:
+			if group%10 != 0 || group%100 >= 10 && group%100 < 20 {
+				return nil, false
+			}
+			group += n
+		default:
+			if group%100 != 0 {
+				return nil, false
+			}
+			group += n
+		}
+	}
+
+	return total.Add(total, big.NewInt(int64(group))), true
+}
+
+var (
+	germanOnes = []string{
+		"", "eins", "zwei", "drei", "vier", "fünf", "sechs", "sieben", "acht", "neun",
+		"zehn", "elf", "zwölf", "dreizehn", "vierzehn", "fünfzehn", "sechzehn",
+		"siebzehn", "achtzehn", "neunzehn",
+	}
+	germanTens = []string{
+		"", "", "zwanzig", "dreißig", "vierzig", "fünfzig", "sechzig", "siebzig", "achtzig", "neunzig",
+	}
+	// germanScales are the scales from a million on, in the long scale,
+	// singular and plural.
+	germanScales = [][2]string{
+		{"Million", "Millionen"}, {"Milliarde", "Milliarden"},
+		{"Billion", "Billionen"}, {"Billiarde", "Billiarden"},
+		{"Trillion", "Trillionen"}, {"Trilliarde", "Trilliarden"},
+		{"Quadrillion", "Quadrillionen"}, {"Quadrilliarde", "Quadrilliarden"},
+		{"Quintillion", "Quintillionen"}, {"Quintilliarde", "Quintilliarden"},
+	}
+)
+
+// germanWords spells x in German, as in eintausendzweihundert. Numbers
+// below a million are a single word.
+func germanWords(x *big.Int) string {
+	var words []string
+	gs := groups(x)
+	for i := len(gs) - 1; i >= 2; i-- {
+		switch {
+		case gs[i] == 1:
+			words = append(words, "eine", germanScales[i-2][0])
+		case gs[i] > 1:
+			words = append(words, germanHundreds(gs[i], true), germanScales[i-2][1])
+		}
+	}
+
+	var below string
+	if len(gs) > 1 && gs[1] > 0 {
+		below = germanHundreds(gs[1], false) + "tausend"
+	}
+	if len(gs) > 0 && gs[0] > 0 {
+		below += germanHundreds(gs[0], true)
+	}
+	if below != "" {
+		words = append(words, below)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// germanHundreds spells n, between 1 and 999. One is eins if it is the end
+// of the number and ein otherwise.
+func germanHundreds(n int, end bool) string {
+	var s string
+	if n >= 100 {
+		s = germanOne(n/100) + "hundert"
+		n %= 100
+	}
+	switch {
+	case n == 1 && !end:
+		s += "ein"
+	case n >= 20 && n%10 != 0:
+		s += germanOne(n%10) + "und" + germanTens[n/10]
+	case n >= 20:
+		s += germanTens[n/10]
+	case n > 0:
+		s += germanOnes[n]
+	}
+
+	return s
+}
+
+// germanOne spells n, between 1 and 9, in compounds like einhundert.
+func germanOne(n int) string {
+	if n == 1 {
+		return "ein"
+	}
+
+	return germanOnes[n]
+}
+
+var (
+	frenchOnes = []string{
+		"", "un", "deux", "trois", "quatre", "cinq", "six", "sept", "huit", "neuf",
+		"dix", "onze", "douze", "treize", "quatorze", "quinze", "seize",
+		"dix-sept", "dix-huit", "dix-neuf",
+	}
+	frenchTens = []string{
+		"", "", "vingt", "trente", "quarante", "cinquante", "soixante",
+	}
+	// frenchScales are the scales from a million on, in the long scale.
+	frenchScales = []string{
+		"million", "milliard", "billion", "billiard", "trillion", "trilliard",
+		"quadrillion", "quadrilliard", "quintillion", "quintilliard",
+	}
+)
+
+// frenchWords spells x in French, as in mille deux cents, following the
+// spelling of before the 1990 reform.
+func frenchWords(x *big.Int) string {
+	var words []string
+	gs := groups(x)
+	for i := len(gs) - 1; i >= 0; i-- {
+		g := gs[i]
+		switch {
+		case g == 0:
+		case i == 0:
+			words = append(words, frenchHundreds(g, true))
+		case i == 1 && g == 1:
+			words = append(words, "mille")
+		case i == 1:
+			// Mille is an adjective, which cent and vingt don't agree with.
+			words = append(words, frenchHundreds(g, false), "mille")
+		case g == 1:
+			words = append(words, "un", frenchScales[i-2])
+		default:
+			words = append(words, frenchHundreds(g, true), frenchScales[i-2]+"s")
+		}
+	}
+
+	return strings.Join(words, " ")
+}
+
+// frenchHundreds spells n, between 1 and 999. The cents of deux cents and
+// the vingts of quatre-vingts are plural only if plural is true.
+func frenchHundreds(n int, plural bool) string {
+	var words []string
+	if n >= 100 {
+		h := "cent"
+		if n/100 > 1 {
+			h = frenchOnes[n/100] + " cent"
+			if n%100 == 0 && plural {
+				h += "s"
+			}
+		}
+		words = append(words, h)
+		n %= 100
+	}
+
+	t, u := n/10, n%10
+	switch {
+	case n == 0:
+	case n < 20:
+		words = append(words, frenchOnes[n])
+	case t == 8 && u == 0 && plural:
+		words = append(words, "quatre-vingts")
+	case t == 8 && u == 0:
+		words = append(words, "quatre-vingt")
+	case t >= 8:
+		words = append(words, "quatre-vingt-"+frenchOnes[n-80])
+	case t == 7 && u == 1:
+		words = append(words, "soixante et onze")
+	case t == 7:
+		words = append(words, "soixante-"+frenchOnes[n-60])
+	case u == 0:
+		words = append(words, frenchTens[t])
+	case u == 1:
+		words = append(words, frenchTens[t]+" et un")
+	default:
+		words = append(words, frenchTens[t]+"-"+frenchOnes[u])
+	}
+
+	return strings.Join(words, " ")
+}
+
+var numeralBuiltins = map[string]integerBuiltin{
+	"roman": {1, false, roman},
+	"words": {1, false, words},
+	"base":  {2, false, inBase},
+}
+
+// roman implements the roman(n) builtin.
+func roman(e *Evaluator, args []*big.Int) (Value, error) {
+	n := args[0]
+	if !n.IsInt64() || n.Int64() < 1 || n.Int64() > maxRoman {
+		return nil, fmt.Errorf("Roman numerals go from 1 to %d, got %s", maxRoman, n)
+	}
+
+	return Text(formatRoman(int(n.Int64()))), nil
+}
+
+// words implements the words(n) builtin, which spells n in the language of
+// the evaluator's locale.
+func words(e *Evaluator, args []*big.Int) (Value, error) {
+	s, ok := formatWords(args[0], e.locale)
+	if !ok {
+		return nil, fmt.Errorf("Only numbers of up to %d digits can be spelled", maxWords)
+	}
+
+	return Text(s), nil
+}
+
+// inBase implements the base(n, b) builtin.
+func inBase(e *Evaluator, args []*big.Int) (Value, error) {
+	b := args[1]
+	if !b.IsInt64() || b.Int64() < 2 || b.Int64() > 36 {
+		return nil, fmt.Errorf("Bases go from 2 to 36, got %s", b)
+	}
+
+	return Text(formatBase(args[0], int(b.Int64()))), nil
+}
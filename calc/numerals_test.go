@@ -0,0 +1,236 @@
+package calc
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestNumerals(t *testing.T) {
+	t.Run("Should read numerals", func(t *testing.T) {
+		testCases := []struct {
+			Input string
+			Value float64
+		}{
+			{"MCMXCIV + 6", 2000},
+			{"XIV", 14},
+			{"MMMCMXCIX", 3999},
+			{"X = 3; X + IV", 7},
+			{"one thousand two hundred", 1200},
+			{"One hundred and five", 105},
+			{"twenty-one * 2", 42},
+			{"twenty-one", 21},
+			{"twenty - one", 19},
+			{"twenty one", 21},
+			{"million", 1e6},
+			{"three million four hundred thousand nine", 3400009},
+			{"zero + 1", 1},
+			{"513₇", 255},
+			{"FF₁₆ + ff₁₆", 510},
+			{"1010₂", 10},
+			{"ZZ₃₆", 1295},
+			{"0xFF", 255},
+			{"0b1010 + 0o17", 25},
+			{"x = 2; 0x", 0},
+		}
+
+		for _, c := range testCases {
+			v, err := Evaluate(c.Input)
+			if err != nil || v != c.Value {
+				t.Fatalf("%v != %v or error (%s) not nil in test case %+v", v, c.Value, err, c)
+			}
+		}
+	})
+
+	t.Run("Should read large numerals exactly", func(t *testing.T) {
+		testCases := []struct {
+			Input string
+			Value string
+		}{
+			{"0xFFFFFFFFFFFFFFFFFF", "4722366482869645213695"},
+			{"nine hundred ninety-nine decillion one", "999000000000000000000000000000000001"},
+		}
+
+		for _, c := range testCases {
+			v, err := NewEvaluator().Evaluate(c.Input)
+			if err != nil || v.String() != c.Value {
+				t.Fatalf("%v != %v or error (%s) not nil in test case %+v", v, c.Value, err, c)
+			}
+		}
+	})
+
+	t.Run("Should fail on malformed numerals", func(t *testing.T) {
+		testCases := []string{
+			"one one",
+			"ten five",
+			"thousand million",
+			"one hundred hundred",
+			"12₁",
+			"12₃₇",
+			"19₈",
+			"0xFG",
+		}
+
+		for _, c := range testCases {
+			if _, err := Evaluate(c); err == nil {
+				t.Fatalf("error is nil for %q", c)
+			}
+		}
+	})
+
+	t.Run("Should leave names as names", func(t *testing.T) {
+		testCases := []string{"IIII", "IC", "C", "MIXED", "oneself", "b2"}
+
+		for _, c := range testCases {
+			stmts, err := Parse(c)
+			if _, ok := stmts[0].(*Ident); err != nil || !ok {
+				t.Fatalf("%v is not a name or error (%s) not nil for %q", stmts, err, c)
+			}
+		}
+	})
+
+	t.Run("Should leave variables that look like numerals as names", func(t *testing.T) {
+		testCases := []struct {
+			Input string
+			Value string
+		}{
+			{"one = 1; one", "1"},
+			{"ten = 3; ten * 2", "6"},
+			{"zero = 5; hundred = 2; million = 3; zero + hundred + million", "10"},
+			{"hundred = 5; two hundred", "10"},
+			{"f = one -> one + 1; f(2)", "3"},
+			{"[ten * 2 for ten in [1, 2]]", "[2, 4]"},
+			{"(ten, one) -> ten - one", "(ten, one) -> ten - one"},
+			{"CD = 1; DC = 2; MIX = 3; MM = 4; IV = 5; CD + DC + MIX + MM + IV", "15"},
+			{"one thousand two hundred", "1200"},
+			{"MCMXCIV", "1994"},
+		}
+
+		for _, c := range testCases {
+			v, err := NewEvaluator().Evaluate(c.Input)
+			if err != nil || v.String() != c.Value {
+				t.Fatalf("%v != %v or error (%s) not nil in test case %+v", v, c.Value, err, c)
+			}
+		}
+
+		e := NewEvaluator()
+		if _, err := e.Evaluate("MM = 2; ten = 10"); err != nil {
+			t.Fatalf("error (%s) not nil assigning MM and ten", err)
+		}
+		if v, err := e.Evaluate("MM * ten"); err != nil || v.String() != "20" {
+			t.Fatalf("%v != 20 or error (%s) not nil using MM and ten in another program", v, err)
+		}
+	})
+
+	t.Run("Should write numerals", func(t *testing.T) {
+		testCases := []struct {
+			Input  string
+			Locale Locale
+			Text   string
+		}{
+			{"roman(1994)", Plain, "MCMXCIV"},
+			{"roman(3999)", Plain, "MMMCMXCIX"},
+			{"base(255, 7)", Plain, "513₇"},
+			{"base(-255, 16)", Plain, "-FF₁₆"},
+			{"base(0x400000000000000000, 2)", Plain, "10000000000000000000000000000000000000000000000000000000000000000000000₂"},
+			{"words(0)", English, "zero"},
+			{"words(1200)", English, "one thousand two hundred"},
+			{"words(-21)", English, "minus twenty-one"},
+			{"words(1000001)", English, "one million one"},
+			{"words(999999)", English, "nine hundred ninety-nine thousand nine hundred ninety-nine"},
+			{"words(1200)", German, "eintausendzweihundert"},
+			{"words(21)", German, "einundzwanzig"},
+			{"words(101)", German, "einhunderteins"},
+			{"words(2000001)", German, "zwei Millionen eins"},
+			{"words(1000000000)", German, "eine Milliarde"},
+			{"words(1200)", French, "mille deux cents"},
+			{"words(71)", French, "soixante et onze"},
+			{"words(80)", French, "quatre-vingts"},
+			{"words(91)", French, "quatre-vingt-onze"},
+			{"words(80200)", French, "quatre-vingt mille deux cents"},
+			{"words(200000000)", French, "deux cents millions"},
+			{"words(201)", French, "deux cent un"},
+		}
+
+		for _, c := range testCases {
+			v, err := NewEvaluator(WithLocale(c.Locale)).Evaluate(c.Input)
+			if err != nil || v != Text(c.Text) {
+				t.Fatalf("%q != %q or error (%s) not nil in test case %+v", v, c.Text, err, c)
+			}
+		}
+	})
+
+	t.Run("Should fail to write out of range", func(t *testing.T) {
+		testCases := []string{"roman(0)", "roman(4000)", "roman(1.5)", "base(10, 1)", "base(10, 37)", "words(10^36)"}
+
+		for _, c := range testCases {
+			if _, err := NewEvaluator().Evaluate(c); err == nil {
+				t.Fatalf("error is nil for %q", c)
+			}
+		}
+	})
+
+	t.Run("Should read what it writes", func(t *testing.T) {
+		for n := int64(1); n <= maxRoman; n += 7 {
+			if v, ok := parseRoman(formatRoman(int(n))); !ok || int64(v) != n {
+				t.Fatalf("%d != %d", v, n)
+			}
+
+			x := big.NewInt(n * 1000003)
+			for _, s := range []string{formatBase(x, 2), formatBase(x, 7), formatBase(x, 36), englishWords(x)} {
+				v, err := Evaluate(s)
+				if err != nil || v != float64(x.Int64()) {
+					t.Fatalf("%v != %s or error (%s) not nil for %q", v, x, err, s)
+				}
+			}
+		}
+	})
+}
+
+func TestNumeralFormats(t *testing.T) {
+	t.Run("Should format numbers as numerals", func(t *testing.T) {
+		testCases := []struct {
+			Number    float64
+			Format    NumberFormat
+			Formatted string
+		}{
+			{1994, NumberFormat{Mode: Roman}, "MCMXCIV"},
+			{1994.5, NumberFormat{Mode: Roman}, "1994.5"},
+			{1e6, NumberFormat{Mode: Roman, Locale: English}, "1,000,000"},
+			{255, NumberFormat{Mode: Base}, "FF₁₆"},
+			{255, NumberFormat{Mode: Base, Base: 2}, "11111111₂"},
+			{0.5, NumberFormat{Mode: Base, Base: 2}, "0.5"},
+			{1200, NumberFormat{Mode: Words}, "one thousand two hundred"},
+			{3.14, NumberFormat{Mode: Words}, "three point one four"},
+			{-0.05, NumberFormat{Mode: Words}, "minus zero point zero five"},
+			{0.1 + 0.2, NumberFormat{Mode: Words}, "zero point three"},
+			{2.5, NumberFormat{Mode: Words, Locale: German}, "zwei Komma fünf"},
+			{1.01, NumberFormat{Mode: Words, Locale: French}, "un virgule zéro un"},
+			{1e40, NumberFormat{Mode: Words}, "1e40"},
+		}
+
+		for _, c := range testCases {
+			if s := FormatNumber(c.Number, c.Format); s != c.Formatted {
+				t.Fatalf("%q != %q in test case %+v", s, c.Formatted, c)
+			}
+		}
+	})
+
+	t.Run("Should format integers as numerals", func(t *testing.T) {
+		x, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+		testCases := []struct {
+			Format    NumberFormat
+			Formatted string
+		}{
+			{NumberFormat{Mode: Auto, Locale: English}, "123,456,789,012,345,678,901,234,567,890"},
+			{NumberFormat{Mode: Roman}, "123456789012345678901234567890"},
+			{NumberFormat{Mode: Base, Base: 36}, "BYW97UM9S91DLZ68TSI₃₆"},
+			{NumberFormat{Mode: Words}, "one hundred twenty-three octillion four hundred fifty-six septillion seven hundred eighty-nine sextillion twelve quintillion three hundred forty-five quadrillion six hundred seventy-eight trillion nine hundred one billion two hundred thirty-four million five hundred sixty-seven thousand eight hundred ninety"},
+		}
+
+		for _, c := range testCases {
+			if s := FormatInteger(x, c.Format); s != c.Formatted {
+				t.Fatalf("%q != %q in test case %+v", s, c.Formatted, c)
+			}
+		}
+	})
+}
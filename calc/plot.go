@@ -0,0 +1,172 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// plotSamples is how many points of each series plot() evaluates.
+const plotSamples = 500
+
+// Point is a sample of a plotted function.
+type Point struct {
+	X, Y float64
+}
+
+// Series is a function sampled over the range of a plot. Y is NaN where the
+// function is undefined.
+type Series struct {
+	Label  string
+	Points []Point
+}
+
+// Plot is the value of plot(f, g, ..., x, from, to): the functions f, g, ...
+// of x sampled from x = from to x = to.
+type Plot struct {
+	Var      string
+	From, To float64
+	Series   []Series
+}
+
+func (p *Plot) String() string {
+	labels := make([]string, len(p.Series))
+	for i, s := range p.Series {
+		labels[i] = s.Label
+	}
+
+	return fmt.Sprintf("plot of %s for %s from %s to %s", strings.Join(labels, ", "), p.Var, formatFloat(p.From), formatFloat(p.To))
+}
+
+// plot implements the plot builtin. Its arguments are one or more
+// expressions followed by the variable they're functions of and the range
+// to plot them over.
+func (e *Evaluator) plot(n *CallExpr) (Value, error) {
+	if len(n.Args) < 4 {
+		return nil, fmt.Errorf("plot takes at least 4 arguments, e.g. plot(sin(x), x, 0, 10)")
+	}
+
+	exprs, args := n.Args[:len(n.Args)-3], n.Args[len(n.Args)-3:]
+	v, ok := args[0].(*Ident)
+	if !ok {
+		return nil, fmt.Errorf("The variable to plot over must be a name, not %s", args[0])
+	}
+	if _, ok := e.constant(v.Name); ok {
+		return nil, fmt.Errorf("Can't plot over %s, it is a constant", v.Name)
+	}
+
+	from, err := e.evalNumber(args[1])
+	if err != nil {
+		return nil, err
+	}
+	to, err := e.evalNumber(args[2])
+	if err != nil {
+		return nil, err
+	}
+	if !(from < to) {
+		return nil, fmt.Errorf("Can't plot from %s to %s", formatFloat(from), formatFloat(to))
+	}
+
+	// The variable is bound while sampling and restored afterwards.
+	old, defined := e.vars[v.Name]
+	defer func() {
+		if defined {
+			e.vars[v.Name] = old
+		} else {
+			delete(e.vars, v.Name)
+		}
+	}()
+
+	// Functions are undefined at some points, like 1/x at 0, which are left
+	// out of the plot rather than failing it.
+	policy := e.domain
+	e.domain = SpecialValues
+	defer func() {
+		e.domain = policy
+	}()
+
+	p := &Plot{Var: v.Name, From: from, To: to}
+	for _, expr := range exprs {
+		f := e.sampler(expr, v.Name)
+		s := Series{Label: expr.String(), Points: make([]Point, plotSamples)}
+		for i := range s.Points {
+			x := from + (to-from)*float64(i)/(plotSamples-1)
+			y, err := f(x)
+			if err != nil {
+				return nil, err
+			}
+			s.Points[i] = Point{x, y}
+		}
+		p.Series = append(p.Series, s)
+	}
+
+	return p, nil
+}
+
+// sampler returns the function of x that expr is. It runs expr compiled
+// when it can, which is when expr doesn't assign variables and the ones it
+// uses, other than x, are numbers. Previous results like ans are looked up
+// like variables. Compiled samples count one operation per instruction
+// against the limits of the evaluation, as interpreted ones do per node.
+func (e *Evaluator) sampler(expr Node, x string) func(float64) (float64, error) {
+	interpret := func(f float64) (float64, error) {
+		e.vars[x] = Number(f)
+		return e.evalNumber(expr)
+	}
+
+	p, err := e.compile([]Node{expr})
+	if err != nil {
+		return interpret
+	}
+	for _, in := range p.code {
+		if in.op == opStore {
+			return interpret
+		}
+	}
+
+	values := make([]float64, len(p.vars))
+	slot := -1
+	for i, name := range p.vars {
+		if name == x {
+			slot = i
+			continue
+		}
+		v, defined, err := e.lookup(name)
+		if err != nil {
+			// Interpreting reports the error.
+			return interpret
+		}
+		f, ok := toFloat(v).(Number)
+		if defined && !ok {
+			return interpret
+		}
+		values[i] = float64(f)
+	}
+
+	return func(f float64) (float64, error) {
+		for range p.code {
+			if err := e.tick(); err != nil {
+				return math.NaN(), err
+			}
+		}
+		if slot >= 0 {
+			values[slot] = f
+		}
+		return p.Eval(values...)
+	}
+}
+
+// evalNumber evaluates n, failing if its value isn't a number.
+func (e *Evaluator) evalNumber(n Node) (float64, error) {
+	v, err := e.eval(n)
+	if err != nil {
+		return math.NaN(), err
+	}
+
+	f, ok := toFloat(v).(Number)
+	if !ok {
+		return math.NaN(), fmt.Errorf("%s is not a number", v)
+	}
+
+	return float64(f), nil
+}
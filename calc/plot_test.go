@@ -0,0 +1,93 @@
+package calc
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestPlot(t *testing.T) {
+	t.Run("Should sample every series over the range", func(t *testing.T) {
+		v, err := NewEvaluator().Evaluate("plot(x, pow(x, 2), x, -1, 1)")
+		if err != nil {
+			t.Fatalf("error (%s) not nil", err)
+		}
+
+		p := v.(*Plot)
+		if len(p.Series) != 2 || p.Series[0].Label != "x" || p.Series[1].Label != "x^2" {
+			t.Fatalf("wrong series in %s", p)
+		}
+
+		for _, s := range p.Series {
+			first, last := s.Points[0], s.Points[len(s.Points)-1]
+			if first.X != -1 || last.X != 1 {
+				t.Fatalf("%s sampled from %f to %f", s.Label, first.X, last.X)
+			}
+		}
+
+		for _, pt := range p.Series[1].Points {
+			if !floatEquals(pt.Y, pt.X*pt.X, 1e-12) {
+				t.Fatalf("pow(x, 2) is %f at %f", pt.Y, pt.X)
+			}
+		}
+	})
+
+	t.Run("Should leave the plotted variable as it was", func(t *testing.T) {
+		testCases := []struct {
+			Input string
+			Value float64
+		}{
+			{"x = 5; plot(x, x, 0, 1); x", 5},
+			{"plot(x, x, 0, 1); x", 0},
+			{"a = 2; plot(a * x, x, 0, 1); a", 2},
+		}
+
+		for _, c := range testCases {
+			result, err := Evaluate(c.Input)
+			if err != nil || result != c.Value {
+				t.Fatalf("%f != %f or error (%s) not nil in test case %+v", result, c.Value, err, c)
+			}
+		}
+	})
+
+	t.Run("Should mark undefined points as NaN", func(t *testing.T) {
+		v, err := NewEvaluator().Evaluate("plot(sqrt(x), x, -1, 1)")
+		if err != nil {
+			t.Fatalf("error (%s) not nil", err)
+		}
+
+		if y := v.(*Plot).Series[0].Points[0].Y; !math.IsNaN(y) {
+			t.Fatalf("sqrt(-1) plotted as %f", y)
+		}
+	})
+
+	t.Run("Should sample within the limits of the evaluation", func(t *testing.T) {
+		for _, c := range []string{"plot(sin(x) * x + 1, x, 0, 1)", "f = x -> x + 1; plot(f(x), x, 0, 1)"} {
+			_, err := NewEvaluator(WithLimits(Limits{MaxOperations: 1000})).Evaluate(c)
+			if err == nil || err.Error() != (&TooManyOperationsError{1000}).Error() {
+				t.Fatalf("error (%v) is not a TooManyOperationsError for %q", err, c)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if _, err := NewEvaluator().EvaluateContext(ctx, c); err != context.Canceled {
+				t.Fatalf("error (%v) != %v for %q", err, context.Canceled, c)
+			}
+		}
+	})
+
+	t.Run("Should reject bad arguments", func(t *testing.T) {
+		testCases := []string{
+			"plot(x, x, 0)",
+			"plot(x, 2, 0, 1)",
+			"plot(x, x, 1, 0)",
+			"plot(x, x, 0, 1) + 1",
+		}
+
+		for _, c := range testCases {
+			if v, err := NewEvaluator().Evaluate(c); err == nil {
+				t.Fatalf("%q evaluated to %s instead of failing", c, v)
+			}
+		}
+	})
+}
@@ -0,0 +1,292 @@
+package calc
+
+import "fmt"
+
+// Program is a program compiled to be evaluated many times, with different
+// values of its variables, without parsing it again. It runs on a small
+// stack machine and only computes with float64 numbers, so it can't use
+// lists, dice, special forms or builtins of integers, money or random
+// numbers. It follows the domain policy of the evaluator that compiled it.
+// A Program is safe for concurrent use.
+type Program struct {
+	code   []instr
+	consts []float64
+	funcs  []builtin
+	vars   []string // names of the variable slots
+	stack  int      // the deepest the stack gets
+
+	domain DomainPolicy
+	exprs  []Node // the expression of each instruction, for domain errors
+}
+
+type opcode uint8
+
+const (
+	opConst opcode = iota // push consts[arg]
+	opLoad                // push the variable in slot arg
+	opStore               // store the top of the stack in slot arg
+	opPop                 // drop the top of the stack
+	opNeg                 // negate the top of the stack
+	opAdd                 // replace the top two values by their sum
+	opSub                 // by their difference, and so on
+	opMul
+	opDiv
+	opMod
+	opLess
+	opGreater
+	opLessEq
+	opGreaterEq
+	opEqual
+	opNotEqual
+	opCall // replace the top values by funcs[arg] applied to them
+)
+
+type instr struct {
+	op  opcode
+	arg uint16
+}
+
+// maxOperands is how many constants, functions or variables a Program may
+// have, as many as an instruction's argument can tell apart.
+const maxOperands = 1 << 16
+
+// Compile parses program and compiles it into a Program.
+func Compile(program string) (*Program, error) {
+	return NewEvaluator().Compile(program)
+}
+
+// Compile parses program, following the conventions the evaluator was
+// configured with, and compiles it into a Program. The constants of the
+// evaluator are fixed at their current values, but its variables are not
+// shared with the Program.
+func (e *Evaluator) Compile(program string) (*Program, error) {
+	stmts, err := e.Parse(program)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.compile(stmts)
+}
+
+func (e *Evaluator) compile(stmts []Node) (*Program, error) {
+	c := &compiler{e: e, p: &Program{domain: e.domain}, slots: make(map[string]int)}
+	for i, stmt := range stmts {
+		if i > 0 {
+			c.emit(nil, opPop, 0, -1)
+		}
+		if err := c.compile(stmt); err != nil {
+			return nil, err
+		}
+	}
+	if len(c.p.consts) > maxOperands || len(c.p.funcs) > maxOperands || len(c.p.vars) > maxOperands {
+		return nil, fmt.Errorf("Program is too large to compile")
+	}
+
+	return c.p, nil
+}
+
+// Vars returns the names of the variables of p, both the ones it assigns
+// and the ones it expects values for, in the order Eval takes their values.
+func (p *Program) Vars() []string {
+	return append([]string(nil), p.vars...)
+}
+
+// Eval runs p with its variables bound to values, in the order of Vars, and
+// returns the value of the last statement. Variables not given a value are
+// 0, as in the Evaluator.
+func (p *Program) Eval(values ...float64) (float64, error) {
+	if len(values) > len(p.vars) {
+		return 0, fmt.Errorf("Program has %d variable(s), got %d value(s)", len(p.vars), len(values))
+	}
+
+	mem := make([]float64, len(p.vars)+p.stack)
+	slots, stack := mem[:len(p.vars)], mem[len(p.vars):]
+	copy(slots, values)
+
+	sp := 0
+	for pc, in := range p.code {
+		switch in.op {
+		case opConst:
+			stack[sp] = p.consts[in.arg]
+			sp++
+		case opLoad:
+			stack[sp] = slots[in.arg]
+			sp++
+		case opStore:
+			slots[in.arg] = stack[sp-1]
+		case opPop:
+			sp--
+		case opNeg:
+			stack[sp-1] = -stack[sp-1]
+		case opCall:
+			f := p.funcs[in.arg]
+			sp -= f.arity - 1
+			args := stack[sp-1 : sp-1+f.arity]
+			if err := p.checkBuiltin(pc, args); err != nil {
+				return 0, err
+			}
+			stack[sp-1] = f.fn(args...)
+		default:
+			sp--
+			if (in.op == opDiv || in.op == opMod) && stack[sp] == 0 && p.domain == DomainErrors {
+				n := p.exprs[pc].(*BinaryExpr)
+				return 0, &DomainError{DivisionByZero, n.String(), n.Pos}
+			}
+			stack[sp-1] = binaryOp(in.op, stack[sp-1], stack[sp])
+		}
+	}
+
+	return stack[0], nil
+}
+
+// checkBuiltin is Evaluator.checkBuiltin for the call at pc.
+func (p *Program) checkBuiltin(pc int, args []float64) error {
+	if p.domain == SpecialValues {
+		return nil
+	}
+
+	n := p.exprs[pc].(*CallExpr)
+	if kind, ok := builtinDomain(n.Func, args); !ok {
+		return &DomainError{kind, n.String(), n.Pos}
+	}
+
+	return nil
+}
+
+func binaryOp(op opcode, a, b float64) float64 {
+	switch op {
+	case opAdd:
+		return a + b
+	case opSub:
+		return a - b
+	case opMul:
+		return a * b
+	case opDiv:
+		return a / b
+	case opMod:
+		return floorMod(a, b)
+	case opLess:
+		return float64(truth(a < b))
+	case opGreater:
+		return float64(truth(a > b))
+	case opLessEq:
+		return float64(truth(a <= b))
+	case opGreaterEq:
+		return float64(truth(a >= b))
+	case opEqual:
+		return float64(truth(a == b))
+	case opNotEqual:
+		return float64(truth(a != b))
+	}
+
+	panic(fmt.Sprintf("calc: unknown opcode %d", op))
+}
+
+var binaryOpcodes = map[string]opcode{
+	"+":  opAdd,
+	"-":  opSub,
+	"*":  opMul,
+	"/":  opDiv,
+	"%":  opMod,
+	"<":  opLess,
+	">":  opGreater,
+	"<=": opLessEq,
+	">=": opGreaterEq,
+	"==": opEqual,
+	"!=": opNotEqual,
+}
+
+// compiler lowers syntax trees to the code of a Program.
+type compiler struct {
+	e     *Evaluator
+	p     *Program
+	slots map[string]int // slot of each variable, by name
+	depth int            // stack depth after the code emitted so far
+}
+
+// emit appends an instruction, compiled from n, that changes the depth of
+// the stack by delta.
+func (c *compiler) emit(n Node, op opcode, arg int, delta int) {
+	c.p.code = append(c.p.code, instr{op, uint16(arg)})
+	c.p.exprs = append(c.p.exprs, n)
+
+	c.depth += delta
+	if c.depth > c.p.stack {
+		c.p.stack = c.depth
+	}
+}
+
+func (c *compiler) slot(name string) int {
+	if i, ok := c.slots[name]; ok {
+		return i
+	}
+
+	c.slots[name] = len(c.p.vars)
+	c.p.vars = append(c.p.vars, name)
+	return len(c.p.vars) - 1
+}
+
+func (c *compiler) constant(f float64) {
+	c.emit(nil, opConst, len(c.p.consts), 1)
+	c.p.consts = append(c.p.consts, f)
+}
+
+func (c *compiler) compile(n Node) error {
+	switch n := n.(type) {
+	case *NumberLit:
+		c.constant(n.Value)
+	case *Ident:
+		if k, ok := c.e.constant(n.Name); ok {
+			c.constant(k.Value)
+		} else {
+			c.emit(n, opLoad, c.slot(n.Name), 1)
+		}
+	case *UnaryExpr:
+		if n.Op != "-" {
+			return fmt.Errorf("Unknown operator %s", n.Op)
+		}
+		if err := c.compile(n.X); err != nil {
+			return err
+		}
+		c.emit(n, opNeg, 0, 0)
+	case *BinaryExpr:
+		op, ok := binaryOpcodes[n.Op]
+		if !ok {
+			return fmt.Errorf("%s can't be compiled, only numbers can", n)
+		}
+		if err := c.compile(n.X); err != nil {
+			return err
+		}
+		if err := c.compile(n.Y); err != nil {
+			return err
+		}
+		c.emit(n, op, 0, -1)
+	case *CallExpr:
+		f, ok := builtins[n.Func]
+		if !ok {
+			return fmt.Errorf("%s can't be compiled, only functions of numbers can", n.Func)
+		}
+		if len(n.Args) != f.arity {
+			return fmt.Errorf("%s takes %d argument(s), got %d", n.Func, f.arity, len(n.Args))
+		}
+		for _, arg := range n.Args {
+			if err := c.compile(arg); err != nil {
+				return err
+			}
+		}
+		c.emit(n, opCall, len(c.p.funcs), 1-f.arity)
+		c.p.funcs = append(c.p.funcs, f)
+	case *AssignExpr:
+		if _, ok := c.e.constant(n.Name); ok {
+			return fmt.Errorf("%s is a constant and can't be reassigned", n.Name)
+		}
+		if err := c.compile(n.Value); err != nil {
+			return err
+		}
+		c.emit(n, opStore, c.slot(n.Name), 0)
+	default:
+		return fmt.Errorf("%s can't be compiled, only numbers can", n)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestProgram(t *testing.T) {
+	t.Run("Should evaluate like the evaluator", func(t *testing.T) {
+		testCases := []struct {
+			Program string
+			X       float64
+		}{
+			{"1 + 2 * 3", 0},
+			{"x * x - 2x + 1", 3},
+			{"-x / 4", 2},
+			{"a = x + 1; b = a * a; b - a", 5},
+			{"pow(x, 2) + log(2, 8) - sqrt(x)", 16},
+			{"sin(pi * x) + e", 0.5},
+			{"x < 2; x >= 2", 2},
+			{"(x > 0) * x", -3},
+			{"y + x", 1},
+		}
+
+		for _, c := range testCases {
+			p, err := Compile(c.Program)
+			if err != nil {
+				t.Fatalf("error (%s) not nil in test case %+v", err, c)
+			}
+
+			values := []float64{c.X}
+			if len(p.Vars()) == 0 {
+				values = nil
+			}
+
+			expected, _ := Evaluate(fmt.Sprintf("x = %g; %s", c.X, c.Program))
+			if actual, err := p.Eval(values...); err != nil || !floatEquals(actual, expected, 1e-12) {
+				t.Fatalf("%f != %f or error (%s) not nil in test case %+v", actual, expected, err, c)
+			}
+		}
+	})
+
+	t.Run("Should list the variables in order of appearance", func(t *testing.T) {
+		p, err := Compile("a = (x * y); a + z + pi")
+		if err != nil {
+			t.Fatalf("error (%s) not nil", err)
+		}
+
+		if vars := fmt.Sprint(p.Vars()); vars != "[x y a z]" {
+			t.Fatalf("%s != [x y a z]", vars)
+		}
+		if v, err := p.Eval(2, 3); err != nil || v != 6+math.Pi {
+			t.Fatalf("%f != 6 + pi or error (%s) not nil", v, err)
+		}
+		if _, err := p.Eval(1, 2, 3, 4, 5); err == nil {
+			t.Fatalf("error is nil for too many values")
+		}
+	})
+
+	t.Run("Should fail to compile what isn't arithmetic", func(t *testing.T) {
+		testCases := []string{
+			"[1, 2]",
+			"gcd(4, 6)",
+			"3d6",
+			"simplify(x + x)",
+			"log(2)",
+			"pi = 3",
+			"1 +",
+		}
+
+		for _, c := range testCases {
+			if _, err := Compile(c); err == nil {
+				t.Fatalf("error is nil for %q", c)
+			}
+		}
+	})
+}
+
+const benchmarkExpr = "x * x - 2 * x + sin(x) / (1 + pow(x, 2))"
+
+func BenchmarkProgram(b *testing.B) {
+	p, err := Compile(benchmarkExpr)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 0; i < b.N; i++ {
+		p.Eval(float64(i))
+	}
+}
+
+// BenchmarkEvaluate is what BenchmarkProgram saves: parsing and walking the
+// syntax tree every time.
+func BenchmarkEvaluate(b *testing.B) {
+	e := NewEvaluator()
+	for i := 0; i < b.N; i++ {
+		e.vars["x"] = Number(i)
+		e.Evaluate(benchmarkExpr)
+	}
+}
@@ -0,0 +1,226 @@
+package calc
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+// maxDice bounds how many dice a single dice expression may roll.
+const maxDice = 1000
+
+// DiceExpr rolls dice in tabletop notation: Count dice with Sides faces,
+// adding up all of them or only the Keep highest ones (lowest if KeepLowest
+// is set), e.g. 3d6 or 4d6kh3.
+type DiceExpr struct {
+	Count, Sides int
+	Keep         int // 0 to keep all dice
+	KeepLowest   bool
+}
+
+func (n *DiceExpr) String() string {
+	return defaultPrinter.Print(n)
+}
+
+// notation returns n written in dice notation.
+func (n *DiceExpr) notation() string {
+	s := "d" + strconv.Itoa(n.Sides)
+	if n.Count != 1 {
+		s = strconv.Itoa(n.Count) + s
+	}
+
+	switch {
+	case n.Keep > 0 && n.KeepLowest:
+		s += "kl" + strconv.Itoa(n.Keep)
+	case n.Keep > 0:
+		s += "kh" + strconv.Itoa(n.Keep)
+	}
+
+	return s
+}
+
+// Roll is the outcome of evaluating a dice expression.
+type Roll struct {
+	Dice  *DiceExpr
+	Faces []int  // in the order the dice were rolled
+	Kept  []bool // whether each die counts towards Total
+	Total int
+}
+
+func (r Roll) String() string {
+	var b bytes.Buffer
+	b.WriteString(r.Dice.notation() + ":")
+	for i, f := range r.Faces {
+		if r.Kept[i] {
+			fmt.Fprintf(&b, " %d", f)
+		} else {
+			fmt.Fprintf(&b, " (%d)", f)
+		}
+	}
+	fmt.Fprintf(&b, " = %d", r.Total)
+
+	return b.String()
+}
+
+// WithSeed seeds the random numbers of the evaluator, so that evaluating
+// the same program gives the same rolls and samples again.
+func WithSeed(seed int64) Option {
+	return func(e *Evaluator) {
+		e.seed = seed
+	}
+}
+
+// Seed returns the seed of the random numbers of the evaluator, which can be
+// handed to WithSeed to repeat its rolls.
+func (e *Evaluator) Seed() int64 {
+	return e.seed
+}
+
+// Rolls returns the dice rolled by the evaluator so far.
+func (e *Evaluator) Rolls() []Roll {
+	return e.rolls
+}
+
+// Random tells if the evaluator drew random numbers so far, rolling dice or
+// calling rand, randint, choice, uniform or normal. Evaluating the same
+// program again may then give another value.
+func (e *Evaluator) Random() bool {
+	return e.drew
+}
+
+func (e *Evaluator) roll(n *DiceExpr) (Value, error) {
+	if n.Count < 1 || n.Count > maxDice {
+		return nil, fmt.Errorf("%s: can't roll more than %d dice or none at all", n.notation(), maxDice)
+	}
+	if n.Sides < 1 {
+		return nil, fmt.Errorf("%s: dice need at least one side", n.notation())
+	}
+	if n.Keep > n.Count {
+		return nil, fmt.Errorf("%s: can't keep more dice than are rolled", n.notation())
+	}
+
+	e.drew = true
+	r := Roll{Dice: n, Faces: make([]int, n.Count), Kept: make([]bool, n.Count)}
+	for i := range r.Faces {
+		r.Faces[i] = e.rng.Intn(n.Sides) + 1
+	}
+
+	// Dice are kept from the best (or worst, if keeping the lowest) down.
+	order := make([]int, n.Count)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if n.KeepLowest {
+			return r.Faces[order[i]] < r.Faces[order[j]]
+		}
+		return r.Faces[order[i]] > r.Faces[order[j]]
+	})
+
+	keep := n.Keep
+	if keep == 0 {
+		keep = n.Count
+	}
+	for _, i := range order[:keep] {
+		r.Kept[i] = true
+		r.Total += r.Faces[i]
+	}
+
+	e.rolls = append(e.rolls, r)
+	return Number(r.Total), nil
+}
+
+type randomBuiltin struct {
+	arity int
+	fn    func(r *rand.Rand, args []Value) (Value, error)
+}
+
+var randomBuiltins = map[string]randomBuiltin{
+	"rand":    {0, random},
+	"randint": {2, randint},
+	"choice":  {1, choice},
+	"uniform": {2, uniform},
+	"normal":  {2, normal},
+}
+
+// callRandom evaluates the arguments of n and applies f to them.
+func (e *Evaluator) callRandom(n *CallExpr, f randomBuiltin) (Value, error) {
+	if len(n.Args) != f.arity {
+		return nil, fmt.Errorf("%s takes %d argument(s), got %d", n.Func, f.arity, len(n.Args))
+	}
+
+	args := make([]Value, len(n.Args))
+	for i, arg := range n.Args {
+		v, err := e.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	e.drew = true
+	v, err := f.fn(e.rng, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", n.Func, err)
+	}
+
+	return v, nil
+}
+
+// random implements rand(), a number from 0 up to but not including 1.
+func random(r *rand.Rand, args []Value) (Value, error) {
+	return Number(r.Float64()), nil
+}
+
+// randint implements randint(a, b), an integer from a to b inclusive.
+func randint(r *rand.Rand, args []Value) (Value, error) {
+	a, aerr := toInt(args[0])
+	b, berr := toInt(args[1])
+	if aerr != nil || berr != nil || !a.IsInt64() || !b.IsInt64() {
+		return nil, fmt.Errorf("the bounds must be integers that fit in 64 bits")
+	}
+
+	lo, hi := a.Int64(), b.Int64()
+	if lo > hi || hi-lo < 0 || hi-lo == 1<<63-1 {
+		return nil, fmt.Errorf("can't pick an integer from %d to %d", lo, hi)
+	}
+
+	return Number(lo + r.Int63n(hi-lo+1)), nil
+}
+
+// choice implements choice(list), an element of list.
+func choice(r *rand.Rand, args []Value) (Value, error) {
+	l, ok := args[0].(List)
+	if !ok || len(l) == 0 {
+		return nil, fmt.Errorf("%s is not a list to choose from", args[0])
+	}
+
+	return l[r.Intn(len(l))], nil
+}
+
+// uniform implements uniform(a, b), a number from a up to but not
+// including b.
+func uniform(r *rand.Rand, args []Value) (Value, error) {
+	a, err := numbers(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return Number(a[0] + (a[1]-a[0])*r.Float64()), nil
+}
+
+// normal implements normal(mean, stddev), a number from the normal
+// distribution.
+func normal(r *rand.Rand, args []Value) (Value, error) {
+	a, err := numbers(args)
+	if err != nil {
+		return nil, err
+	}
+	if a[1] < 0 {
+		return nil, fmt.Errorf("the standard deviation can't be negative")
+	}
+
+	return Number(a[0] + a[1]*r.NormFloat64()), nil
+}
@@ -0,0 +1,214 @@
+package calc
+
+import (
+	"testing"
+)
+
+func TestRandom(t *testing.T) {
+	t.Run("Should repeat results with the same seed", func(t *testing.T) {
+		programs := []string{
+			"rand()",
+			"randint(1, 100) + uniform(-1, 1) * normal(0, 1)",
+			"choice([1, 2, 3, 4, 5])",
+			"3d6 + 2; 4d6kh3 * d20",
+		}
+
+		for _, p := range programs {
+			a, err := NewEvaluator(WithSeed(42)).Evaluate(p)
+			if err != nil {
+				t.Fatalf("error (%s) not nil for %s", err, p)
+			}
+			b, _ := NewEvaluator(WithSeed(42)).Evaluate(p)
+			if a != b {
+				t.Fatalf("%s evaluated to %s and %s with the same seed", p, a, b)
+			}
+
+			trace, err := NewEvaluator(WithSeed(42)).Trace(p)
+			if err != nil || trace.Value() != a {
+				t.Fatalf("%s traced to %v, expected %s, error %v", p, trace.Value(), a, err)
+			}
+		}
+	})
+
+	t.Run("Should stay within bounds", func(t *testing.T) {
+		e := NewEvaluator(WithSeed(1))
+		for i := 0; i < 50; i++ {
+			testCases := []struct {
+				Input    string
+				Min, Max float64
+			}{
+				{"rand()", 0, 0.999999},
+				{"randint(-2, 2)", -2, 2},
+				{"uniform(5, 6)", 5, 5.999999},
+				{"choice([7, 8])", 7, 8},
+				{"3d6 + 2", 5, 20},
+				{"d20", 1, 20},
+				{"4d6kh3", 3, 18},
+				{"2d20kl1", 1, 20},
+			}
+
+			for _, tc := range testCases {
+				v, err := e.Evaluate(tc.Input)
+				if err != nil {
+					t.Fatalf("error (%s) not nil for %s", err, tc.Input)
+				}
+				if f := float64(v.(Number)); f < tc.Min || f > tc.Max {
+					t.Fatalf("%s evaluated to %f", tc.Input, f)
+				}
+			}
+		}
+	})
+
+	t.Run("Should record the dice rolled", func(t *testing.T) {
+		e := NewEvaluator(WithSeed(7))
+		v, err := e.Evaluate("4d6kh3 + 1")
+		if err != nil {
+			t.Fatalf("error (%s) not nil", err)
+		}
+
+		rolls := e.Rolls()
+		if len(rolls) != 1 || len(rolls[0].Faces) != 4 {
+			t.Fatalf("unexpected rolls %v", rolls)
+		}
+
+		r := rolls[0]
+		lowest, kept, total := 0, 0, 0
+		for i, f := range r.Faces {
+			if f < r.Faces[lowest] {
+				lowest = i
+			}
+			if r.Kept[i] {
+				kept++
+				total += f
+			}
+		}
+		if kept != 3 || r.Kept[lowest] || total != r.Total || v != Number(r.Total+1) {
+			t.Fatalf("wrong dice kept in %s, value %s", r, v)
+		}
+	})
+
+	t.Run("Should tell when random numbers were drawn", func(t *testing.T) {
+		testCases := []struct {
+			Input  string
+			Random bool
+		}{
+			{"d6", true},
+			{"rand()", true},
+			{"randint(1, 6) * 2", true},
+			{"choice([1, 2])", true},
+			{"uniform(0, 1) + normal(0, 1)", true},
+			{"[rand() for k in range(3)]", true},
+			{"1 + 2", false},
+			{"d1 = 2; d1", false},
+			{"simplify(d6 - d6)", false},
+		}
+
+		for _, c := range testCases {
+			e := NewEvaluator()
+			if _, err := e.Evaluate(c.Input); err != nil || e.Random() != c.Random {
+				t.Fatalf("Random() != %v or error (%s) not nil for %q", c.Random, err, c.Input)
+			}
+		}
+	})
+
+	t.Run("Should format dice notation", func(t *testing.T) {
+		testCases := []struct {
+			Input     string
+			Formatted string
+		}{
+			{"3d6+2", "3d6 + 2"},
+			{"1d20", "d20"},
+			{"4d6k3", "4d6kh3"},
+			{"2d20kl1 - d4", "2d20kl1 - d4"},
+			{"d6x", "d6x"},
+		}
+
+		for _, c := range testCases {
+			formatted, err := Format(c.Input)
+			if err != nil || formatted != c.Formatted {
+				t.Fatalf("%q != %q or error (%s) not nil", formatted, c.Formatted, err)
+			}
+		}
+	})
+
+	t.Run("Should leave variables that look like dice as names", func(t *testing.T) {
+		testCases := []struct {
+			Input string
+			Value string
+		}{
+			{"d1 = 5; d1", "5"},
+			{"d2 = 3; d2 * 2 + d2", "9"},
+			{"d20=7; d20 - 1", "6"},
+			{"f = d6 -> d6 + 1; f(2)", "3"},
+			{"[d4 * 2 for d4 in [1, 2]]", "[2, 4]"},
+			{"d1 = 5; 2d1 + d1", "7"},
+		}
+
+		for _, c := range testCases {
+			v, err := NewEvaluator().Evaluate(c.Input)
+			if err != nil || v.String() != c.Value {
+				t.Fatalf("%v != %v or error (%s) not nil in test case %+v", v, c.Value, err, c)
+			}
+		}
+
+		e := NewEvaluator()
+		if _, err := e.Evaluate("d20 = 4"); err != nil {
+			t.Fatalf("error (%s) not nil assigning d20", err)
+		}
+		if v, err := e.Evaluate("d20 + 1"); err != nil || v.String() != "5" {
+			t.Fatalf("%v != 5 or error (%s) not nil using d20 in another program", v, err)
+		}
+	})
+
+	t.Run("Should simplify separate draws as different values", func(t *testing.T) {
+		testCases := []struct {
+			Input      string
+			Simplified string
+		}{
+			{"d6 - d6", "d6 - d6"},
+			{"2d6 / 2d6", "2d6 / 2d6"},
+			{"rand() - rand()", "rand() - rand()"},
+			{"sin(d6) - sin(d6)", "sin(d6) - sin(d6)"},
+			{"ln(exp(d6))", "ln(exp(d6))"},
+			{"d6 + 1 + 2", "d6 + 3"},
+			{"pow(d6, 2) * 2", "2 * d6^2"},
+		}
+
+		for _, c := range testCases {
+			stmts, err := Parse(c.Input)
+			if err != nil {
+				t.Fatalf("error (%s) not nil in test case %+v", err, c)
+			}
+
+			if simplified := Simplify(stmts[0]).String(); simplified != c.Simplified {
+				t.Fatalf("%q != %q in test case %+v", simplified, c.Simplified, c)
+			}
+		}
+
+		v, err := NewEvaluator().Evaluate("simplify(d6 - d6)")
+		if err != nil || v.String() != "d6 - d6" {
+			t.Fatalf("%v != d6 - d6 or error (%s) not nil", v, err)
+		}
+	})
+
+	t.Run("Should reject invalid arguments", func(t *testing.T) {
+		testCases := []string{
+			"rand(1)",
+			"randint(2, 1)",
+			"randint(1.5, 3)",
+			"choice([])",
+			"choice(3)",
+			"normal(0, -1)",
+			"0d6",
+			"2d0",
+			"2d6kh3",
+			"100000d6",
+		}
+
+		for _, tc := range testCases {
+			if _, err := NewEvaluator().Evaluate(tc); err == nil {
+				t.Fatalf("error is nil for %s", tc)
+			}
+		}
+	})
+}
@@ -0,0 +1,303 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+)
+
+// Lambda is the value of a lambda expression, a function that can be passed
+// to map, filter and reduce or named and called, as in double = x -> x * 2;
+// double(4). Names in its body other than its parameters are looked up when
+// it is called, not when it is defined.
+type Lambda struct {
+	*LambdaExpr
+}
+
+// bind evaluates fn with the variables names bound to values, and restores
+// them afterwards.
+func (e *Evaluator) bind(names []string, values []Value, fn func() (Value, error)) (Value, error) {
+	type saved struct {
+		value Value
+		ok    bool
+	}
+
+	old := make([]saved, len(names))
+	for i, name := range names {
+		old[i].value, old[i].ok = e.vars[name]
+		e.vars[name] = values[i]
+	}
+	defer func() {
+		for i := len(names) - 1; i >= 0; i-- {
+			if old[i].ok {
+				e.vars[names[i]] = old[i].value
+			} else {
+				delete(e.vars, names[i])
+			}
+		}
+	}()
+
+	return fn()
+}
+
+// apply calls f with args, failing if calls nest deeper than the limit, as
+// they would in f = x -> f(x).
+func (e *Evaluator) apply(f Lambda, args []Value) (Value, error) {
+	if len(args) != len(f.Params) {
+		return nil, fmt.Errorf("%s takes %d argument(s), got %d", f, len(f.Params), len(args))
+	}
+
+	e.calls++
+	defer func() { e.calls-- }()
+	if max := e.limits.MaxDepth; max > 0 && e.calls > max {
+		return nil, &TooDeepError{e.calls, max}
+	}
+
+	return e.bind(f.Params, args, func() (Value, error) {
+		return e.eval(f.Body)
+	})
+}
+
+// callLambda applies the lambda named by n to its arguments.
+func (e *Evaluator) callLambda(n *CallExpr, f Lambda) (Value, error) {
+	args := make([]Value, len(n.Args))
+	for i, arg := range n.Args {
+		v, err := e.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	return e.apply(f, args)
+}
+
+// comprehension evaluates the list comprehension n.
+func (e *Evaluator) comprehension(n *ComprehensionExpr) (Value, error) {
+	v, err := e.eval(n.Iter)
+	if err != nil {
+		return nil, err
+	}
+	l, err := toList(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.iterate(len(l)); err != nil {
+		return nil, err
+	}
+
+	result := List{}
+	for _, x := range l {
+		keep := true
+		v, err := e.bind([]string{n.Var}, []Value{x}, func() (Value, error) {
+			if n.Cond != nil {
+				c, err := e.eval(n.Cond)
+				if err != nil {
+					return nil, err
+				}
+				if keep, err = truthy(c); err != nil || !keep {
+					return nil, err
+				}
+			}
+			return e.eval(n.Elem)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			result = append(result, v)
+		}
+	}
+
+	return result, nil
+}
+
+func toList(v Value) (List, error) {
+	if l, ok := v.(List); ok {
+		return l, nil
+	}
+
+	return nil, fmt.Errorf("%s is not a list", v)
+}
+
+func toLambda(v Value) (Lambda, error) {
+	if f, ok := v.(Lambda); ok {
+		return f, nil
+	}
+
+	return Lambda{}, fmt.Errorf("%s is not a function", v)
+}
+
+// truthy tells if v, the value of a condition, is true: comparisons are 1
+// if they hold and 0 otherwise.
+func truthy(v Value) (bool, error) {
+	if f, ok := toFloat(v).(Number); ok {
+		return f != 0, nil
+	}
+
+	return false, fmt.Errorf("%s is neither true nor false", v)
+}
+
+type sequenceBuiltin struct {
+	minArity, maxArity int
+	fn                 func(e *Evaluator, args []Value) (Value, error)
+}
+
+// lookupSequence returns the sequence builtin named name. It is a function
+// rather than a map because map, filter and reduce call back into the
+// evaluator, which would make the map's initialization depend on itself.
+func lookupSequence(name string) (sequenceBuiltin, bool) {
+	switch name {
+	case "range":
+		return sequenceBuiltin{1, 3, rangeList}, true
+	case "map":
+		return sequenceBuiltin{2, 2, mapList}, true
+	case "filter":
+		return sequenceBuiltin{2, 2, filterList}, true
+	case "reduce":
+		return sequenceBuiltin{2, 3, reduceList}, true
+	}
+
+	return sequenceBuiltin{}, false
+}
+
+// callSequence evaluates the arguments of n and applies f to them.
+func (e *Evaluator) callSequence(n *CallExpr, f sequenceBuiltin) (Value, error) {
+	if f.minArity == f.maxArity && len(n.Args) != f.minArity {
+		return nil, fmt.Errorf("%s takes %d argument(s), got %d", n.Func, f.minArity, len(n.Args))
+	}
+	if len(n.Args) < f.minArity || len(n.Args) > f.maxArity {
+		return nil, fmt.Errorf("%s takes %d to %d arguments, got %d", n.Func, f.minArity, f.maxArity, len(n.Args))
+	}
+
+	args := make([]Value, len(n.Args))
+	for i, arg := range n.Args {
+		v, err := e.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	return f.fn(e, args)
+}
+
+// rangeList implements range(stop), range(start, stop) and range(start,
+// stop, step): the numbers from start, 0 by default, up to but not including
+// stop, step apart, 1 by default.
+func rangeList(e *Evaluator, args []Value) (Value, error) {
+	a, err := numbers(args)
+	if err != nil || len(a) != len(args) {
+		return nil, fmt.Errorf("range takes numbers, got %s", List(args))
+	}
+
+	start, stop, step := 0.0, a[0], 1.0
+	if len(a) > 1 {
+		start, stop = a[0], a[1]
+	}
+	if len(a) > 2 {
+		step = a[2]
+	}
+	if step == 0 {
+		return nil, fmt.Errorf("range: the step can't be 0")
+	}
+
+	count := math.Max(math.Ceil((stop-start)/step), 0)
+	if math.IsNaN(count) || math.IsInf(count, 0) || count > math.MaxInt32 {
+		return nil, fmt.Errorf("range: can't count from %s to %s", formatFloat(start), formatFloat(stop))
+	}
+	if err := e.iterate(int(count)); err != nil {
+		return nil, err
+	}
+
+	l := make(List, int(count))
+	for i := range l {
+		l[i] = Number(start + float64(i)*step)
+	}
+
+	return l, nil
+}
+
+// mapList implements map(f, list), the list of f applied to each element.
+func mapList(e *Evaluator, args []Value) (Value, error) {
+	f, l, err := e.sequenceArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(List, len(l))
+	for i, x := range l {
+		if result[i], err = e.apply(f, []Value{x}); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// filterList implements filter(f, list), the elements for which f is true.
+func filterList(e *Evaluator, args []Value) (Value, error) {
+	f, l, err := e.sequenceArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	result := List{}
+	for _, x := range l {
+		v, err := e.apply(f, []Value{x})
+		if err != nil {
+			return nil, err
+		}
+		keep, err := truthy(v)
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			result = append(result, x)
+		}
+	}
+
+	return result, nil
+}
+
+// reduceList implements reduce(f, list) and reduce(f, list, initial), which
+// combine the elements with f from left to right, starting with initial or
+// the first element.
+func reduceList(e *Evaluator, args []Value) (Value, error) {
+	f, l, err := e.sequenceArgs(args[:2])
+	if err != nil {
+		return nil, err
+	}
+
+	var acc Value
+	switch {
+	case len(args) > 2:
+		acc = args[2]
+	case len(l) > 0:
+		acc, l = l[0], l[1:]
+	default:
+		return nil, fmt.Errorf("reduce: can't reduce an empty list without an initial value")
+	}
+
+	for _, x := range l {
+		if acc, err = e.apply(f, []Value{acc, x}); err != nil {
+			return nil, err
+		}
+	}
+
+	return acc, nil
+}
+
+// sequenceArgs returns the function and the list map, filter and reduce are
+// given, counting an iteration for each element.
+func (e *Evaluator) sequenceArgs(args []Value) (Lambda, List, error) {
+	f, err := toLambda(args[0])
+	if err != nil {
+		return Lambda{}, nil, err
+	}
+	l, err := toList(args[1])
+	if err != nil {
+		return Lambda{}, nil, err
+	}
+
+	return f, l, e.iterate(len(l))
+}
@@ -0,0 +1,120 @@
+package calc
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSequences(t *testing.T) {
+	t.Run("Should generate and transform lists", func(t *testing.T) {
+		testCases := []struct {
+			Input  string
+			Result string
+		}{
+			{"range(1, 10)", "[1, 2, 3, 4, 5, 6, 7, 8, 9]"},
+			{"range(3)", "[0, 1, 2]"},
+			{"range(10, 0, -3)", "[10, 7, 4, 1]"},
+			{"range(5, 1)", "[]"},
+			{"[k^2 for k in range(1, 10) if k % 2 == 1]", "[1, 9, 25, 49, 81]"},
+			{"[k * 2 for k in [1, 2, 3]]", "[2, 4, 6]"},
+			{"map(x -> x * 2, [1, 2, 3])", "[2, 4, 6]"},
+			{"filter(x -> x != 2, range(4))", "[0, 1, 3]"},
+			{"reduce((a, b) -> a + b, range(1, 101))", "5050"},
+			{"reduce((a, b) -> a * b, [], 1)", "1"},
+			{"double = x -> x * 2; double(4)", "8"},
+			{"k = 7; [k for k in [1, 2]]; k", "7"},
+			{"n = 3; add = x -> x + n; n = 10; add(1)", "11"},
+			{"fact = n -> reduce((a, b) -> a * b, range(1, n + 1), 1); fact(6)", "720"},
+		}
+
+		for _, c := range testCases {
+			v, err := NewEvaluator().Evaluate(c.Input)
+			if err != nil || v.String() != c.Result {
+				t.Fatalf("%v != %v or error (%s) not nil in test case %+v", v, c.Result, err, c)
+			}
+		}
+	})
+
+	t.Run("Should compute remainders, powers and equality", func(t *testing.T) {
+		testCases := []struct {
+			Input string
+			Value float64
+		}{
+			{"7 % 3", 1},
+			{"-7 % 3", 2},
+			{"7.5 % 2", 1.5},
+			{"2^10", 1024},
+			{"2^3^2", 512},
+			{"-2^2", -4},
+			{"1 + 1 == 2", 1},
+			{"0.1 + 0.2 == 0.3", 0},
+			{"3 != 4", 1},
+		}
+
+		for _, c := range testCases {
+			v, err := Evaluate(c.Input)
+			if err != nil || !floatEquals(v, c.Value, 1e-12) {
+				t.Fatalf("%v != %v or error (%s) not nil in test case %+v", v, c.Value, err, c)
+			}
+		}
+	})
+
+	t.Run("Should format lambdas and comprehensions", func(t *testing.T) {
+		testCases := []struct {
+			Input  string
+			Output string
+		}{
+			{"map(x->x*2,[1,2])", "map(x -> x * 2, [1, 2])"},
+			{"reduce((a,b)->a+b, [1])", "reduce((a, b) -> a + b, [1])"},
+			{"[k^2 for k in range(1,10) if k%2==1]", "[k^2 for k in range(1, 10) if k % 2 == 1]"},
+		}
+
+		for _, c := range testCases {
+			s, err := Format(c.Input)
+			if err != nil || s != c.Output {
+				t.Fatalf("%v != %v or error (%s) not nil in test case %+v", s, c.Output, err, c)
+			}
+		}
+	})
+
+	t.Run("Should fail on invalid sequences", func(t *testing.T) {
+		testCases := []string{
+			"range(1, 10, 0)",
+			"range(1 / 0)",
+			"map(2, [1])",
+			"map(x -> x, 3)",
+			"map(x -> x)",
+			"reduce((a, b) -> a + b, [])",
+			"map((a, b) -> a, [1])",
+			"filter(x -> [x], [1])",
+			"5 % 0",
+		}
+
+		for _, c := range testCases {
+			if v, err := NewEvaluator().Evaluate(c); err == nil {
+				t.Fatalf("Expected error, got %v in test case %q", v, c)
+			}
+		}
+	})
+
+	t.Run("Should cap iterations", func(t *testing.T) {
+		var iterations *TooManyIterationsError
+		e := NewEvaluator(WithLimits(Limits{MaxIterations: 100}))
+		_, err := e.Evaluate("[k for k in range(60)]; [k for k in range(60)]")
+		if !errors.As(err, &iterations) || iterations.Max != 100 {
+			t.Fatalf("Expected a TooManyIterationsError, got %v", err)
+		}
+
+		if _, err := NewEvaluator().Evaluate("range(1e9)"); !errors.As(err, &iterations) {
+			t.Fatalf("Expected a TooManyIterationsError, got %v", err)
+		}
+	})
+
+	t.Run("Should cap recursion", func(t *testing.T) {
+		var depth *TooDeepError
+		_, err := NewEvaluator().Evaluate("f = x -> f(x); f(1)")
+		if !errors.As(err, &depth) {
+			t.Fatalf("Expected a TooDeepError, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,602 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Simplify returns an expression equivalent to n in a normalized form:
+// constant subexpressions are folded, exactly if they are integers, like
+// terms and repeated factors are combined (so x - x is 0 and x * x is x^2),
+// inverse functions such as ln(exp(x)) cancel out and the operands of sums
+// and products are put in a canonical order. Two expressions that only differ in the order of their
+// terms simplify to the same tree. Dice and random builtins are different
+// draws wherever they appear, so d6 - d6 is left as it is.
+func Simplify(n Node) Node {
+	switch n := n.(type) {
+	case *AssignExpr:
+		return &AssignExpr{Name: n.Name, Value: Simplify(n.Value)}
+	case *ListExpr:
+		elems := make([]Node, len(n.Elems))
+		for i, elem := range n.Elems {
+			elems[i] = Simplify(elem)
+		}
+		return &ListExpr{Elems: elems}
+	}
+
+	return toSum(n).node()
+}
+
+// A sum is a normalized expression: a linear combination of terms, indexed
+// by the key of their factors. The constant term has the empty key.
+type sum map[string]*term
+
+// A term is a coefficient times a product of factors sorted by key.
+type term struct {
+	coef    float64
+	factors []factor
+}
+
+// A factor is a base that can't be simplified further, such as a variable or
+// a function call, raised to a numeric exponent.
+type factor struct {
+	base Node
+	exp  float64
+	draw int64 // tells apart bases that draw random numbers, 0 for the rest
+}
+
+func (f factor) key() string {
+	if f.draw != 0 {
+		return fmt.Sprintf("%s#%016d", f.base, f.draw)
+	}
+
+	return f.base.String()
+}
+
+// draws counts the factors that draw random numbers, to tell them apart.
+var draws int64
+
+// newFactor returns base raised to exp, a new draw if base is random.
+func newFactor(base Node, exp float64) factor {
+	f := factor{base: base, exp: exp}
+	if isRandom(base) {
+		f.draw = atomic.AddInt64(&draws, 1)
+	}
+
+	return f
+}
+
+func (t *term) key() string {
+	keys := make([]string, len(t.factors))
+	for i, f := range t.factors {
+		keys[i] = f.key() + "^" + formatFloat(f.exp)
+	}
+
+	return strings.Join(keys, "*")
+}
+
+func constant(c float64) sum {
+	if c == 0 {
+		return sum{}
+	}
+
+	return sum{"": &term{coef: c}}
+}
+
+func atom(n Node) sum {
+	return power(n, 1)
+}
+
+func power(base Node, exp float64) sum {
+	if exp == 0 {
+		return constant(1)
+	}
+
+	t := &term{coef: 1, factors: []factor{newFactor(base, exp)}}
+	return sum{t.key(): t}
+}
+
+// toSum normalizes n into a sum, simplifying its subexpressions.
+func toSum(n Node) sum {
+	switch n := n.(type) {
+	case *NumberLit:
+		if n.Int != nil {
+			// Coefficients are floats, which would lose its digits.
+			return integerSum(n.Int)
+		}
+		return constant(n.Value)
+	case *UnaryExpr:
+		if n.Op == "-" {
+			return toSum(n.X).scale(-1)
+		}
+	case *BinaryExpr:
+		x, y := toSum(n.X), toSum(n.Y)
+		if z, ok := foldIntegers(n.Op, x, y); ok {
+			return z
+		}
+		switch n.Op {
+		case "+":
+			return x.add(y)
+		case "-":
+			return x.add(y.scale(-1))
+		case "*":
+			return x.mul(y)
+		case "/":
+			return x.div(y)
+		case "<", ">", "<=", ">=", "==", "!=":
+			return compare(n.Op, x.node(), y.node())
+		case "%":
+			if a, ok := x.constant(); ok {
+				if b, ok := y.constant(); ok && b != 0 {
+					return constant(floorMod(a, b))
+				}
+			}
+		}
+		return atom(&BinaryExpr{Op: n.Op, X: x.node(), Y: y.node(), Pos: n.Pos})
+	case *CallExpr:
+		return simplifyCall(n)
+	case *AssignExpr, *ListExpr:
+		return atom(Simplify(n))
+	}
+
+	return atom(n)
+}
+
+// integerSum returns the constant x, as a float if one holds it exactly and
+// as a literal factor otherwise.
+func integerSum(x *big.Int) sum {
+	if isExactFloat(x) {
+		return constant(Integer{x}.float())
+	}
+
+	return atom(&NumberLit{Value: Integer{x}.float(), Int: x})
+}
+
+// integer returns the value of t if it is an integer constant held exactly,
+// by its coefficient or by its coefficient times a literal.
+func (t *term) integer() (*big.Int, bool) {
+	if t.coef != math.Trunc(t.coef) || math.Abs(t.coef) >= maxExactFloat {
+		return nil, false
+	}
+
+	c := big.NewInt(int64(t.coef))
+	if len(t.factors) == 0 {
+		return c, true
+	}
+	if t.isLiteral() {
+		return c.Mul(c, t.factors[0].base.(*NumberLit).Int), true
+	}
+
+	return nil, false
+}
+
+// isLiteral tells if t is a coefficient times an integer literal too large
+// for the coefficient to hold.
+func (t *term) isLiteral() bool {
+	if len(t.factors) != 1 || t.factors[0].exp != 1 {
+		return false
+	}
+	lit, ok := t.factors[0].base.(*NumberLit)
+	return ok && lit.Int != nil
+}
+
+// exactInteger returns the value of s if it is an integer constant held
+// exactly.
+func (s sum) exactInteger() (*big.Int, bool) {
+	if len(s) == 0 {
+		return new(big.Int), true
+	}
+	if t, ok := s.monomial(); ok {
+		return t.integer()
+	}
+
+	return nil, false
+}
+
+// foldLiterals adds up the integer constants of s exactly if any of them is
+// too large for a float.
+func (s sum) foldLiterals() sum {
+	literals := false
+	for _, t := range s {
+		literals = literals || t.isLiteral()
+	}
+	if !literals {
+		return s
+	}
+
+	total := new(big.Int)
+	r := make(sum, len(s))
+	for k, t := range s {
+		if x, ok := t.integer(); ok {
+			total.Add(total, x)
+		} else {
+			r[k] = t
+		}
+	}
+	for k, t := range integerSum(total) {
+		r[k] = t
+	}
+
+	return r
+}
+
+// foldIntegers computes x op y exactly if x and y are integer constants and
+// so is the result.
+func foldIntegers(op string, x, y sum) (sum, bool) {
+	a, aok := x.exactInteger()
+	b, bok := y.exactInteger()
+	if !aok || !bok {
+		return nil, false
+	}
+
+	if z, ok := integerBinary(op, Integer{a}, Integer{b}); ok {
+		if z, ok := z.(Integer); ok {
+			return integerSum(z.Int), true
+		}
+	}
+
+	return nil, false
+}
+
+// simplifyCall folds calls to builtins with constant arguments and applies
+// identities between inverse functions.
+func simplifyCall(n *CallExpr) sum {
+	args := make([]Node, len(n.Args))
+	values := make([]float64, len(n.Args))
+	constants := true
+	for i, arg := range n.Args {
+		args[i] = Simplify(arg)
+		if lit, ok := args[i].(*NumberLit); ok && lit.Int == nil {
+			values[i] = lit.Value
+		} else {
+			constants = false
+		}
+	}
+
+	if f, ok := builtins[n.Func]; ok && constants && len(args) == f.arity {
+		if v := f.fn(values...); !math.IsNaN(v) && !math.IsInf(v, 0) {
+			return constant(v)
+		}
+	}
+
+	if x, ok := inverse(n.Func, args); ok && !isRandom(n) {
+		return toSum(x)
+	}
+
+	if n.Func == "pow" && len(args) == 2 {
+		if exp, ok := args[1].(*NumberLit); ok && exp.Int == nil {
+			return toSum(args[0]).pow(exp.Value)
+		}
+	}
+
+	return atom(&CallExpr{Func: n.Func, Args: args})
+}
+
+// inverse applies identities like ln(exp(x)) = x, returning x and true if
+// one applies to fn(args...).
+func inverse(fn string, args []Node) (Node, bool) {
+	var base, arg Node
+	switch fn {
+	case "ln":
+		if call, ok := args[0].(*CallExpr); ok && call.Func == "exp" && len(call.Args) == 1 {
+			return call.Args[0], true
+		}
+		return nil, false
+	case "log10":
+		base, arg = &NumberLit{Value: 10}, args[0]
+	case "log2":
+		base, arg = &NumberLit{Value: 2}, args[0]
+	case "log":
+		if len(args) != 2 {
+			return nil, false
+		}
+		base, arg = args[0], args[1]
+		if equalNodes(base, arg) {
+			return &NumberLit{Value: 1}, true
+		}
+	case "exp":
+		if call, ok := args[0].(*CallExpr); ok && call.Func == "ln" && len(call.Args) == 1 {
+			return call.Args[0], true
+		}
+		return nil, false
+	case "pow":
+		if len(args) != 2 {
+			return nil, false
+		}
+		if call, ok := args[1].(*CallExpr); ok && call.Func == "log" && len(call.Args) == 2 && equalNodes(call.Args[0], args[0]) {
+			return call.Args[1], true
+		}
+		return nil, false
+	default:
+		return nil, false
+	}
+
+	call, ok := arg.(*CallExpr)
+	if !ok {
+		return nil, false
+	}
+	if call.Func == "pow" && len(call.Args) == 2 && equalNodes(call.Args[0], base) {
+		return call.Args[1], true
+	}
+
+	return nil, false
+}
+
+func (s sum) copy() sum {
+	c := make(sum, len(s))
+	for k, t := range s {
+		factors := make([]factor, len(t.factors))
+		copy(factors, t.factors)
+		c[k] = &term{coef: t.coef, factors: factors}
+	}
+
+	return c
+}
+
+// constant returns the value of s and true if s has no variable terms.
+func (s sum) constant() (float64, bool) {
+	if len(s) == 0 {
+		return 0, true
+	}
+	if t, ok := s[""]; ok && len(s) == 1 {
+		return t.coef, true
+	}
+
+	return 0, false
+}
+
+// monomial returns the only term of s, if s has exactly one.
+func (s sum) monomial() (*term, bool) {
+	if len(s) != 1 {
+		return nil, false
+	}
+	for _, t := range s {
+		return t, true
+	}
+
+	return nil, false
+}
+
+func (s sum) add(o sum) sum {
+	r := s.copy()
+	for k, t := range o.copy() {
+		if rt, ok := r[k]; ok {
+			rt.coef += t.coef
+			if rt.coef == 0 {
+				delete(r, k)
+			}
+		} else {
+			r[k] = t
+		}
+	}
+
+	return r.foldLiterals()
+}
+
+func (s sum) scale(c float64) sum {
+	if c == 0 {
+		return sum{}
+	}
+
+	r := s.copy()
+	for _, t := range r {
+		t.coef *= c
+	}
+
+	return r
+}
+
+func (s sum) mul(o sum) sum {
+	if c, ok := s.constant(); ok {
+		return o.scale(c)
+	}
+	if c, ok := o.constant(); ok {
+		return s.scale(c)
+	}
+
+	a, ok := s.monomial()
+	if !ok {
+		a = atomTerm(s)
+	}
+	b, ok := o.monomial()
+	if !ok {
+		b = atomTerm(o)
+	}
+
+	t := &term{coef: a.coef * b.coef}
+	t.factors = mergeFactors(a.factors, b.factors)
+	if len(t.factors) == 0 {
+		return constant(t.coef)
+	}
+
+	return sum{t.key(): t}
+}
+
+func (s sum) div(o sum) sum {
+	if c, ok := o.constant(); ok {
+		if c == 0 {
+			return atom(&BinaryExpr{Op: "/", X: s.node(), Y: o.node()})
+		}
+		return s.scale(1 / c)
+	}
+
+	b, ok := o.monomial()
+	if !ok {
+		b = atomTerm(o)
+	}
+
+	inv := &term{coef: 1 / b.coef}
+	for _, f := range b.factors {
+		inv.factors = append(inv.factors, factor{f.base, -f.exp, f.draw})
+	}
+
+	return s.mul(sum{inv.key(): inv})
+}
+
+// pow raises s to a constant exponent. Only single terms raised to integers
+// are expanded; anything else is kept as a power of the whole sum.
+func (s sum) pow(exp float64) sum {
+	if exp == 0 {
+		return constant(1)
+	}
+	if exp == 1 {
+		return s
+	}
+
+	t, ok := s.monomial()
+	if !ok || exp != math.Trunc(exp) {
+		return power(s.node(), exp)
+	}
+
+	r := &term{coef: math.Pow(t.coef, exp)}
+	for _, f := range t.factors {
+		r.factors = append(r.factors, factor{f.base, f.exp * exp, f.draw})
+	}
+	if len(r.factors) == 0 {
+		return constant(r.coef)
+	}
+
+	return sum{r.key(): r}
+}
+
+// atomTerm wraps a sum of several terms into a single factor.
+func atomTerm(s sum) *term {
+	return &term{coef: 1, factors: []factor{newFactor(s.node(), 1)}}
+}
+
+// mergeFactors multiplies two sorted lists of factors, adding the exponents
+// of equal bases and dropping the ones that cancel out.
+func mergeFactors(a, b []factor) []factor {
+	var r []factor
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || (i < len(a) && a[i].key() < b[j].key()):
+			r = append(r, a[i])
+			i++
+		case i == len(a) || b[j].key() < a[i].key():
+			r = append(r, b[j])
+			j++
+		default:
+			if exp := a[i].exp + b[j].exp; exp != 0 {
+				r = append(r, factor{a[i].base, exp, a[i].draw})
+			}
+			i++
+			j++
+		}
+	}
+
+	return r
+}
+
+// node converts s back into a syntax tree, ordering the terms by key and
+// leaving the constant terms last.
+func (s sum) node() Node {
+	if len(s) == 0 {
+		return &NumberLit{Value: 0}
+	}
+
+	var keys, literals []string
+	for k, t := range s {
+		switch {
+		case t.isLiteral():
+			literals = append(literals, k)
+		case k != "":
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	sort.Strings(literals)
+	keys = append(keys, literals...)
+	if _, ok := s[""]; ok {
+		keys = append(keys, "")
+	}
+
+	var n Node
+	for _, k := range keys {
+		t := s[k]
+		switch {
+		case n == nil:
+			n = t.node(t.coef)
+		case t.coef < 0:
+			n = &BinaryExpr{Op: "-", X: n, Y: t.node(-t.coef)}
+		default:
+			n = &BinaryExpr{Op: "+", X: n, Y: t.node(t.coef)}
+		}
+	}
+
+	return n
+}
+
+// node converts t back into a syntax tree with coef as its coefficient.
+// Factors with negative exponents go to the denominator, and so do
+// coefficients like 1/2, so that x / 2 isn't printed as 0.5 * x.
+func (t *term) node(coef float64) Node {
+	if len(t.factors) == 0 {
+		return &NumberLit{Value: coef}
+	}
+
+	var num, den Node
+	if inv := 1 / coef; math.Abs(coef) < 1 && inv == math.Trunc(inv) {
+		den = &NumberLit{Value: math.Abs(inv)}
+		coef = math.Copysign(1, coef)
+	}
+	if coef != 1 && coef != -1 {
+		num = &NumberLit{Value: coef}
+	}
+
+	for _, f := range t.factors {
+		if f.exp > 0 {
+			num = product(num, powerNode(f.base, f.exp))
+		} else {
+			den = product(den, powerNode(f.base, -f.exp))
+		}
+	}
+
+	switch {
+	case num == nil && coef == -1:
+		num = &NumberLit{Value: -1}
+	case num == nil:
+		num = &NumberLit{Value: 1}
+	case coef == -1:
+		num = &UnaryExpr{Op: "-", X: num}
+	}
+
+	if den == nil {
+		return num
+	}
+
+	return &BinaryExpr{Op: "/", X: num, Y: den}
+}
+
+func product(x, y Node) Node {
+	if x == nil {
+		return y
+	}
+
+	return &BinaryExpr{Op: "*", X: x, Y: y}
+}
+
+func powerNode(base Node, exp float64) Node {
+	if exp == 1 {
+		return base
+	}
+
+	return &CallExpr{Func: "pow", Args: []Node{base, &NumberLit{Value: exp}}}
+}
+
+// compare folds the comparison x op y if both sides are numbers.
+func compare(op string, x, y Node) sum {
+	a, aok := x.(*NumberLit)
+	b, bok := y.(*NumberLit)
+	if aok && bok {
+		v, _ := applyBinary(op, fromNode(a), fromNode(b))
+		return constant(float64(v.(Number)))
+	}
+
+	return atom(&BinaryExpr{Op: op, X: x, Y: y})
+}
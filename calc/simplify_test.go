@@ -0,0 +1,99 @@
+package calc
+
+import "testing"
+
+func TestSimplify(t *testing.T) {
+	t.Run("Should simplify expressions", func(t *testing.T) {
+		testCases := []struct {
+			Input      string
+			Simplified string
+		}{
+			{"1 + 2 * 3", "7"},
+			{"x - x", "0"},
+			{"x + x", "2 * x"},
+			{"2*x + 3*x", "5 * x"},
+			{"x * x", "x^2"},
+			{"x / x", "1"},
+			{"x * 1 + 0", "x"},
+			{"y + x + 1 + 2", "x + y + 3"},
+			{"2 * (x + 1)", "2 * x + 2"},
+			{"(x + 1) * (1 + x)", "(x + 1)^2"},
+			{"x - 3*y", "x - 3 * y"},
+			{"x*y / (2*z)", "x * y / (2 * z)"},
+			{"-(x*2)", "-2 * x"},
+			{"pow(2*x, 2)", "4 * x^2"},
+			{"pow(x, 0)", "1"},
+			{"ln(exp(x))", "x"},
+			{"exp(ln(x))", "x"},
+			{"log(2, pow(2, x))", "x"},
+			{"log10(pow(10, x + 1))", "x + 1"},
+			{"log(x, x)", "1"},
+			{"log10(100) + x*0", "2"},
+			{"a = (x - x)", "a = 0"},
+			{"1 / 0", "1 / 0"},
+			{"100000000000000000000 + 1", "100000000000000000001"},
+			{"100000000000000000000 * 3 - 100000000000000000000", "200000000000000000000"},
+			{"-100000000000000000000 + 100000000000000000000", "0"},
+			{"100000000000000000000 - 1 + x", "x + 99999999999999999999"},
+			{"x + 100000000000000000000 + 100000000000000000000", "x + 200000000000000000000"},
+			{"100000000000000000000 - x - 100000000000000000000", "-x"},
+			{"(x + x) % (1 + 2)", "2 * x % 3"},
+			{"7 % 3 + x", "x + 1"},
+			{"(x + x) ± (0.5 * 2)", "(2 * x) ± 1"},
+		}
+
+		for _, c := range testCases {
+			stmts, err := Parse(c.Input)
+			if err != nil {
+				t.Fatalf("error (%s) not nil in test case %+v", err, c)
+			}
+
+			if simplified := Simplify(stmts[0]).String(); simplified != c.Simplified {
+				t.Fatalf("%q != %q in test case %+v", simplified, c.Simplified, c)
+			}
+		}
+	})
+
+	t.Run("Should canonicalize the order of terms", func(t *testing.T) {
+		testCases := []struct {
+			A, B string
+		}{
+			{"x + y", "y + x"},
+			{"2*x*y", "y*x*2"},
+			{"x - 1 + y", "y + x - 1"},
+			{"ln(x) * (a + b)", "(b + a) * ln(x)"},
+		}
+
+		for _, c := range testCases {
+			a, errA := Parse(c.A)
+			b, errB := Parse(c.B)
+			if errA != nil || errB != nil {
+				t.Fatalf("errors (%s, %s) not nil in test case %+v", errA, errB, c)
+			}
+
+			if sa, sb := Simplify(a[0]).String(), Simplify(b[0]).String(); sa != sb {
+				t.Fatalf("%q != %q in test case %+v", sa, sb, c)
+			}
+		}
+	})
+
+	t.Run("Should expose simplify in the language", func(t *testing.T) {
+		testCases := []struct {
+			Input string
+			Value string
+		}{
+			{"simplify(x + x)", "2 * x"},
+			{"a = 2; simplify(a*x + x)", "3 * x"},
+			{"b = simplify(y - 1); b + 1", "y"},
+			{"simplify(ln(exp(3)))", "3"},
+			{"simplify(100000000000000000000 + 1)", "100000000000000000001"},
+		}
+
+		for _, c := range testCases {
+			v, err := NewEvaluator().Evaluate(c.Input)
+			if err != nil || v.String() != c.Value {
+				t.Fatalf("%v != %q or error (%s) not nil in test case %+v", v, c.Value, err, c)
+			}
+		}
+	})
+}
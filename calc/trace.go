@@ -0,0 +1,230 @@
+package calc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// StepKind tells what kind of reduction a Step is.
+type StepKind int
+
+const (
+	// Substitution replaces a variable by its value.
+	Substitution StepKind = iota
+	// Operation applies an operator to values, e.g. 1 + 2 to 3.
+	Operation
+	// Application applies a function to its arguments, e.g. log2(8) to 3.
+	Application
+	// Assignment binds a value to a variable.
+	Assignment
+)
+
+// Step is a single reduction in the evaluation of a statement.
+type Step struct {
+	Kind    StepKind
+	Reduced Node // the subexpression that was reduced
+	Result  Node // what it was reduced to
+	Expr    Node // the whole statement after the reduction
+}
+
+// StatementTrace holds the steps taken to evaluate one statement.
+type StatementTrace struct {
+	Stmt  Node
+	Steps []Step
+	Value Value
+}
+
+// Trace is the record of every reduction made while evaluating a program,
+// e.g. (1 + 2) * 3 → 3 * 3 → 9.
+type Trace struct {
+	Statements []StatementTrace
+}
+
+// Value returns the value of the program's last statement.
+func (t *Trace) Value() Value {
+	if len(t.Statements) == 0 {
+		return nil
+	}
+
+	return t.Statements[len(t.Statements)-1].Value
+}
+
+// String renders the trace as text, one step per line. Substitutions and
+// function applications are annotated with what was replaced.
+func (t *Trace) String() string {
+	var b bytes.Buffer
+	for i, st := range t.Statements {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		b.WriteString(st.Stmt.String() + "\n")
+		for _, step := range st.Steps {
+			b.WriteString("→ " + step.Expr.String())
+			if step.Kind == Substitution || step.Kind == Application {
+				fmt.Fprintf(&b, "   [%s = %s]", step.Reduced, step.Result)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	return b.String()
+}
+
+// Trace evaluates program like Evaluate, but records every step taken.
+func (e *Evaluator) Trace(program string) (*Trace, error) {
+	return e.TraceContext(context.Background(), program)
+}
+
+// TraceContext is like Trace, but gives up with the context's error once
+// ctx is done.
+func (e *Evaluator) TraceContext(ctx context.Context, program string) (*Trace, error) {
+	stmts, err := e.Parse(program)
+	if err != nil {
+		return nil, err
+	}
+
+	saved := e.save()
+	e.start(ctx)
+	t := &Trace{}
+	for _, stmt := range stmts {
+		st := StatementTrace{Stmt: stmt}
+
+		n := stmt
+		for !isValueNode(n) {
+			before := n.String()
+			var step *Step
+			if n, step, err = e.step(n); err != nil {
+				e.rollback(saved)
+				return nil, err
+			}
+			// Reductions of symbolic values may leave the expression as it
+			// was, as in 2 * x + 1, which isn't worth a step.
+			if step.Expr = n; n.String() != before {
+				st.Steps = append(st.Steps, *step)
+			}
+		}
+
+		if st.Value, err = e.eval(n); err == nil {
+			err = e.checkOutput(st.Value)
+		}
+		if err != nil {
+			e.rollback(saved)
+			return nil, err
+		}
+		e.history = append(e.history, st.Value)
+		t.Statements = append(t.Statements, st)
+	}
+
+	return t, nil
+}
+
+// valueNode is an already computed value inside a partially evaluated
+// expression.
+type valueNode struct {
+	value Value
+}
+
+func (n *valueNode) String() string {
+	return defaultPrinter.Print(n)
+}
+
+func newValueNode(v Value) Node {
+	if n, ok := v.(Number); ok {
+		return &NumberLit{Value: float64(n)}
+	}
+
+	return &valueNode{v}
+}
+
+func isValueNode(n Node) bool {
+	switch n := n.(type) {
+	case *NumberLit, *valueNode:
+		return true
+	case *ListExpr:
+		for _, elem := range n.Elems {
+			if !isValueNode(elem) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// step makes the leftmost innermost reduction in n, returning the reduced
+// expression and a description of the step.
+func (e *Evaluator) step(n Node) (Node, *Step, error) {
+	switch n := n.(type) {
+	case *Ident:
+		v, err := e.eval(n)
+		if err != nil {
+			return nil, nil, err
+		}
+		r := newValueNode(v)
+		return r, &Step{Kind: Substitution, Reduced: n, Result: r}, nil
+	case *UnaryExpr:
+		if !isValueNode(n.X) {
+			x, step, err := e.step(n.X)
+			return &UnaryExpr{Op: n.Op, X: x}, step, err
+		}
+		return e.reduce(Operation, n)
+	case *BinaryExpr:
+		if !isValueNode(n.X) {
+			x, step, err := e.step(n.X)
+			return &BinaryExpr{Op: n.Op, X: x, Y: n.Y, Pos: n.Pos}, step, err
+		}
+		if !isValueNode(n.Y) {
+			y, step, err := e.step(n.Y)
+			return &BinaryExpr{Op: n.Op, X: n.X, Y: y, Pos: n.Pos}, step, err
+		}
+		return e.reduce(Operation, n)
+	case *CallExpr:
+		if isSpecialForm(n.Func) {
+			return e.reduce(Application, n)
+		}
+		for i, arg := range n.Args {
+			if !isValueNode(arg) {
+				a, step, err := e.step(arg)
+				args := make([]Node, len(n.Args))
+				copy(args, n.Args)
+				args[i] = a
+				return &CallExpr{Func: n.Func, Args: args, Pos: n.Pos}, step, err
+			}
+		}
+		return e.reduce(Application, n)
+	case *DiceExpr, *LambdaExpr, *ComprehensionExpr:
+		return e.reduce(Application, n)
+	case *ListExpr:
+		for i, elem := range n.Elems {
+			if !isValueNode(elem) {
+				x, step, err := e.step(elem)
+				elems := make([]Node, len(n.Elems))
+				copy(elems, n.Elems)
+				elems[i] = x
+				return &ListExpr{Elems: elems}, step, err
+			}
+		}
+	case *AssignExpr:
+		if !isValueNode(n.Value) {
+			v, step, err := e.step(n.Value)
+			return &AssignExpr{Name: n.Name, Value: v}, step, err
+		}
+		return e.reduce(Assignment, n)
+	}
+
+	return nil, nil, fmt.Errorf("Unknown node %T", n)
+}
+
+// reduce evaluates n, whose operands are all values, in a single step.
+func (e *Evaluator) reduce(kind StepKind, n Node) (Node, *Step, error) {
+	v, err := e.eval(n)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r := newValueNode(v)
+	return r, &Step{Kind: kind, Reduced: n, Result: r}, nil
+}
@@ -0,0 +1,100 @@
+package calc
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	t.Run("Should record every reduction step", func(t *testing.T) {
+		testCases := []struct {
+			Input string
+			Steps []string
+		}{
+			{"1", nil},
+			{"(1 + 2) * 3", []string{"3 * 3", "9"}},
+			{"-(2 - 4)", []string{"-(-2)", "2"}},
+			{"log2(4 * 2)", []string{"log2(8)", "3"}},
+			{"a = (1 + 1)", []string{"a = 2", "2"}},
+			{"a = 3; a * a", []string{"3", "3 * a", "3 * 3", "9"}},
+			{"simplify(x + x) + 1", []string{"2 * x + 1"}},
+		}
+
+		for _, c := range testCases {
+			trace, err := NewEvaluator().Trace(c.Input)
+			if err != nil {
+				t.Fatalf("error (%s) not nil in test case %+v", err, c)
+			}
+
+			var steps []string
+			for _, st := range trace.Statements {
+				for _, step := range st.Steps {
+					steps = append(steps, step.Expr.String())
+				}
+			}
+
+			if len(steps) != len(c.Steps) {
+				t.Fatalf("%q != %q in test case %+v", steps, c.Steps, c)
+			}
+			for i := range steps {
+				if steps[i] != c.Steps[i] {
+					t.Fatalf("%q != %q in test case %+v", steps, c.Steps, c)
+				}
+			}
+		}
+	})
+
+	t.Run("Should tell substitutions and applications apart", func(t *testing.T) {
+		trace, err := NewEvaluator().Trace("x = 8; log2(x) + 1")
+		if err != nil {
+			t.Fatalf("error (%s) not nil", err)
+		}
+
+		expected := []StepKind{Substitution, Application, Operation}
+		steps := trace.Statements[1].Steps
+		if len(steps) != len(expected) {
+			t.Fatalf("%d steps != %d steps", len(steps), len(expected))
+		}
+		for i, step := range steps {
+			if step.Kind != expected[i] {
+				t.Fatalf("step %d is of kind %d, not %d", i+1, step.Kind, expected[i])
+			}
+		}
+
+		if v := trace.Value(); v != Number(4) {
+			t.Fatalf("%v != 4", v)
+		}
+	})
+
+	t.Run("Should fail like the evaluation", func(t *testing.T) {
+		for _, c := range []string{"1 / 0", "a = 2; sqrt(-a)", "f = x -> f(x); f(1)"} {
+			e := NewEvaluator()
+			if trace, err := e.Trace(c); err == nil {
+				t.Fatalf("Expected error, got %v for %q", trace, c)
+			}
+			if h := e.History(); len(h) != 0 {
+				t.Fatalf("Expected no history, got %v for %q", h, c)
+			}
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := NewEvaluator().TraceContext(ctx, "[k for k in range(5000)]")
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected the context's error, got %v", err)
+		}
+	})
+
+	t.Run("Should render the trace as text", func(t *testing.T) {
+		trace, err := NewEvaluator().Trace("x = 2; (x + 1) * 3")
+		if err != nil {
+			t.Fatalf("error (%s) not nil", err)
+		}
+
+		expected := "x = 2\n→ 2\n\n(x + 1) * 3\n→ (2 + 1) * 3   [x = 2]\n→ 3 * 3\n→ 9\n"
+		if trace.String() != expected {
+			t.Fatalf("%q != %q", trace.String(), expected)
+		}
+	})
+}
@@ -0,0 +1,253 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// PlusMinus is a convention for what a ± b means.
+type PlusMinus int
+
+const (
+	// PlusMinusInterval makes a ± b the interval from a - b to a + b.
+	PlusMinusInterval PlusMinus = iota
+	// PlusMinusUncertainty makes a ± b a measurement of a with standard
+	// uncertainty b, an Uncertain value.
+	PlusMinusUncertainty
+)
+
+// WithPlusMinus makes the evaluator follow convention p instead of
+// PlusMinusInterval.
+func WithPlusMinus(p PlusMinus) Option {
+	return func(e *Evaluator) {
+		e.plusMinus = p
+	}
+}
+
+// Uncertain is a measured value with a standard uncertainty, the value of
+// 9.81 ± 0.02 under PlusMinusUncertainty. Operators and builtins applied to
+// uncertain values propagate their uncertainties to first order: the result
+// depends linearly on the error of each measurement it was computed from.
+// Measurements are tracked separately, so x - x is exactly 0 when x is
+// uncertain, while errors of different measurements are independent.
+type Uncertain struct {
+	Value float64
+	// terms are the partial derivatives of the value with respect to each
+	// measurement, by the measurement's id, times its uncertainty.
+	terms map[int]float64
+}
+
+// Uncertainty returns the standard uncertainty of u.
+func (u Uncertain) Uncertainty() float64 {
+	var sum float64
+	for _, t := range u.terms {
+		sum += t * t
+	}
+
+	return math.Sqrt(sum)
+}
+
+// String prints u with its uncertainty rounded to one significant figure,
+// or two if the first one is 1, and its value rounded to the same decimal
+// place, as in 9.81 ± 0.02 or 1.52 ± 0.15.
+func (u Uncertain) String() string {
+	return u.Localize(Plain)
+}
+
+// Localize is String with numbers written with the separators of l.
+func (u Uncertain) Localize(l Locale) string {
+	value, uncertainty, exp := u.round()
+	value, uncertainty = l.Localize(value), l.Localize(uncertainty)
+	if exp != 0 {
+		return fmt.Sprintf("(%s ± %s)e%d", value, uncertainty, exp)
+	}
+
+	return value + " ± " + uncertainty
+}
+
+// round returns the digits of the value and uncertainty of u to print,
+// times 10^exp. Values far from 1 are printed in scientific notation.
+func (u Uncertain) round() (string, string, int) {
+	v, s := u.Value, u.Uncertainty()
+	if s == 0 || math.IsInf(s, 0) || math.IsNaN(s) || math.IsInf(v, 0) || math.IsNaN(v) {
+		return formatFloat(v), formatFloat(s), 0
+	}
+
+	// place is the power of ten of the last significant digit of s.
+	k := int(math.Floor(math.Log10(s)))
+	place := k
+	if first := math.Round(s / math.Pow10(k)); first == 1 || first == 10 {
+		if first == 10 {
+			k++
+		}
+		place = k - 1
+	}
+	s = math.Round(s/math.Pow10(place)) * math.Pow10(place)
+
+	exp := 0
+	if e := int(math.Floor(math.Log10(math.Max(math.Abs(v), s)))); e >= 9 || e < -5 {
+		exp = e
+	}
+	place -= exp
+	v, s = v/math.Pow10(exp), s/math.Pow10(exp)
+
+	decimals := 0
+	if place < 0 {
+		decimals = -place
+	} else {
+		v = math.Round(v/math.Pow10(place)) * math.Pow10(place)
+	}
+
+	return strconv.FormatFloat(v, 'f', decimals, 64), strconv.FormatFloat(s, 'f', decimals, 64), exp
+}
+
+// measure returns a new measurement of x with uncertainty s, independent of
+// every other one.
+func (e *Evaluator) measure(x, s Value) (Value, error) {
+	u, ok := toUncertain(x)
+	if !ok {
+		return nil, fmt.Errorf("Operator ± is not defined for %s", x)
+	}
+	r, ok := toFloat(s).(Number)
+	if !ok {
+		return nil, fmt.Errorf("Uncertainty %s is not a number", s)
+	}
+
+	e.measurements++
+	return u.add(1, Uncertain{terms: map[int]float64{e.measurements: math.Abs(float64(r))}}, 1), nil
+}
+
+// toUncertain converts numbers to uncertain values with no uncertainty.
+func toUncertain(v Value) (Uncertain, bool) {
+	switch v := toFloat(v).(type) {
+	case Number:
+		return Uncertain{Value: float64(v)}, true
+	case Uncertain:
+		return v, true
+	}
+
+	return Uncertain{}, false
+}
+
+func isUncertain(v Value) bool {
+	_, ok := v.(Uncertain)
+	return ok
+}
+
+// add returns the terms of a*u + b*w, with the value of u.
+func (u Uncertain) add(a float64, w Uncertain, b float64) Uncertain {
+	terms := make(map[int]float64, len(u.terms)+len(w.terms))
+	for id, t := range u.terms {
+		terms[id] += a * t
+	}
+	for id, t := range w.terms {
+		terms[id] += b * t
+	}
+
+	return Uncertain{u.Value, terms}
+}
+
+// uncertainBinary applies op to x and y, one of which is uncertain.
+func uncertainBinary(op string, x, y Value) (Value, error) {
+	a, aok := toUncertain(x)
+	b, bok := toUncertain(y)
+	if !aok || !bok {
+		v := x
+		if aok {
+			v = y
+		}
+		return nil, fmt.Errorf("Operator %s is not defined for %s", op, v)
+	}
+
+	var r Uncertain
+	switch op {
+	case "+":
+		r = a.add(1, b, 1)
+		r.Value = a.Value + b.Value
+	case "-":
+		r = a.add(1, b, -1)
+		r.Value = a.Value - b.Value
+	case "*":
+		r = a.add(b.Value, b, a.Value)
+		r.Value = a.Value * b.Value
+	case "/":
+		r = a.add(1/b.Value, b, -a.Value/(b.Value*b.Value))
+		r.Value = a.Value / b.Value
+	case "<", ">", "<=", ">=", "==", "!=":
+		// Comparisons are of the measured values.
+		return applyBinary(op, Number(a.Value), Number(b.Value))
+	default:
+		return nil, fmt.Errorf("Operator %s is not defined for %s", op, x)
+	}
+
+	return r, nil
+}
+
+// callUncertain applies the builtin of n to args, one or more of which are
+// uncertain.
+func (e *Evaluator) callUncertain(n *CallExpr, args []Value) (Value, error) {
+	partials, ok := derivatives[n.Func]
+	if !ok {
+		return nil, fmt.Errorf("%s can't be applied to uncertain values", n.Func)
+	}
+
+	values := make([]Uncertain, len(args))
+	x := make([]float64, len(args))
+	for i, arg := range args {
+		if values[i], ok = toUncertain(arg); !ok {
+			return nil, fmt.Errorf("%s can't be passed to %s", arg, n.Func)
+		}
+		x[i] = values[i].Value
+	}
+
+	if err := e.checkBuiltin(n, x); err != nil {
+		return nil, err
+	}
+
+	r := Uncertain{Value: builtins[n.Func].fn(x...)}
+	for i, d := range partials(x) {
+		if len(values[i].terms) > 0 {
+			r = r.add(1, values[i], d)
+		}
+	}
+
+	return r, nil
+}
+
+// derivatives are the partial derivatives of the builtins with respect to
+// each of their arguments.
+var derivatives = map[string]func(x []float64) []float64{
+	"log": func(x []float64) []float64 {
+		lb := math.Log(x[0])
+		return []float64{-math.Log(x[1]) / (x[0] * lb * lb), 1 / (x[1] * lb)}
+	},
+	"log10": func(x []float64) []float64 { return []float64{1 / (x[0] * math.Ln10)} },
+	"log2":  func(x []float64) []float64 { return []float64{1 / (x[0] * math.Ln2)} },
+	"ln":    func(x []float64) []float64 { return []float64{1 / x[0]} },
+	"pow": func(x []float64) []float64 {
+		// x^y changes with y only for positive x.
+		dy := 0.0
+		if x[0] > 0 {
+			dy = math.Pow(x[0], x[1]) * math.Log(x[0])
+		}
+		return []float64{x[1] * math.Pow(x[0], x[1]-1), dy}
+	},
+	"exp":  func(x []float64) []float64 { return []float64{math.Exp(x[0])} },
+	"sqrt": func(x []float64) []float64 { return []float64{1 / (2 * math.Sqrt(x[0]))} },
+	"abs": func(x []float64) []float64 {
+		if x[0] < 0 {
+			return []float64{-1}
+		}
+		return []float64{1}
+	},
+	"sin": func(x []float64) []float64 { return []float64{math.Cos(x[0])} },
+	"cos": func(x []float64) []float64 { return []float64{-math.Sin(x[0])} },
+	"tan": func(x []float64) []float64 {
+		t := math.Tan(x[0])
+		return []float64{1 + t*t}
+	},
+	"asin": func(x []float64) []float64 { return []float64{1 / math.Sqrt(1-x[0]*x[0])} },
+	"acos": func(x []float64) []float64 { return []float64{-1 / math.Sqrt(1-x[0]*x[0])} },
+	"atan": func(x []float64) []float64 { return []float64{1 / (1 + x[0]*x[0])} },
+}
@@ -0,0 +1,115 @@
+package calc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUncertain(t *testing.T) {
+	t.Run("Should propagate uncertainties", func(t *testing.T) {
+		testCases := []struct {
+			Input       string
+			Value       float64
+			Uncertainty float64
+		}{
+			{"9.81 ± 0.02", 9.81, 0.02},
+			{"-(9.81 ± 0.02)", -9.81, 0.02},
+			{"(1 ± 0.3) + (2 ± 0.4)", 3, 0.5},
+			{"(1 ± 0.3) - (2 ± 0.4)", -1, 0.5},
+			{"(2 ± 0.1) * 3", 6, 0.3},
+			{"(2 ± 0.02) * (3 ± 0.06)", 6, math.Sqrt(0.06*0.06 + 0.12*0.12)},
+			{"1 / (4 ± 0.1)", 0.25, 0.1 / 16},
+			{"sqrt(4 ± 0.4)", 2, 0.1},
+			{"ln(2 ± 0.1)", math.Ln2, 0.05},
+			{"pow(2 ± 0.1, 3)", 8, 1.2},
+			{"sin(0 ± 0.1)", 0, 0.1},
+			{"abs(-2 ± 0.1)", 2, 0.1},
+			{"x = 2 ± 0.1; x - x", 0, 0},
+			{"x = 2 ± 0.1; x + x", 4, 0.2},
+			{"x = 2 ± 0.1; x * x", 4, 0.4},
+			{"x = 2 ± 0.1; x / x", 1, 0},
+			{"x = 2 ± 0.1; y = 2 ± 0.1; x - y", 0, math.Sqrt(0.02)},
+			{"(2 ± 0.3) ± 0.4", 2, 0.5},
+		}
+
+		for _, c := range testCases {
+			v, err := NewEvaluator(WithPlusMinus(PlusMinusUncertainty)).Evaluate(c.Input)
+			if err != nil {
+				t.Fatalf("error (%s) not nil in test case %+v", err, c)
+			}
+
+			u, ok := v.(Uncertain)
+			if !ok || !floatEquals(u.Value, c.Value, 1e-9) || !floatEquals(u.Uncertainty(), c.Uncertainty, 1e-9) {
+				t.Fatalf("%v != %v ± %v in test case %q", v, c.Value, c.Uncertainty, c.Input)
+			}
+		}
+	})
+
+	t.Run("Should keep measurements independent across programs", func(t *testing.T) {
+		e := NewEvaluator(WithPlusMinus(PlusMinusUncertainty))
+		if _, err := e.Evaluate("x = 1 ± 0.3"); err != nil {
+			t.Fatalf("error (%s) not nil", err)
+		}
+
+		v, err := e.Evaluate("y = 1 ± 0.4; x - y")
+		if err != nil {
+			t.Fatalf("error (%s) not nil", err)
+		}
+		if u := v.(Uncertain).Uncertainty(); !floatEquals(u, 0.5, 1e-9) {
+			t.Fatalf("%v != 0.5", u)
+		}
+	})
+
+	t.Run("Should print significant figures", func(t *testing.T) {
+		testCases := []struct {
+			Input   string
+			Printed string
+		}{
+			{"9.81 ± 0.02", "9.81 ± 0.02"},
+			{"9.8123 ± 0.0234", "9.81 ± 0.02"},
+			{"9.8123 ± 0.0149", "9.812 ± 0.015"},
+			{"9.8123 ± 0.096", "9.81 ± 0.10"},
+			{"(9.81 ± 0.02) * (1.5 ± 0.1)", "14.7 ± 1.0"},
+			{"12345 ± 230", "12300 ± 200"},
+			{"-0.5 ± 1", "-0.5 ± 1.0"},
+			{"3 ± 0", "3 ± 0"},
+			{"6.02214076e23 ± 3e15", "(6.02214076 ± 0.00000003)e23"},
+			{"1.234e-9 ± 5e-12", "(1.234 ± 0.005)e-9"},
+		}
+
+		for _, c := range testCases {
+			v, err := NewEvaluator(WithPlusMinus(PlusMinusUncertainty)).Evaluate(c.Input)
+			if err != nil || v.String() != c.Printed {
+				t.Fatalf("%q != %q or error (%s) not nil in test case %+v", v, c.Printed, err, c)
+			}
+		}
+
+		v, _ := NewEvaluator(WithPlusMinus(PlusMinusUncertainty)).Evaluate("1234.5678 ± 0.012")
+		if s := v.(Uncertain).Localize(German); s != "1.234,568 ± 0,012" {
+			t.Fatalf("%q != %q", s, "1.234,568 ± 0,012")
+		}
+	})
+
+	t.Run("Should fail outside of the domains", func(t *testing.T) {
+		testCases := []string{
+			"1 / (0 ± 1)",
+			"ln(-1 ± 0.1)",
+			"gcd(1 ± 1, 2)",
+			"(1 ± 1) ± (1 ± 1)",
+			"interval(1, 2) + (1 ± 1)",
+			"[1] ± 1",
+		}
+
+		for _, c := range testCases {
+			if _, err := NewEvaluator(WithPlusMinus(PlusMinusUncertainty)).Evaluate(c); err == nil {
+				t.Fatalf("error is nil for %q", c)
+			}
+		}
+	})
+
+	t.Run("Should make intervals by default", func(t *testing.T) {
+		if v, _ := NewEvaluator().Evaluate("9.81 ± 0.02"); !isInterval(v) {
+			t.Fatalf("%v is not an interval", v)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package calc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUnicodeInput(t *testing.T) {
+	t.Run("Should accept Unicode operators and symbols", func(t *testing.T) {
+		testCases := []struct {
+			Input    string
+			Expected float64
+		}{
+			{"2 × 3", 6},
+			{"3 · 4", 12},
+			{"6 ÷ 4", 1.5},
+			{"5 − 2", 3},
+			{"−2", -2},
+			{"√16", 4},
+			{"√(9 + 7)", 4},
+			{"2√2", 2 * math.Sqrt2},
+			{"x = 3; √2x", 3 * math.Sqrt2},
+			{"π", math.Pi},
+			{"2π", 2 * math.Pi},
+			{"r = 2; π r²", 4 * math.Pi},
+			{"τ", 2 * math.Pi},
+			{"ℯ", math.E},
+			{"x = 3; x²", 9},
+			{"x = 2; x³ − 1", 7},
+			{"x = 3; −x²", -9},
+			{"x = 3; 2x²", 18},
+			{"x = 4; x⁻¹", 0.25},
+			{"x = 2; y = 3; x²y", 12},
+			{"10¹²", 1e12},
+			{"½", 0.5},
+			{"1½", 1.5},
+			{"3¾ × 4", 15},
+			{"⅓ + ⅔", 1},
+			{"1 ≤ 2", 1},
+			{"2 ≤ 1", 0},
+			{"3 ≥ 3", 1},
+			{"1 < 2", 1},
+			{"1 > 2", 0},
+			{"1 + 1 >= 2", 1},
+		}
+
+		for _, tc := range testCases {
+			actual, err := Evaluate(tc.Input)
+			if err != nil {
+				t.Fatalf("error (%s) not nil for %s", err, tc.Input)
+			}
+			if !floatEquals(actual, tc.Expected, 1e-9) {
+				t.Fatalf("%s: expected %f, got %f", tc.Input, tc.Expected, actual)
+			}
+		}
+	})
+
+	t.Run("Should parse symbols as the ASCII they stand for", func(t *testing.T) {
+		testCases := []struct {
+			Input     string
+			Formatted string
+		}{
+			{"a × b ÷ c − d", "a * b / c - d"},
+			{"√x", "sqrt(x)"},
+			{"x²", "x^2"},
+			{"(x + 1)²", "(x + 1)^2"},
+			{"−x²", "-x^2"},
+			{"πr", "πr"},
+			{"τὰφυσικά", "τὰφυσικά"},
+			{"a ≤ b + 1", "a <= b + 1"},
+			{"(a < b) + 1", "(a < b) + 1"},
+		}
+
+		for _, c := range testCases {
+			formatted, err := Format(c.Input)
+			if err != nil || formatted != c.Formatted {
+				t.Fatalf("%q != %q or error (%s) not nil", formatted, c.Formatted, err)
+			}
+		}
+	})
+
+	t.Run("Should parse what the Unicode printer prints", func(t *testing.T) {
+		programs := []string{
+			"pow(x, 2) * 3 - 1 / 2",
+			"pow(x, -1) + pow(y, 3)",
+			"pow(x, 1.5) + pow(-x, 2.5)",
+			"-2 * a <= b",
+			"√x",
+			"√(x + 1) / 2",
+			"pow(√x, y) + √x²",
+		}
+
+		unicode := &Printer{Unicode: true}
+		for _, p := range programs {
+			printed, err := unicode.Format(p)
+			if err != nil {
+				t.Fatalf("error (%s) not nil for %s", err, p)
+			}
+
+			formatted, err := Format(p)
+			reformatted, rerr := Format(printed)
+			if err != nil || rerr != nil || formatted != reformatted {
+				t.Fatalf("%s printed as %s, which parses as %s", p, printed, reformatted)
+			}
+		}
+	})
+}
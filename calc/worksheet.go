@@ -0,0 +1,99 @@
+package calc
+
+import (
+	"bytes"
+	"context"
+)
+
+// Span is where a statement is in its program, program[Start:End], without
+// the white space and comments around it.
+type Span struct {
+	Start, End int
+}
+
+// StatementResult is the value of one statement of a program.
+type StatementResult struct {
+	Stmt   Node
+	Span   Span
+	Source string // the statement as written
+	Name   string // variable the statement assigns, if it is an assignment
+	Value  Value
+}
+
+// Worksheet holds the value of every statement of a program, e.g.
+// a = 4 → 4 and log2(a) → 2 for a = 4; log2(a).
+type Worksheet struct {
+	Statements []StatementResult
+}
+
+// Value returns the value of the program's last statement.
+func (w *Worksheet) Value() Value {
+	if len(w.Statements) == 0 {
+		return nil
+	}
+
+	return w.Statements[len(w.Statements)-1].Value
+}
+
+// String renders the worksheet as text, one statement and its value per
+// line.
+func (w *Worksheet) String() string {
+	var b bytes.Buffer
+	for _, st := range w.Statements {
+		b.WriteString(st.Source + " → " + st.Value.String() + "\n")
+	}
+
+	return b.String()
+}
+
+// Worksheet evaluates program like Evaluate, but returns the value of every
+// statement rather than only the last one.
+func (e *Evaluator) Worksheet(program string) (*Worksheet, error) {
+	return e.WorksheetContext(context.Background(), program)
+}
+
+// WorksheetContext is like Worksheet, but gives up with the context's error
+// once ctx is done.
+func (e *Evaluator) WorksheetContext(ctx context.Context, program string) (*Worksheet, error) {
+	saved := e.save()
+	w, err := e.worksheet(ctx, program)
+	if err != nil {
+		e.rollback(saved)
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// worksheet parses and evaluates program, adding the value of its statements
+// to the history. Every value, not only the last one, must be short enough
+// to print under Limits.MaxOutputLength. Callers roll the evaluator back if
+// it fails.
+func (e *Evaluator) worksheet(ctx context.Context, program string) (*Worksheet, error) {
+	stmts, spans, err := e.parse(program)
+	if err != nil {
+		return nil, err
+	}
+
+	e.start(ctx)
+	w := &Worksheet{}
+	for i, stmt := range stmts {
+		v, err := e.eval(stmt)
+		if err == nil {
+			err = e.checkOutput(v)
+		}
+		if err != nil {
+			return nil, err
+		}
+		e.history = append(e.history, v)
+
+		st := StatementResult{Stmt: stmt, Span: spans[i], Value: v}
+		st.Source = program[st.Span.Start:st.Span.End]
+		if assign, ok := stmt.(*AssignExpr); ok {
+			st.Name = assign.Name
+		}
+		w.Statements = append(w.Statements, st)
+	}
+
+	return w, nil
+}
@@ -0,0 +1,95 @@
+package calc
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWorksheet(t *testing.T) {
+	t.Run("Should return every statement's result", func(t *testing.T) {
+		program := "a = 4; log2(a)\n# the square\nsq = x ->\n  x * x # of x\nsq(a)"
+		w, err := NewEvaluator().Worksheet(program)
+		if err != nil {
+			t.Fatalf("Error (%s) not nil", err)
+		}
+
+		expected := []struct {
+			Source string
+			Name   string
+			Value  string
+		}{
+			{"a = 4", "a", "4"},
+			{"log2(a)", "", "2"},
+			{"sq = x ->\n  x * x", "sq", "x -> x * x"},
+			{"sq(a)", "", "16"},
+		}
+		if len(w.Statements) != len(expected) {
+			t.Fatalf("%d != %d statements in %v", len(w.Statements), len(expected), w)
+		}
+		for i, c := range expected {
+			st := w.Statements[i]
+			if st.Source != c.Source || st.Name != c.Name || st.Value.String() != c.Value {
+				t.Fatalf("%+v doesn't match %+v", st, c)
+			}
+			if program[st.Span.Start:st.Span.End] != st.Source {
+				t.Fatalf("Span %+v doesn't match %q", st.Span, st.Source)
+			}
+		}
+	})
+
+	t.Run("Should find the spans of statements", func(t *testing.T) {
+		testCases := []struct {
+			Input string
+			Spans []Span
+		}{
+			{"1", []Span{{0, 1}}},
+			{"  2x + 1  ", []Span{{2, 8}}},
+			{"a=1;b=2", []Span{{0, 3}, {4, 7}}},
+			{"\n\n1 # one\n\n2 ;3", []Span{{2, 3}, {11, 12}, {14, 15}}},
+			{"sqrt(2); π", []Span{{0, 7}, {9, 11}}},
+		}
+
+		for _, c := range testCases {
+			w, err := NewEvaluator().Worksheet(c.Input)
+			if err != nil || len(w.Statements) != len(c.Spans) {
+				t.Fatalf("Unexpected worksheet %v or error (%s) not nil in test case %+v", w, err, c)
+			}
+			for i, span := range c.Spans {
+				if w.Statements[i].Span != span {
+					t.Fatalf("%+v != %+v in test case %+v", w.Statements[i].Span, span, c)
+				}
+			}
+		}
+	})
+
+	t.Run("Should print worksheets", func(t *testing.T) {
+		w, err := NewEvaluator().Worksheet("a = 4; log2(a)")
+		if err != nil || w.String() != "a = 4 → 4\nlog2(a) → 2\n" {
+			t.Fatalf("%q is unexpected or error (%s) not nil", w, err)
+		}
+		if w.Value().String() != "2" {
+			t.Fatalf("%v != 2", w.Value())
+		}
+	})
+
+	t.Run("Should check the output of every statement", func(t *testing.T) {
+		e := NewEvaluator(WithLimits(Limits{MaxOutputLength: 10}))
+		_, err := e.Worksheet("range(100); 1")
+		var output *OutputTooLongError
+		if !errors.As(err, &output) {
+			t.Fatalf("Expected an OutputTooLongError, got %v", err)
+		}
+		if len(e.History()) != 0 {
+			t.Fatalf("Expected no history, got %v", e.History())
+		}
+
+		for _, program := range []string{"range(100); 1", "x = range(1, 100); 0"} {
+			if _, err := e.Evaluate(program); !errors.As(err, &output) {
+				t.Fatalf("Expected an OutputTooLongError for %q, got %v", program, err)
+			}
+			if _, err := e.Trace(program); !errors.As(err, &output) {
+				t.Fatalf("Expected an OutputTooLongError tracing %q, got %v", program, err)
+			}
+		}
+	})
+}
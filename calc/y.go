@@ -1,29 +1,48 @@
+// Code generated by goyacc -o y.go calc.y. DO NOT EDIT.
+
 //line calc.y:2
 package calc
 
 import __yyfmt__ "fmt"
 
 //line calc.y:2
-import (
-	"math"
-)
 
-//line calc.y:9
+import "math/big"
+
+//line calc.y:8
 type yySymType struct {
-	yys  int
-	val  float64
-	name string
+	yys   int
+	val   float64
+	num   *big.Int // exact value of a NUMBER too large for val
+	name  string
+	names []string // parameters of a LAMBDA
+	node  Node
+	nodes []Node
+	pos   int // position of the token, counting bytes from 1
 }
 
 const NUMBER = 57346
 const IDENTIFIER = 57347
-const LOG = 57348
-const LOG10 = 57349
-const LOG2 = 57350
-const LN = 57351
-const POW = 57352
-const EXP = 57353
-const UMINUS = 57354
+const DICE = 57348
+const LOG = 57349
+const LOG10 = 57350
+const LOG2 = 57351
+const LN = 57352
+const POW = 57353
+const EXP = 57354
+const IMPLICIT = 57355
+const LE = 57356
+const GE = 57357
+const SQRT = 57358
+const PM = 57359
+const EQ = 57360
+const NE = 57361
+const FOR = 57362
+const IN = 57363
+const IF = 57364
+const LAMBDA = 57365
+const SUPERSCRIPT = 57366
+const UMINUS = 57367
 
 var yyToknames = [...]string{
 	"$end",
@@ -31,109 +50,240 @@ var yyToknames = [...]string{
 	"$unk",
 	"NUMBER",
 	"IDENTIFIER",
+	"DICE",
 	"LOG",
 	"LOG10",
 	"LOG2",
 	"LN",
 	"POW",
 	"EXP",
+	"IMPLICIT",
+	"LE",
+	"GE",
+	"SQRT",
+	"PM",
+	"EQ",
+	"NE",
+	"FOR",
+	"IN",
+	"IF",
+	"LAMBDA",
+	"SUPERSCRIPT",
+	"'<'",
+	"'>'",
 	"'+'",
 	"'-'",
 	"'*'",
 	"'/'",
+	"'%'",
 	"'='",
 	"UMINUS",
+	"'^'",
 	"';'",
 	"'('",
 	"')'",
 	"','",
+	"'['",
+	"']'",
 }
+
 var yyStatenames = [...]string{}
 
 const yyEofCode = 1
 const yyErrCode = 2
 const yyInitialStackSize = 16
 
-//line calc.y:52
+//line calc.y:100
+
 //line yacctab:1
-var yyExca = [...]int{
+var yyExca = [...]int8{
 	-1, 1,
 	1, -1,
 	-2, 0,
+	-1, 56,
+	14, 0,
+	15, 0,
+	18, 0,
+	19, 0,
+	25, 0,
+	26, 0,
+	-2, 13,
+	-1, 57,
+	14, 0,
+	15, 0,
+	18, 0,
+	19, 0,
+	25, 0,
+	26, 0,
+	-2, 14,
+	-1, 58,
+	14, 0,
+	15, 0,
+	18, 0,
+	19, 0,
+	25, 0,
+	26, 0,
+	-2, 15,
+	-1, 59,
+	14, 0,
+	15, 0,
+	18, 0,
+	19, 0,
+	25, 0,
+	26, 0,
+	-2, 16,
+	-1, 60,
+	14, 0,
+	15, 0,
+	18, 0,
+	19, 0,
+	25, 0,
+	26, 0,
+	-2, 17,
+	-1, 61,
+	14, 0,
+	15, 0,
+	18, 0,
+	19, 0,
+	25, 0,
+	26, 0,
+	-2, 18,
 }
 
 const yyPrivate = 57344
 
-const yyLast = 102
-
-var yyAct = [...]int{
-
-	2, 14, 15, 16, 17, 18, 19, 25, 1, 24,
-	44, 23, 22, 21, 27, 28, 29, 30, 31, 20,
-	13, 33, 34, 35, 36, 37, 38, 39, 3, 12,
-	6, 7, 8, 9, 10, 11, 26, 4, 0, 16,
-	17, 46, 0, 5, 0, 47, 14, 15, 16, 17,
-	14, 15, 16, 17, 0, 40, 0, 0, 49, 14,
-	15, 16, 17, 14, 15, 16, 17, 48, 0, 0,
-	0, 45, 14, 15, 16, 17, 14, 15, 16, 17,
-	43, 0, 0, 0, 42, 14, 15, 16, 17, 14,
-	15, 16, 17, 41, 0, 0, 0, 32, 14, 15,
-	16, 17,
+const yyLast = 498
+
+var yyAct = [...]int8{
+	2, 75, 41, 74, 40, 45, 33, 34, 35, 83,
+	75, 43, 39, 38, 37, 42, 46, 47, 48, 49,
+	50, 51, 52, 53, 54, 55, 56, 57, 58, 59,
+	60, 61, 62, 36, 17, 88, 1, 64, 65, 66,
+	67, 68, 69, 72, 73, 24, 27, 28, 71, 23,
+	29, 30, 85, 0, 93, 32, 32, 25, 26, 18,
+	19, 20, 21, 22, 24, 31, 31, 0, 23, 0,
+	0, 0, 92, 0, 0, 32, 84, 0, 86, 0,
+	0, 0, 87, 0, 24, 31, 24, 27, 28, 91,
+	23, 29, 30, 0, 94, 32, 0, 32, 25, 26,
+	18, 19, 20, 21, 22, 31, 0, 31, 0, 0,
+	0, 0, 0, 95, 3, 14, 4, 8, 9, 10,
+	11, 12, 13, 0, 24, 0, 6, 0, 23, 0,
+	0, 0, 0, 16, 0, 32, 0, 0, 5, 0,
+	20, 21, 22, 0, 0, 31, 7, 0, 0, 15,
+	44, 24, 27, 28, 0, 23, 29, 30, 0, 0,
+	0, 0, 32, 25, 26, 18, 19, 20, 21, 22,
+	0, 0, 31, 24, 27, 28, 81, 23, 29, 30,
+	0, 0, 0, 0, 32, 25, 26, 18, 19, 20,
+	21, 22, 0, 0, 31, 0, 0, 0, 77, 3,
+	14, 4, 8, 9, 10, 11, 12, 13, 0, 0,
+	0, 6, 0, 0, 0, 0, 0, 0, 16, 0,
+	0, 0, 0, 5, 0, 0, 0, 0, 0, 0,
+	0, 7, 70, 0, 15, 3, 14, 4, 8, 9,
+	10, 11, 12, 13, 0, 0, 0, 6, 0, 0,
+	0, 0, 0, 0, 16, 0, 0, 0, 0, 5,
+	24, 27, 28, 0, 23, 29, 30, 7, 0, 0,
+	15, 32, 25, 26, 18, 19, 20, 21, 22, 0,
+	0, 31, 0, 0, 90, 24, 27, 28, 0, 23,
+	29, 30, 0, 0, 0, 0, 32, 25, 26, 18,
+	19, 20, 21, 22, 0, 0, 31, 0, 0, 89,
+	24, 27, 28, 0, 23, 29, 30, 0, 0, 0,
+	0, 32, 25, 26, 18, 19, 20, 21, 22, 0,
+	0, 31, 0, 0, 82, 24, 27, 28, 0, 23,
+	29, 30, 0, 0, 0, 0, 32, 25, 26, 18,
+	19, 20, 21, 22, 0, 0, 31, 0, 0, 80,
+	24, 27, 28, 0, 23, 29, 30, 0, 0, 0,
+	0, 32, 25, 26, 18, 19, 20, 21, 22, 0,
+	0, 31, 0, 0, 79, 24, 27, 28, 0, 23,
+	29, 30, 0, 0, 0, 0, 32, 25, 26, 18,
+	19, 20, 21, 22, 0, 0, 31, 0, 0, 78,
+	24, 27, 28, 0, 23, 29, 30, 0, 0, 0,
+	0, 32, 25, 26, 18, 19, 20, 21, 22, 0,
+	0, 31, 0, 0, 63, 24, 27, 28, 0, 23,
+	29, 30, 76, 0, 0, 0, 32, 25, 26, 18,
+	19, 20, 21, 22, 0, 0, 31, 24, 27, 28,
+	0, 23, 29, 30, 0, 0, 0, 0, 32, 25,
+	26, 18, 19, 20, 21, 22, 24, 0, 31, 0,
+	23, 0, 0, 0, 0, 0, 0, 32, 0, 0,
+	18, 19, 20, 21, 22, 0, 0, 31,
 }
-var yyPact = [...]int{
 
-	24, 2, 86, -1000, 24, 24, 0, -6, -7, -8,
-	-10, -12, 20, 24, 24, 24, 24, 24, -1000, 77,
-	24, 24, 24, 24, 24, 24, 24, 86, 25, 25,
-	-1000, -1000, -1000, 34, 73, 64, 60, -11, 51, -1000,
-	24, -1000, -1000, -1000, 24, -1000, 47, 38, -1000, -1000,
+var yyPact = [...]int16{
+	231, -1, 444, -1000, -1000, 231, 231, 231, -3, -22,
+	-23, -24, -32, -34, -21, 110, 231, 231, 231, 231,
+	231, 231, 231, 231, 231, 231, 231, 231, 231, 231,
+	231, 231, -1000, 31, 31, 397, 231, 231, 231, 231,
+	231, 231, 195, 231, -1000, -37, 422, 444, 444, 111,
+	111, 51, 51, 51, 71, 31, 463, 463, 463, 463,
+	463, 463, 31, -1000, 160, 372, 347, 322, 138, 297,
+	-1000, -28, 444, 51, -1000, 231, 47, 231, -1000, -1000,
+	-1000, 231, -1000, -1000, 444, 14, 272, 247, 231, -1000,
+	-1000, 32, -1000, 231, 73, -1000,
 }
-var yyPgo = [...]int{
 
-	0, 0, 8,
+var yyPgo = [...]int8{
+	0, 0, 5, 36,
 }
-var yyR1 = [...]int{
 
-	0, 2, 2, 1, 1, 1, 1, 1, 1, 1,
-	1, 1, 1, 1, 1, 1, 1, 1,
+var yyR1 = [...]int8{
+	0, 3, 3, 1, 1, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 1, 1, 1, 2, 2,
 }
-var yyR2 = [...]int{
 
-	0, 1, 3, 1, 2, 3, 3, 3, 3, 3,
-	6, 4, 4, 4, 6, 4, 1, 3,
+var yyR2 = [...]int8{
+	0, 1, 3, 1, 1, 2, 3, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	2, 2, 3, 6, 4, 4, 4, 6, 4, 3,
+	4, 2, 3, 7, 9, 2, 1, 3, 1, 3,
 }
-var yyChk = [...]int{
 
-	-1000, -2, -1, 4, 13, 19, 6, 7, 8, 9,
-	10, 11, 5, 18, 12, 13, 14, 15, -1, -1,
-	19, 19, 19, 19, 19, 19, 16, -1, -1, -1,
-	-1, -1, 20, -1, -1, -1, -1, -1, -1, -1,
-	21, 20, 20, 20, 21, 20, -1, -1, 20, 20,
+var yyChk = [...]int16{
+	-1000, -3, -1, 4, 6, 28, 16, 36, 7, 8,
+	9, 10, 11, 12, 5, 39, 23, 35, 27, 28,
+	29, 30, 31, 17, 13, 25, 26, 14, 15, 18,
+	19, 34, 24, -1, -1, -1, 36, 36, 36, 36,
+	36, 36, 36, 32, 40, -2, -1, -1, -1, -1,
+	-1, -1, -1, -1, -1, -1, -1, -1, -1, -1,
+	-1, -1, -1, 37, -1, -1, -1, -1, -1, -1,
+	37, -2, -1, -1, 40, 38, 20, 38, 37, 37,
+	37, 38, 37, 37, -1, 5, -1, -1, 21, 37,
+	37, -1, 40, 22, -1, 40,
 }
-var yyDef = [...]int{
 
-	0, -2, 1, 3, 0, 0, 0, 0, 0, 0,
-	0, 0, 16, 0, 0, 0, 0, 0, 4, 0,
-	0, 0, 0, 0, 0, 0, 0, 2, 5, 6,
-	7, 8, 9, 0, 0, 0, 0, 0, 0, 17,
-	0, 11, 12, 13, 0, 15, 0, 0, 10, 14,
+var yyDef = [...]int8{
+	0, -2, 1, 3, 4, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 36, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 20, 5, 21, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 31, 0, 38, 35, 2, 6,
+	7, 8, 9, 10, 11, 12, -2, -2, -2, -2,
+	-2, -2, 19, 22, 0, 0, 0, 0, 0, 0,
+	29, 0, 38, 37, 32, 0, 0, 0, 24, 25,
+	26, 0, 28, 30, 39, 0, 0, 0, 0, 23,
+	27, 0, 33, 0, 0, 34,
 }
-var yyTok1 = [...]int{
 
+var yyTok1 = [...]int8{
 	1, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 31, 3, 3,
+	36, 37, 29, 27, 38, 28, 3, 30, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 3, 35,
+	25, 32, 26, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	19, 20, 14, 12, 21, 13, 3, 15, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 3, 18,
-	3, 16,
+	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 39, 3, 40, 34,
 }
-var yyTok2 = [...]int{
 
+var yyTok2 = [...]int8{
 	2, 3, 4, 5, 6, 7, 8, 9, 10, 11,
-	17,
+	12, 13, 14, 15, 16, 17, 18, 19, 20, 21,
+	22, 23, 24, 33,
 }
-var yyTok3 = [...]int{
+
+var yyTok3 = [...]int8{
 	0,
 }
 
@@ -215,9 +365,9 @@ func yyErrorMessage(state, lookAhead int) string {
 	expected := make([]int, 0, 4)
 
 	// Look for shiftable tokens.
-	base := yyPact[state]
+	base := int(yyPact[state])
 	for tok := TOKSTART; tok-1 < len(yyToknames); tok++ {
-		if n := base + tok; n >= 0 && n < yyLast && yyChk[yyAct[n]] == tok {
+		if n := base + tok; n >= 0 && n < yyLast && int(yyChk[int(yyAct[n])]) == tok {
 			if len(expected) == cap(expected) {
 				return res
 			}
@@ -227,13 +377,13 @@ func yyErrorMessage(state, lookAhead int) string {
 
 	if yyDef[state] == -2 {
 		i := 0
-		for yyExca[i] != -1 || yyExca[i+1] != state {
+		for yyExca[i] != -1 || int(yyExca[i+1]) != state {
 			i += 2
 		}
 
 		// Look for tokens that we accept or reduce.
 		for i += 2; yyExca[i] >= 0; i += 2 {
-			tok := yyExca[i]
+			tok := int(yyExca[i])
 			if tok < TOKSTART || yyExca[i+1] == 0 {
 				continue
 			}
@@ -264,30 +414,30 @@ func yylex1(lex yyLexer, lval *yySymType) (char, token int) {
 	token = 0
 	char = lex.Lex(lval)
 	if char <= 0 {
-		token = yyTok1[0]
+		token = int(yyTok1[0])
 		goto out
 	}
 	if char < len(yyTok1) {
-		token = yyTok1[char]
+		token = int(yyTok1[char])
 		goto out
 	}
 	if char >= yyPrivate {
 		if char < yyPrivate+len(yyTok2) {
-			token = yyTok2[char-yyPrivate]
+			token = int(yyTok2[char-yyPrivate])
 			goto out
 		}
 	}
 	for i := 0; i < len(yyTok3); i += 2 {
-		token = yyTok3[i+0]
+		token = int(yyTok3[i+0])
 		if token == char {
-			token = yyTok3[i+1]
+			token = int(yyTok3[i+1])
 			goto out
 		}
 	}
 
 out:
 	if token == 0 {
-		token = yyTok2[1] /* unknown char */
+		token = int(yyTok2[1]) /* unknown char */
 	}
 	if yyDebug >= 3 {
 		__yyfmt__.Printf("lex %s(%d)\n", yyTokname(token), uint(char))
@@ -342,7 +492,7 @@ yystack:
 	yyS[yyp].yys = yystate
 
 yynewstate:
-	yyn = yyPact[yystate]
+	yyn = int(yyPact[yystate])
 	if yyn <= yyFlag {
 		goto yydefault /* simple state */
 	}
@@ -353,8 +503,8 @@ yynewstate:
 	if yyn < 0 || yyn >= yyLast {
 		goto yydefault
 	}
-	yyn = yyAct[yyn]
-	if yyChk[yyn] == yytoken { /* valid shift */
+	yyn = int(yyAct[yyn])
+	if int(yyChk[yyn]) == yytoken { /* valid shift */
 		yyrcvr.char = -1
 		yytoken = -1
 		yyVAL = yyrcvr.lval
@@ -367,7 +517,7 @@ yynewstate:
 
 yydefault:
 	/* default state action */
-	yyn = yyDef[yystate]
+	yyn = int(yyDef[yystate])
 	if yyn == -2 {
 		if yyrcvr.char < 0 {
 			yyrcvr.char, yytoken = yylex1(yylex, &yyrcvr.lval)
@@ -376,18 +526,18 @@ yydefault:
 		/* look through exception table */
 		xi := 0
 		for {
-			if yyExca[xi+0] == -1 && yyExca[xi+1] == yystate {
+			if yyExca[xi+0] == -1 && int(yyExca[xi+1]) == yystate {
 				break
 			}
 			xi += 2
 		}
 		for xi += 2; ; xi += 2 {
-			yyn = yyExca[xi+0]
+			yyn = int(yyExca[xi+0])
 			if yyn < 0 || yyn == yytoken {
 				break
 			}
 		}
-		yyn = yyExca[xi+1]
+		yyn = int(yyExca[xi+1])
 		if yyn < 0 {
 			goto ret0
 		}
@@ -409,10 +559,10 @@ yydefault:
 
 			/* find a state where "error" is a legal shift action */
 			for yyp >= 0 {
-				yyn = yyPact[yyS[yyp].yys] + yyErrCode
+				yyn = int(yyPact[yyS[yyp].yys]) + yyErrCode
 				if yyn >= 0 && yyn < yyLast {
-					yystate = yyAct[yyn] /* simulate a shift of "error" */
-					if yyChk[yystate] == yyErrCode {
+					yystate = int(yyAct[yyn]) /* simulate a shift of "error" */
+					if int(yyChk[yystate]) == yyErrCode {
 						goto yystack
 					}
 				}
@@ -448,7 +598,7 @@ yydefault:
 	yypt := yyp
 	_ = yypt // guard against "declared and not used"
 
-	yyp -= yyR2[yyn]
+	yyp -= int(yyR2[yyn])
 	// yyp is now the index of $0. Perform the default action. Iff the
 	// reduced production is ε, $1 is possibly out of range.
 	if yyp+1 >= len(yyS) {
@@ -459,16 +609,16 @@ yydefault:
 	yyVAL = yyS[yyp+1]
 
 	/* consult goto table to find next state */
-	yyn = yyR1[yyn]
-	yyg := yyPgo[yyn]
+	yyn = int(yyR1[yyn])
+	yyg := int(yyPgo[yyn])
 	yyj := yyg + yyS[yyp].yys + 1
 
 	if yyj >= yyLast {
-		yystate = yyAct[yyg]
+		yystate = int(yyAct[yyg])
 	} else {
-		yystate = yyAct[yyj]
-		if yyChk[yystate] != -yyn {
-			yystate = yyAct[yyg]
+		yystate = int(yyAct[yyj])
+		if int(yyChk[yystate]) != -yyn {
+			yystate = int(yyAct[yyg])
 		}
 	}
 	// dummy call; replaced with literal code
@@ -476,106 +626,237 @@ yydefault:
 
 	case 1:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line calc.y:32
+//line calc.y:56
 		{
-			yylex.(*calcLexer).result = yyDollar[1].val
+			yylex.(*calcLexer).stmts = append(yylex.(*calcLexer).stmts, yyDollar[1].node)
 		}
 	case 2:
 		yyDollar = yyS[yypt-3 : yypt+1]
-		//line calc.y:33
+//line calc.y:57
 		{
-			yylex.(*calcLexer).result = yyDollar[3].val
+			yylex.(*calcLexer).stmts = append(yylex.(*calcLexer).stmts, yyDollar[3].node)
 		}
 	case 3:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line calc.y:35
+//line calc.y:59
 		{
-			yyVAL.val = yyDollar[1].val
+			yyVAL.node = &NumberLit{Value: yyDollar[1].val, Int: yyDollar[1].num}
 		}
 	case 4:
-		yyDollar = yyS[yypt-2 : yypt+1]
-		//line calc.y:36
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line calc.y:60
 		{
-			yyVAL.val = -yyDollar[2].val
+			yyVAL.node = yyDollar[1].node
 		}
 	case 5:
-		yyDollar = yyS[yypt-3 : yypt+1]
-		//line calc.y:37
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line calc.y:61
 		{
-			yyVAL.val = yyDollar[1].val + yyDollar[3].val
+			yyVAL.node = &UnaryExpr{Op: "-", X: yyDollar[2].node}
 		}
 	case 6:
 		yyDollar = yyS[yypt-3 : yypt+1]
-		//line calc.y:38
+//line calc.y:62
 		{
-			yyVAL.val = yyDollar[1].val - yyDollar[3].val
+			yyVAL.node = &BinaryExpr{Op: "+", X: yyDollar[1].node, Y: yyDollar[3].node, Pos: yyDollar[2].pos}
 		}
 	case 7:
 		yyDollar = yyS[yypt-3 : yypt+1]
-		//line calc.y:39
+//line calc.y:63
 		{
-			yyVAL.val = yyDollar[1].val * yyDollar[3].val
+			yyVAL.node = &BinaryExpr{Op: "-", X: yyDollar[1].node, Y: yyDollar[3].node, Pos: yyDollar[2].pos}
 		}
 	case 8:
 		yyDollar = yyS[yypt-3 : yypt+1]
-		//line calc.y:40
+//line calc.y:64
 		{
-			yyVAL.val = yyDollar[1].val / yyDollar[3].val
+			yyVAL.node = &BinaryExpr{Op: "*", X: yyDollar[1].node, Y: yyDollar[3].node, Pos: yyDollar[2].pos}
 		}
 	case 9:
 		yyDollar = yyS[yypt-3 : yypt+1]
-		//line calc.y:41
+//line calc.y:65
 		{
-			yyVAL.val = yyDollar[2].val
+			yyVAL.node = &BinaryExpr{Op: "/", X: yyDollar[1].node, Y: yyDollar[3].node, Pos: yyDollar[2].pos}
 		}
 	case 10:
-		yyDollar = yyS[yypt-6 : yypt+1]
-		//line calc.y:42
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line calc.y:66
 		{
-			yyVAL.val = log(yyDollar[3].val, yyDollar[5].val)
+			yyVAL.node = &BinaryExpr{Op: "%", X: yyDollar[1].node, Y: yyDollar[3].node, Pos: yyDollar[2].pos}
 		}
 	case 11:
-		yyDollar = yyS[yypt-4 : yypt+1]
-		//line calc.y:43
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line calc.y:67
 		{
-			yyVAL.val = log(10, yyDollar[3].val)
+			yyVAL.node = &BinaryExpr{Op: "±", X: yyDollar[1].node, Y: yyDollar[3].node, Pos: yyDollar[2].pos}
 		}
 	case 12:
-		yyDollar = yyS[yypt-4 : yypt+1]
-		//line calc.y:44
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line calc.y:68
 		{
-			yyVAL.val = log(2, yyDollar[3].val)
+			yyVAL.node = &BinaryExpr{Op: "*", X: yyDollar[1].node, Y: yyDollar[3].node, Pos: yyDollar[2].pos}
 		}
 	case 13:
-		yyDollar = yyS[yypt-4 : yypt+1]
-		//line calc.y:45
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line calc.y:69
 		{
-			yyVAL.val = log(math.E, yyDollar[3].val)
+			yyVAL.node = &BinaryExpr{Op: "<", X: yyDollar[1].node, Y: yyDollar[3].node, Pos: yyDollar[2].pos}
 		}
 	case 14:
-		yyDollar = yyS[yypt-6 : yypt+1]
-		//line calc.y:46
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line calc.y:70
 		{
-			yyVAL.val = pow(yyDollar[3].val, yyDollar[5].val)
+			yyVAL.node = &BinaryExpr{Op: ">", X: yyDollar[1].node, Y: yyDollar[3].node, Pos: yyDollar[2].pos}
 		}
 	case 15:
-		yyDollar = yyS[yypt-4 : yypt+1]
-		//line calc.y:47
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line calc.y:71
 		{
-			yyVAL.val = exp(yyDollar[3].val)
+			yyVAL.node = &BinaryExpr{Op: "<=", X: yyDollar[1].node, Y: yyDollar[3].node, Pos: yyDollar[2].pos}
 		}
 	case 16:
-		yyDollar = yyS[yypt-1 : yypt+1]
-		//line calc.y:48
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line calc.y:72
 		{
-			yyVAL.val = yylex.(*calcLexer).symTab[yyDollar[1].name]
+			yyVAL.node = &BinaryExpr{Op: ">=", X: yyDollar[1].node, Y: yyDollar[3].node, Pos: yyDollar[2].pos}
 		}
 	case 17:
 		yyDollar = yyS[yypt-3 : yypt+1]
-		//line calc.y:49
+//line calc.y:73
+		{
+			yyVAL.node = &BinaryExpr{Op: "==", X: yyDollar[1].node, Y: yyDollar[3].node, Pos: yyDollar[2].pos}
+		}
+	case 18:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line calc.y:74
+		{
+			yyVAL.node = &BinaryExpr{Op: "!=", X: yyDollar[1].node, Y: yyDollar[3].node, Pos: yyDollar[2].pos}
+		}
+	case 19:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line calc.y:75
+		{
+			yyVAL.node = &CallExpr{Func: "pow", Args: []Node{yyDollar[1].node, yyDollar[3].node}, Pos: yyDollar[2].pos}
+		}
+	case 20:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line calc.y:76
+		{
+			yyVAL.node = &CallExpr{Func: "pow", Args: []Node{yyDollar[1].node, &NumberLit{Value: yyDollar[2].val}}, Pos: yyDollar[2].pos}
+		}
+	case 21:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line calc.y:77
+		{
+			yyVAL.node = &CallExpr{Func: "sqrt", Args: []Node{yyDollar[2].node}, Pos: yyDollar[1].pos}
+		}
+	case 22:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line calc.y:78
+		{
+			yyVAL.node = yyDollar[2].node
+		}
+	case 23:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line calc.y:79
+		{
+			yyVAL.node = &CallExpr{Func: "log", Args: []Node{yyDollar[3].node, yyDollar[5].node}, Pos: yyDollar[1].pos}
+		}
+	case 24:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line calc.y:80
+		{
+			yyVAL.node = &CallExpr{Func: "log10", Args: []Node{yyDollar[3].node}, Pos: yyDollar[1].pos}
+		}
+	case 25:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line calc.y:81
+		{
+			yyVAL.node = &CallExpr{Func: "log2", Args: []Node{yyDollar[3].node}, Pos: yyDollar[1].pos}
+		}
+	case 26:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line calc.y:82
+		{
+			yyVAL.node = &CallExpr{Func: "ln", Args: []Node{yyDollar[3].node}, Pos: yyDollar[1].pos}
+		}
+	case 27:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line calc.y:83
+		{
+			yyVAL.node = &CallExpr{Func: "pow", Args: []Node{yyDollar[3].node, yyDollar[5].node}, Pos: yyDollar[1].pos}
+		}
+	case 28:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line calc.y:84
+		{
+			yyVAL.node = &CallExpr{Func: "exp", Args: []Node{yyDollar[3].node}, Pos: yyDollar[1].pos}
+		}
+	case 29:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line calc.y:85
+		{
+			yyVAL.node = &CallExpr{Func: yyDollar[1].name, Pos: yyDollar[1].pos}
+		}
+	case 30:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line calc.y:86
+		{
+			yyVAL.node = &CallExpr{Func: yyDollar[1].name, Args: yyDollar[3].nodes, Pos: yyDollar[1].pos}
+		}
+	case 31:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line calc.y:87
+		{
+			yyVAL.node = &ListExpr{}
+		}
+	case 32:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line calc.y:88
+		{
+			yyVAL.node = &ListExpr{Elems: yyDollar[2].nodes}
+		}
+	case 33:
+		yyDollar = yyS[yypt-7 : yypt+1]
+//line calc.y:89
+		{
+			yyVAL.node = &ComprehensionExpr{Elem: yyDollar[2].node, Var: yyDollar[4].name, Iter: yyDollar[6].node}
+		}
+	case 34:
+		yyDollar = yyS[yypt-9 : yypt+1]
+//line calc.y:90
+		{
+			yyVAL.node = &ComprehensionExpr{Elem: yyDollar[2].node, Var: yyDollar[4].name, Iter: yyDollar[6].node, Cond: yyDollar[8].node}
+		}
+	case 35:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line calc.y:91
+		{
+			yyVAL.node = &LambdaExpr{Params: yyDollar[1].names, Body: yyDollar[2].node}
+		}
+	case 36:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line calc.y:92
+		{
+			yyVAL.node = &Ident{Name: yyDollar[1].name}
+		}
+	case 37:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line calc.y:93
+		{
+			yyVAL.node = &AssignExpr{Name: yyDollar[1].name, Value: yyDollar[3].node}
+		}
+	case 38:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line calc.y:96
+		{
+			yyVAL.nodes = []Node{yyDollar[1].node}
+		}
+	case 39:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line calc.y:97
 		{
-			yylex.(*calcLexer).symTab[yyDollar[1].name] = yyDollar[3].val
-			yyVAL.val = yyDollar[3].val
+			yyVAL.nodes = append(yyDollar[1].nodes, yyDollar[3].node)
 		}
 	}
 	goto yystack /* stack new state and value */
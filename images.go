@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// imageCache keeps the last rendered images in memory, so that Telegram can
+// fetch them from the bot's own HTTP server.
+type imageCache struct {
+	mu     sync.Mutex
+	size   int
+	images map[string]image.Image
+	order  []string // ids from oldest to newest
+}
+
+func newImageCache(size int) *imageCache {
+	return &imageCache{
+		size:   size,
+		images: make(map[string]image.Image),
+	}
+}
+
+// put stores the image rendered for key and returns the id to fetch it by.
+// If the cache is full, the oldest image is dropped.
+func (c *imageCache) put(key string, img image.Image) string {
+	sum := sha1.Sum([]byte(key))
+	id := hex.EncodeToString(sum[:])
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.images[id]; !ok {
+		c.order = append(c.order, id)
+	}
+	c.images[id] = img
+
+	for len(c.order) > c.size {
+		delete(c.images, c.order[0])
+		c.order = c.order[1:]
+	}
+
+	return id
+}
+
+func (c *imageCache) get(id string) (image.Image, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	img, ok := c.images[id]
+	return img, ok
+}
+
+// ServeHTTP serves /images/<id>.png and /images/<id>.jpg. The same image is
+// available in both formats because inline photo results must be JPEGs.
+func (c *imageCache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := path.Base(r.URL.Path)
+	ext := path.Ext(name)
+
+	img, ok := c.get(strings.TrimSuffix(name, ext))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	var err error
+	switch ext {
+	case ".png":
+		w.Header().Set("Content-Type", "image/png")
+		err = png.Encode(w, img)
+	case ".jpg":
+		w.Header().Set("Content-Type", "image/jpeg")
+		err = jpeg.Encode(w, img, &jpeg.Options{Quality: 95})
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if err != nil {
+		log.Printf("Error encoding image %s: %s", name, err.Error())
+	}
+}
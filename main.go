@@ -1,49 +1,149 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"image"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/luism6n/calcbot/calc"
+	"github.com/luism6n/calcbot/render"
 
 	"gopkg.in/telegram-bot-api.v4"
 )
 
+const (
+	// Only queries up to this length get a "Show steps" result, longer ones
+	// make for walls of text.
+	maxStepsQueryLength = 40
+	// baseURL is where Telegram reaches the bot's HTTP server.
+	baseURL = "https://luis-calc-bot.herokuapp.com/"
+	// evaluationTimeout is how long a query may take to evaluate.
+	evaluationTimeout = 2 * time.Second
+)
+
 var (
 	token *string
 	debug *bool
 	port  *string
+
+	images = newImageCache(1000)
+	chats  = newSettingsStore()
 )
 
 func main() {
 	bot, updates := setupBot()
 
 	for update := range updates {
-		if update.InlineQuery == nil {
-			continue
+		switch {
+		case update.InlineQuery != nil:
+			answerInlineQuery(bot, update.InlineQuery)
+		case update.Message != nil && update.Message.IsCommand():
+			answerCommand(bot, update.Message)
+		case update.Message != nil && update.Message.Chat.IsPrivate():
+			answerMessage(bot, update.Message)
 		}
+	}
+}
 
-		query := update.InlineQuery.Query
-		evaluation, err := calc.Evaluate(query)
+func answerInlineQuery(bot *tgbotapi.BotAPI, inlineQuery *tgbotapi.InlineQuery) {
+	query := inlineQuery.Query
+	st := chats.get(int64(inlineQuery.From.ID))
+	evaluator := newEvaluator(st)
+	ctx, cancel := context.WithTimeout(context.Background(), evaluationTimeout)
+	evaluation, err := evaluator.EvaluateContext(ctx, query)
+	cancel()
 
-		var result tgbotapi.InlineQueryResultArticle
-		if err != nil {
-			result = newInlineQueryResultArticle(fmt.Sprintf("%s", err.Error()))
-		} else {
-			result = newInlineQueryResultArticle(fmt.Sprintf("%s ~> %f", query, evaluation))
+	var results []interface{}
+	if err != nil {
+		results = append(results, newInlineQueryResultArticle("evaluation", "Evaluation result", describeError(err)))
+	} else if plot, ok := evaluation.(*calc.Plot); ok {
+		results = append(results, newInlineQueryResultPlot(plot))
+	} else {
+		stmts, _ := evaluator.Parse(query)
+		text := fmt.Sprintf("%s\n~> %s", format(stmts), formatValue(evaluation, st))
+		if rolls := showRolls(evaluator); rolls != "" {
+			text += "\n" + rolls
 		}
+		results = append(results, newInlineQueryResultArticle("evaluation", "Evaluation result", text))
+		results = append(results, newInlineQueryResultFormula(query, stmts, formatValue(evaluation, st)))
+	}
+
+	if stmts, err := evaluator.Parse(query); err == nil {
+		if simplified, ok := simplify(stmts); ok {
+			results = append(results, newInlineQueryResultArticle("simplified", "Simplified expression", simplified))
+		}
+
+		results = append(results, newInlineQueryResultArticle("latex", "LaTeX", toLaTeX(stmts)))
+	}
 
-		config := newInlineConfig(update.InlineQuery.ID, result)
+	if steps, ok := showSteps(query, evaluator.Seed(), st); ok {
+		results = append(results, newInlineQueryResultArticle("steps", "Show steps", steps))
+	}
+
+	config := newInlineConfig(inlineQuery.ID, results)
+	if evaluator.Random() || evaluator.UsedHistory() {
+		// Everyone asking for a roll should get a fresh one, and previous
+		// results change from one message to the next.
+		config.CacheTime = 0
+	}
 
-		res, err := bot.AnswerInlineQuery(config)
-		if err != nil {
-			log.Printf("Error:\nerr: %s\nres: %+v\nquery: %s", err.Error(), res, query)
+	res, err := bot.AnswerInlineQuery(config)
+	if err != nil {
+		log.Printf("Error:\nerr: %s\nres: %+v\nquery: %s", err.Error(), res, query)
+	}
+}
+
+// answerMessage replies to a program sent in a private chat, which may be a
+// worksheet of several lines, with the result of each statement. Its results are remembered
+// for the next messages and inline queries of the user to refer to as ans,
+// _1, _2 and so on.
+func answerMessage(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
+	st := chats.get(message.Chat.ID)
+	evaluator := newEvaluator(st)
+	ctx, cancel := context.WithTimeout(context.Background(), evaluationTimeout)
+	worksheet, err := evaluator.WorksheetContext(ctx, message.Text)
+	cancel()
+
+	var text string
+	if err != nil {
+		text = describeError(err)
+	} else {
+		chats.remember(message.Chat.ID, evaluator.History())
+		text = showWorksheet(worksheet, st)
+		if rolls := showRolls(evaluator); rolls != "" {
+			text += "\n" + rolls
 		}
 	}
+
+	if _, err := bot.Send(tgbotapi.NewMessage(message.Chat.ID, text)); err != nil {
+		log.Printf("Error answering %s: %s", message.Text, err.Error())
+	}
+}
+
+// answerCommand replies to the commands that change the settings of a chat.
+func answerCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
+	var text string
+	switch message.Command() {
+	case "locale":
+		text = setLocale(message.Chat.ID, message.CommandArguments())
+	case "format":
+		text = setFormat(message.Chat.ID, message.CommandArguments())
+	case "plusminus":
+		text = setPlusMinus(message.Chat.ID, message.CommandArguments())
+	default:
+		text = "Commands: /locale, /format, /plusminus"
+	}
+
+	if _, err := bot.Send(tgbotapi.NewMessage(message.Chat.ID, text)); err != nil {
+		log.Printf("Error answering %s: %s", message.Text, err.Error())
+	}
 }
 
 func setupBot() (*tgbotapi.BotAPI, tgbotapi.UpdatesChannel) {
@@ -56,7 +156,7 @@ func setupBot() (*tgbotapi.BotAPI, tgbotapi.UpdatesChannel) {
 
 	bot.Debug = *debug
 
-	u, err := url.Parse("https://luis-calc-bot.herokuapp.com/" + *token)
+	u, err := url.Parse(baseURL + *token)
 	if err != nil {
 		die("Parsing webhook URL failed: %s", err.Error())
 	}
@@ -69,6 +169,7 @@ func setupBot() (*tgbotapi.BotAPI, tgbotapi.UpdatesChannel) {
 	}
 
 	updates := bot.ListenForWebhook("/" + *token)
+	http.Handle("/images/", images)
 	log.Print("Listening at 0.0.0.0:" + *port)
 	go http.ListenAndServe("0.0.0.0:"+*port, nil)
 
@@ -84,11 +185,141 @@ func readCommandLineFlags() {
 	log.Printf("Read arguments.\ndebug: %t\ntoken: %s\nport: %s", *debug, *token, *port)
 }
 
-func newInlineQueryResultArticle(text string) tgbotapi.InlineQueryResultArticle {
+// newEvaluator returns an evaluator following the settings of a chat and
+// knowing its previous results, configured further by opts.
+func newEvaluator(st settings, opts ...calc.Option) *calc.Evaluator {
+	opts = append([]calc.Option{calc.WithLocale(st.locale), calc.WithPlusMinus(st.plusMinus), calc.WithHistory(st.results)}, opts...)
+	return calc.NewEvaluator(opts...)
+}
+
+// describeError explains why a query failed to evaluate, in friendlier
+// words than the evaluator's when it ran out of resources.
+func describeError(err error) string {
+	switch err := err.(type) {
+	case *calc.InputTooLongError:
+		return fmt.Sprintf("That's too long for me, please keep it under %d characters", err.Max)
+	case *calc.TooDeepError:
+		return "That's nested too deeply for me, try with fewer parenthesis"
+	case *calc.TooManyOperationsError:
+		return "That takes too many steps to compute"
+	case *calc.NumberTooLargeError:
+		return "The numbers got too large to compute"
+	case *calc.OutputTooLongError:
+		return "The result is too long to show"
+	}
+	if err == context.DeadlineExceeded {
+		return "That took too long to compute"
+	}
+
+	return err.Error()
+}
+
+// formatValue prints numbers in the format and locale of st and anything
+// else, like symbolic results, as is. Integers are always printed exactly.
+func formatValue(v calc.Value, st settings) string {
+	switch v := v.(type) {
+	case calc.Number:
+		nf := st.format
+		nf.Locale = st.locale
+		return calc.FormatNumber(float64(v), nf)
+	case calc.Integer:
+		nf := st.format
+		nf.Locale = st.locale
+		return calc.FormatInteger(v.Int, nf)
+	case calc.Uncertain:
+		return v.Localize(st.locale)
+	}
+
+	return v.String()
+}
+
+// format prints stmts in canonical form.
+func format(stmts []calc.Node) string {
+	formatted := make([]string, len(stmts))
+	for i, stmt := range stmts {
+		formatted[i] = stmt.String()
+	}
+
+	return strings.Join(formatted, "; ")
+}
+
+// showWorksheet lists the statements of worksheet as written, each with its
+// value, as in a = 4 → 4.
+func showWorksheet(worksheet *calc.Worksheet, st settings) string {
+	lines := make([]string, len(worksheet.Statements))
+	for i, stmt := range worksheet.Statements {
+		lines[i] = fmt.Sprintf("%s → %s", stmt.Source, formatValue(stmt.Value, st))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// simplify returns the simplified stmts, or false if they are already as
+// simple as it gets.
+func simplify(stmts []calc.Node) (string, bool) {
+	simplified := make([]string, len(stmts))
+	for i, stmt := range stmts {
+		simplified[i] = calc.Simplify(stmt).String()
+	}
+
+	text := strings.Join(simplified, "; ")
+	return text, text != format(stmts)
+}
+
+// toLaTeX returns the LaTeX snippet of stmts.
+func toLaTeX(stmts []calc.Node) string {
+	latex := make([]string, len(stmts))
+	for i, stmt := range stmts {
+		latex[i] = calc.ToLaTeX(stmt)
+	}
+
+	return strings.Join(latex, `;\quad `)
+}
+
+// showRolls lists the dice rolled by evaluator and the seed that repeats
+// them and any other random numbers drawn, or returns "" if none were.
+func showRolls(evaluator *calc.Evaluator) string {
+	if !evaluator.Random() {
+		return ""
+	}
+
+	var lines []string
+	for _, roll := range evaluator.Rolls() {
+		lines = append(lines, roll.String())
+	}
+	lines = append(lines, fmt.Sprintf("seed: %d", evaluator.Seed()))
+
+	return strings.Join(lines, "\n")
+}
+
+// showSteps returns the step by step evaluation of query, with random
+// numbers drawn from seed, or false if query is too long, fails to evaluate
+// or takes a single step.
+func showSteps(query string, seed int64, st settings) (string, bool) {
+	if len(query) > maxStepsQueryLength {
+		return "", false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), evaluationTimeout)
+	defer cancel()
+	trace, err := newEvaluator(st, calc.WithSeed(seed)).TraceContext(ctx, query)
+	if err != nil {
+		return "", false
+	}
+
+	steps := 0
+	for _, st := range trace.Statements {
+		steps += len(st.Steps)
+	}
+
+	return trace.String(), steps > 1
+}
+
+func newInlineQueryResultArticle(id, title, text string) tgbotapi.InlineQueryResultArticle {
 	return tgbotapi.InlineQueryResultArticle{
 		Type:        "article",
-		ID:          "only result",
-		Title:       "Evaluation result",
+		ID:          id,
+		Title:       title,
 		Description: text,
 		InputMessageContent: tgbotapi.InputTextMessageContent{
 			Text: text,
@@ -96,21 +327,57 @@ func newInlineQueryResultArticle(text string) tgbotapi.InlineQueryResultArticle
 	}
 }
 
-func newInlineConfig(queryID string, onlyResult tgbotapi.InlineQueryResultArticle) tgbotapi.InlineConfig {
-	return tgbotapi.InlineConfig{
-		InlineQueryID: queryID,
-		Results:       castToInterfaceSlice([]tgbotapi.InlineQueryResultArticle{onlyResult}),
-		CacheTime:     300,
-	}
+// newInlineQueryResultFormula renders the typeset statements of query and
+// its result and offers the image as a photo.
+func newInlineQueryResultFormula(query string, stmts []calc.Node, result string) tgbotapi.InlineQueryResultPhoto {
+	img := render.Formula(stmts, result)
+
+	return newInlineQueryResultPhoto("formula", "Typeset formula", images.put("formula:"+query+"="+result, img), img)
+}
+
+// newInlineQueryResultPlot draws the chart of plot and offers it as a photo.
+func newInlineQueryResultPlot(plot *calc.Plot) tgbotapi.InlineQueryResultPhoto {
+	img := render.Chart(plot)
+
+	return newInlineQueryResultPhoto("plot", plot.String(), images.put(plotKey(plot), img), img)
 }
 
-func castToInterfaceSlice(iqra []tgbotapi.InlineQueryResultArticle) []interface{} {
-	s := make([]interface{}, len(iqra))
-	for i, v := range iqra {
-		s[i] = v
+// plotKey identifies the chart of plot by the points it shows. The query
+// alone doesn't tell, as it may refer to the variables and previous results
+// of whoever sent it.
+func plotKey(plot *calc.Plot) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "plot:%s", plot)
+	for _, s := range plot.Series {
+		for _, p := range s.Points {
+			fmt.Fprintf(&b, " %g,%g", p.X, p.Y)
+		}
 	}
 
-	return s
+	return b.String()
+}
+
+// newInlineQueryResultPhoto offers img, stored in the image cache under
+// imageID, as a photo.
+func newInlineQueryResultPhoto(id, title, imageID string, img image.Image) tgbotapi.InlineQueryResultPhoto {
+	photoURL := baseURL + "images/" + imageID + ".jpg"
+
+	photo := tgbotapi.NewInlineQueryResultPhotoWithThumb(id, photoURL, photoURL)
+	photo.Width = img.Bounds().Dx()
+	photo.Height = img.Bounds().Dy()
+	photo.Title = title
+
+	return photo
+}
+
+func newInlineConfig(queryID string, results []interface{}) tgbotapi.InlineConfig {
+	return tgbotapi.InlineConfig{
+		InlineQueryID: queryID,
+		Results:       results,
+		CacheTime:     300,
+		// Results depend on the settings of the user asking.
+		IsPersonal: true,
+	}
 }
 
 func die(format string, a ...interface{}) {
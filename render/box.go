@@ -0,0 +1,268 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// A box is a rectangle of typeset material, TeX style: it extends ascent
+// pixels above its baseline and descent pixels below it. Boxes are combined
+// into bigger boxes and finally drawn at a position.
+type box struct {
+	width, ascent, descent int
+	draw                   func(img draw.Image, x, baseline int)
+}
+
+func (b box) height() int {
+	return b.ascent + b.descent
+}
+
+// ink is the color formulas and charts are drawn with.
+var ink = color.Black
+
+// text typesets s with the embedded font magnified scale times.
+func text(s string, scale int) box {
+	return box{
+		width:   textWidth(s, scale),
+		ascent:  glyphAscent * scale,
+		descent: glyphDescent * scale,
+		draw: func(img draw.Image, x, baseline int) {
+			drawText(img, x, baseline, s, scale, ink)
+		},
+	}
+}
+
+func textWidth(s string, scale int) int {
+	n := len([]rune(s))
+	if n == 0 {
+		return 0
+	}
+
+	return (n*glyphAdvance - 1) * scale
+}
+
+func drawText(img draw.Image, x, baseline int, s string, scale int, c color.Color) {
+	top := baseline - glyphAscent*scale
+	for _, r := range s {
+		g := glyph(r)
+		for row := 0; row < glyphHeight; row++ {
+			for col := 0; col < glyphWidth; col++ {
+				if g[row][col] {
+					fillRect(img, x+col*scale, top+row*scale, x+(col+1)*scale, top+(row+1)*scale, c)
+				}
+			}
+		}
+		x += glyphAdvance * scale
+	}
+}
+
+// hbox lines boxes up side by side on a common baseline.
+func hbox(boxes ...box) box {
+	var b box
+	for _, c := range boxes {
+		b.width += c.width
+		b.ascent = max(b.ascent, c.ascent)
+		b.descent = max(b.descent, c.descent)
+	}
+
+	b.draw = func(img draw.Image, x, baseline int) {
+		for _, c := range boxes {
+			c.draw(img, x, baseline)
+			x += c.width
+		}
+	}
+
+	return b
+}
+
+// vbox stacks boxes, left aligned and separated by gap pixels. The baseline
+// of the result is the one of the last box.
+func vbox(gap int, boxes ...box) box {
+	var b box
+	for i, c := range boxes {
+		b.width = max(b.width, c.width)
+		if i > 0 {
+			b.ascent += b.descent + gap
+		}
+		b.ascent += c.ascent
+		b.descent = c.descent
+	}
+
+	b.draw = func(img draw.Image, x, baseline int) {
+		y := baseline - b.ascent
+		for _, c := range boxes {
+			c.draw(img, x, y+c.ascent)
+			y += c.height() + gap
+		}
+	}
+
+	return b
+}
+
+func hspace(width int) box {
+	return box{width: width, draw: func(draw.Image, int, int) {}}
+}
+
+// fraction puts num over den, separated by a bar on the math axis.
+func fraction(num, den box, scale int) box {
+	gap, thickness, axis := 2*scale, scale, 3*scale
+	width := max(num.width, den.width) + 2*scale
+
+	return box{
+		width:   width,
+		ascent:  axis + gap + num.height(),
+		descent: thickness + gap + den.height() - axis,
+		draw: func(img draw.Image, x, baseline int) {
+			bar := baseline - axis
+			num.draw(img, x+(width-num.width)/2, bar-gap-num.descent)
+			den.draw(img, x+(width-den.width)/2, bar+thickness+gap+den.ascent)
+			fillRect(img, x, bar, x+width, bar+thickness, ink)
+		},
+	}
+}
+
+// superscript raises exp, typeset at a smaller scale, to the top right of
+// base.
+func superscript(base, exp box) box {
+	shift := max(base.ascent/2+exp.descent, base.ascent-exp.ascent/2)
+
+	return box{
+		width:   base.width + exp.width,
+		ascent:  max(base.ascent, shift+exp.ascent),
+		descent: max(base.descent, exp.descent-shift),
+		draw: func(img draw.Image, x, baseline int) {
+			base.draw(img, x, baseline)
+			exp.draw(img, x+base.width, baseline-shift)
+		},
+	}
+}
+
+// subscript lowers sub, typeset at a smaller scale, to the bottom right of
+// base.
+func subscript(base, sub box) box {
+	shift := sub.ascent / 2
+
+	return box{
+		width:   base.width + sub.width,
+		ascent:  max(base.ascent, sub.ascent-shift),
+		descent: max(base.descent, shift+sub.descent),
+		draw: func(img draw.Image, x, baseline int) {
+			base.draw(img, x, baseline)
+			sub.draw(img, x+base.width, baseline+shift)
+		},
+	}
+}
+
+// parens surrounds inner with parenthesis as tall as it is.
+func parens(inner box, scale int) box {
+	width := 3 * scale
+	pad := scale
+
+	return box{
+		width:   inner.width + 2*width + 2*pad,
+		ascent:  inner.ascent + pad,
+		descent: inner.descent + pad,
+		draw: func(img draw.Image, x, baseline int) {
+			top, bottom := baseline-inner.ascent-pad, baseline+inner.descent+pad
+			curve := min(width, (bottom-top)/4)
+
+			l := x + scale/2
+			drawLine(img, l+width-scale, top, l, top+curve, scale, ink)
+			drawLine(img, l, top+curve, l, bottom-curve, scale, ink)
+			drawLine(img, l, bottom-curve, l+width-scale, bottom, scale, ink)
+
+			r := x + width + 2*pad + inner.width + width - scale - scale/2
+			drawLine(img, r-width+scale, top, r, top+curve, scale, ink)
+			drawLine(img, r, top+curve, r, bottom-curve, scale, ink)
+			drawLine(img, r, bottom-curve, r-width+scale, bottom, scale, ink)
+
+			inner.draw(img, x+width+pad, baseline)
+		},
+	}
+}
+
+// radical draws a square root sign over inner.
+func radical(inner box, scale int) box {
+	check := 6 * scale
+	gap := 2 * scale
+
+	return box{
+		width:   check + inner.width + scale,
+		ascent:  inner.ascent + gap + scale,
+		descent: inner.descent,
+		draw: func(img draw.Image, x, baseline int) {
+			top := baseline - inner.ascent - gap
+			bottom := baseline + inner.descent
+			drawLine(img, x, baseline-inner.ascent/3, x+2*scale, bottom-scale, scale, ink)
+			drawLine(img, x+2*scale, bottom-scale, x+check-scale, top, scale, ink)
+			fillRect(img, x+check-scale, top, x+check+inner.width+scale, top+scale, ink)
+			inner.draw(img, x+check, baseline)
+		},
+	}
+}
+
+// fillRect paints the rectangle [x0, x1) × [y0, y1).
+func fillRect(img draw.Image, x0, y0, x1, y1 int, c color.Color) {
+	draw.Draw(img, image.Rect(x0, y0, x1, y1), image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// drawLine draws a line from (x0, y0) to (x1, y1) with a square pen of the
+// given width, using Bresenham's algorithm.
+func drawLine(img draw.Image, x0, y0, x1, y1, width int, c color.Color) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := sign(x1-x0), sign(y1-y0)
+	err := dx + dy
+
+	for {
+		fillRect(img, x0, y0, x0+width, y0+width, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+
+		e := 2 * err
+		if e >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
+
+func sign(x int) int {
+	switch {
+	case x < 0:
+		return -1
+	case x > 0:
+		return 1
+	}
+
+	return 0
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,211 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/luism6n/calcbot/calc"
+)
+
+const (
+	chartWidth  = 800
+	chartHeight = 500
+	// labelScale is how many times the font is magnified in tick labels and
+	// legends.
+	labelScale = 2
+	// maxTicks bounds how many ticks each axis gets.
+	maxTicks = 8
+)
+
+var (
+	gridColor = color.RGBA{0xdd, 0xdd, 0xdd, 0xff}
+	axisColor = color.RGBA{0x66, 0x66, 0x66, 0xff}
+	palette   = []color.Color{
+		color.RGBA{0x1f, 0x77, 0xb4, 0xff},
+		color.RGBA{0xd6, 0x27, 0x28, 0xff},
+		color.RGBA{0x2c, 0xa0, 0x2c, 0xff},
+		color.RGBA{0xff, 0x7f, 0x0e, 0xff},
+		color.RGBA{0x94, 0x67, 0xbd, 0xff},
+	}
+)
+
+// Chart draws the series of p as lines over a grid, with labeled ticks on
+// both axes and a legend.
+func Chart(p *calc.Plot) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, chartWidth, chartHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	ymin, ymax := yRange(p)
+	yticks := ticks(ymin, ymax)
+
+	// The plot area takes what the widest label on the y axis leaves.
+	labelWidth := 0
+	for _, y := range yticks {
+		labelWidth = max(labelWidth, textWidth(tickLabel(y), labelScale))
+	}
+	labelHeight := glyphHeight * labelScale
+	area := image.Rect(margin+labelWidth+margin/2, margin, chartWidth-margin, chartHeight-margin-labelHeight-margin/2)
+	c := chartCoordinates{area, p.From, p.To, ymin, ymax}
+
+	for _, x := range ticks(p.From, p.To) {
+		px := c.x(x)
+		fillRect(img, px, area.Min.Y, px+1, area.Max.Y, gridColor)
+		label := tickLabel(x)
+		drawText(img, px-textWidth(label, labelScale)/2, area.Max.Y+margin/2+glyphAscent*labelScale, label, labelScale, ink)
+	}
+	for _, y := range yticks {
+		py := c.y(y)
+		fillRect(img, area.Min.X, py, area.Max.X, py+1, gridColor)
+		label := tickLabel(y)
+		drawText(img, area.Min.X-margin/2-textWidth(label, labelScale), py+glyphAscent*labelScale/2, label, labelScale, ink)
+	}
+
+	if p.From <= 0 && 0 <= p.To {
+		px := c.x(0)
+		fillRect(img, px, area.Min.Y, px+1, area.Max.Y, axisColor)
+	}
+	if ymin <= 0 && 0 <= ymax {
+		py := c.y(0)
+		fillRect(img, area.Min.X, py, area.Max.X, py+1, axisColor)
+	}
+
+	// Drawing on a sub image clips the lines to the plot area.
+	clip := img.SubImage(area).(*image.RGBA)
+	for i, s := range p.Series {
+		drawSeries(clip, c, s, palette[i%len(palette)])
+	}
+
+	drawFrame(img, area)
+	drawLegend(img, area, p.Series)
+
+	return img
+}
+
+// chartCoordinates maps data coordinates to pixels in area.
+type chartCoordinates struct {
+	area       image.Rectangle
+	xmin, xmax float64
+	ymin, ymax float64
+}
+
+func (c chartCoordinates) x(x float64) int {
+	return c.area.Min.X + int(math.Round((x-c.xmin)/(c.xmax-c.xmin)*float64(c.area.Dx()-1)))
+}
+
+func (c chartCoordinates) y(y float64) int {
+	return c.area.Max.Y - 1 - int(math.Round((y-c.ymin)/(c.ymax-c.ymin)*float64(c.area.Dy()-1)))
+}
+
+// drawSeries draws s as a polyline, broken wherever the function is
+// undefined or runs off the chart, like tan(x) does at its poles.
+func drawSeries(img draw.Image, c chartCoordinates, s calc.Series, col color.Color) {
+	span := c.ymax - c.ymin
+	visible := func(y float64) bool {
+		return !math.IsNaN(y) && c.ymin-span <= y && y <= c.ymax+span
+	}
+
+	for i := 1; i < len(s.Points); i++ {
+		a, b := s.Points[i-1], s.Points[i]
+		if !visible(a.Y) || !visible(b.Y) {
+			continue
+		}
+		drawLine(img, c.x(a.X), c.y(a.Y), c.x(b.X), c.y(b.Y), 2, col)
+	}
+}
+
+func drawFrame(img draw.Image, area image.Rectangle) {
+	fillRect(img, area.Min.X, area.Min.Y, area.Max.X, area.Min.Y+1, axisColor)
+	fillRect(img, area.Min.X, area.Max.Y-1, area.Max.X, area.Max.Y, axisColor)
+	fillRect(img, area.Min.X, area.Min.Y, area.Min.X+1, area.Max.Y, axisColor)
+	fillRect(img, area.Max.X-1, area.Min.Y, area.Max.X, area.Max.Y, axisColor)
+}
+
+// drawLegend lists the series in the top left corner of area.
+func drawLegend(img draw.Image, area image.Rectangle, series []calc.Series) {
+	line := glyphHeight*labelScale + labelScale
+	swatch := 3 * glyphAdvance * labelScale
+
+	width := 0
+	for _, s := range series {
+		width = max(width, textWidth(s.Label, labelScale))
+	}
+
+	x, y := area.Min.X+margin/2, area.Min.Y+margin/2
+	fillRect(img, x, y, x+swatch+width+3*labelScale*2, y+len(series)*line+labelScale*2, color.White)
+	for i, s := range series {
+		baseline := y + labelScale + i*line + glyphAscent*labelScale
+		col := palette[i%len(palette)]
+		fillRect(img, x+labelScale*2, baseline-glyphAscent*labelScale/2, x+swatch, baseline-glyphAscent*labelScale/2+2, col)
+		drawText(img, x+swatch+labelScale*2, baseline, s.Label, labelScale, ink)
+	}
+}
+
+// yRange returns the range of values to show. Values far off the bulk of
+// the samples, like those near a pole, are left out so that they don't
+// flatten the rest of the chart.
+func yRange(p *calc.Plot) (float64, float64) {
+	var ys []float64
+	for _, s := range p.Series {
+		for _, pt := range s.Points {
+			if !math.IsNaN(pt.Y) && !math.IsInf(pt.Y, 0) {
+				ys = append(ys, pt.Y)
+			}
+		}
+	}
+	if len(ys) == 0 {
+		return -1, 1
+	}
+	sort.Float64s(ys)
+
+	lo, hi := ys[0], ys[len(ys)-1]
+	if plo, phi := ys[len(ys)*2/100], ys[len(ys)*98/100]; hi-lo > 10*(phi-plo) {
+		lo, hi = plo, phi
+	}
+
+	if lo == hi {
+		return lo - 1, hi + 1
+	}
+
+	pad := (hi - lo) * 0.05
+	return lo - pad, hi + pad
+}
+
+// ticks returns round values between lo and hi, 1, 2 or 5 times a power of
+// ten apart. Ranges too narrow to tell their ticks apart, as from 1e16 to
+// 1e16 + 2, get none.
+func ticks(lo, hi float64) []float64 {
+	raw := (hi - lo) / maxTicks
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+
+	step := 10 * mag
+	for _, m := range []float64{1, 2, 5} {
+		if m*mag >= raw {
+			step = m * mag
+			break
+		}
+	}
+
+	if lo+step == lo || math.IsNaN(step) || math.IsInf(step, 0) {
+		return nil
+	}
+
+	var ts []float64
+	first := math.Ceil(lo/step) * step
+	for k := 0; k <= maxTicks; k++ {
+		t := first + float64(k)*step
+		if t > hi {
+			break
+		}
+		ts = append(ts, t)
+	}
+
+	return ts
+}
+
+func tickLabel(v float64) string {
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
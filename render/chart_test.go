@@ -0,0 +1,118 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/luism6n/calcbot/calc"
+)
+
+func TestChart(t *testing.T) {
+	t.Run("Should pick round ticks", func(t *testing.T) {
+		testCases := []struct {
+			Lo, Hi float64
+			Ticks  []float64
+		}{
+			{0, 10, []float64{0, 2, 4, 6, 8, 10}},
+			{-10, 10, []float64{-10, -5, 0, 5, 10}},
+			{0.1, 0.95, []float64{0.2, 0.4, 0.6, 0.8}},
+			{-1.2, 1.2, []float64{-1, -0.5, 0, 0.5, 1}},
+		}
+
+		for _, c := range testCases {
+			ticks := ticks(c.Lo, c.Hi)
+			if len(ticks) != len(c.Ticks) {
+				t.Fatalf("%v != %v in test case %+v", ticks, c.Ticks, c)
+			}
+			for i := range ticks {
+				if !floatEquals(ticks[i], c.Ticks[i]) {
+					t.Fatalf("%v != %v in test case %+v", ticks, c.Ticks, c)
+				}
+			}
+		}
+	})
+
+	t.Run("Should give no ticks to ranges too narrow to tell them apart", func(t *testing.T) {
+		testCases := []struct {
+			Lo, Hi float64
+		}{
+			{1e16, 1e16 + 2},
+			{1e300, 1e300},
+			{5, 5},
+		}
+
+		for _, c := range testCases {
+			if ticks := ticks(c.Lo, c.Hi); len(ticks) != 0 {
+				t.Fatalf("Expected no ticks, got %v in test case %+v", ticks, c)
+			}
+		}
+
+		v, err := calc.NewEvaluator().Evaluate("plot(x, x, 1e16, 1e16 + 2)")
+		if err != nil {
+			t.Fatalf("error (%s) not nil", err)
+		}
+		if img := Chart(v.(*calc.Plot)); img.Bounds().Dx() != chartWidth {
+			t.Fatalf("chart wasn't drawn")
+		}
+	})
+
+	t.Run("Should draw plots", func(t *testing.T) {
+		testCases := []string{
+			"plot(sin(x) / x, x, -10, 10)",
+			"plot(sin(x), cos(x), x, 0, 6.3)",
+			"plot(tan(x), x, -3, 3)",
+			"plot(sqrt(x), x, -1, 1)",
+			"plot(1, x, 0, 1)",
+		}
+
+		for _, c := range testCases {
+			v, err := calc.NewEvaluator().Evaluate(c)
+			if err != nil {
+				t.Fatalf("error (%s) not nil for %q", err, c)
+			}
+
+			img := Chart(v.(*calc.Plot))
+			if img.Bounds().Dx() != chartWidth || img.Bounds().Dy() != chartHeight || !inked(img) {
+				t.Fatalf("chart of %q wasn't drawn", c)
+			}
+		}
+	})
+
+	t.Run("Should fit long tick labels in the margin", func(t *testing.T) {
+		testCases := []string{
+			"plot(-12345.6 * x, x, 0, 1)",
+			"plot(1e6 * x, x, -1, 1)",
+			"plot(x / 1e5, x, -1, 1)",
+		}
+
+		for _, c := range testCases {
+			v, err := calc.NewEvaluator().Evaluate(c)
+			if err != nil {
+				t.Fatalf("error (%s) not nil for %q", err, c)
+			}
+
+			img := Chart(v.(*calc.Plot))
+			for y := 0; y < chartHeight; y++ {
+				for x := 0; x < margin; x++ {
+					if r, _, _, _ := img.At(x, y).RGBA(); r == 0 {
+						t.Fatalf("label of %q drawn into the margin at (%d, %d)", c, x, y)
+					}
+				}
+			}
+		}
+	})
+
+	t.Run("Should leave poles out of the range", func(t *testing.T) {
+		v, err := calc.NewEvaluator().Evaluate("plot(tan(x), x, -3, 3)")
+		if err != nil {
+			t.Fatalf("error (%s) not nil", err)
+		}
+
+		if lo, hi := yRange(v.(*calc.Plot)); lo < -100 || hi > 100 {
+			t.Fatalf("range [%f, %f] is too wide", lo, hi)
+		}
+	})
+}
+
+func floatEquals(a, b float64) bool {
+	return a-b < 1e-9 && b-a < 1e-9
+}
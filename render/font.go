@@ -0,0 +1,155 @@
+package render
+
+import "strings"
+
+// The embedded font is a 5x9 pixel bitmap. Glyphs sit on a baseline below
+// their 7th row; the last two rows are for descenders.
+const (
+	glyphWidth   = 5
+	glyphHeight  = 9
+	glyphAscent  = 7
+	glyphDescent = glyphHeight - glyphAscent
+	glyphAdvance = glyphWidth + 1
+)
+
+// glyphs holds the rows of each glyph, top to bottom, '#' being a set pixel.
+// Missing trailing rows are blank.
+var glyphs = map[rune]string{
+	'0': ".###. #...# #..## #.#.# ##..# #...# .###.",
+	'1': "..#.. .##.. ..#.. ..#.. ..#.. ..#.. .###.",
+	'2': ".###. #...# ....# ...#. ..#.. .#... #####",
+	'3': "##### ...#. ..#.. ...#. ....# #...# .###.",
+	'4': "...#. ..##. .#.#. #..#. ##### ...#. ...#.",
+	'5': "##### #.... ####. ....# ....# #...# .###.",
+	'6': "..##. .#... #.... ####. #...# #...# .###.",
+	'7': "##### ....# ...#. ..#.. .#... .#... .#...",
+	'8': ".###. #...# #...# .###. #...# #...# .###.",
+	'9': ".###. #...# #...# .#### ....# ...#. .##..",
+
+	'A': ".###. #...# #...# ##### #...# #...# #...#",
+	'B': "####. #...# #...# ####. #...# #...# ####.",
+	'C': ".###. #...# #.... #.... #.... #...# .###.",
+	'D': "###.. #..#. #...# #...# #...# #..#. ###..",
+	'E': "##### #.... #.... ####. #.... #.... #####",
+	'F': "##### #.... #.... ####. #.... #.... #....",
+	'G': ".###. #...# #.... #.### #...# #...# .####",
+	'H': "#...# #...# #...# ##### #...# #...# #...#",
+	'I': ".###. ..#.. ..#.. ..#.. ..#.. ..#.. .###.",
+	'J': "..### ...#. ...#. ...#. ...#. #..#. .##..",
+	'K': "#...# #..#. #.#.. ##... #.#.. #..#. #...#",
+	'L': "#.... #.... #.... #.... #.... #.... #####",
+	'M': "#...# ##.## #.#.# #.#.# #...# #...# #...#",
+	'N': "#...# #...# ##..# #.#.# #..## #...# #...#",
+	'O': ".###. #...# #...# #...# #...# #...# .###.",
+	'P': "####. #...# #...# ####. #.... #.... #....",
+	'Q': ".###. #...# #...# #...# #.#.# #..#. .##.#",
+	'R': "####. #...# #...# ####. #.#.. #..#. #...#",
+	'S': ".#### #.... #.... .###. ....# ....# ####.",
+	'T': "##### ..#.. ..#.. ..#.. ..#.. ..#.. ..#..",
+	'U': "#...# #...# #...# #...# #...# #...# .###.",
+	'V': "#...# #...# #...# #...# #...# .#.#. ..#..",
+	'W': "#...# #...# #...# #.#.# #.#.# #.#.# .#.#.",
+	'X': "#...# #...# .#.#. ..#.. .#.#. #...# #...#",
+	'Y': "#...# #...# .#.#. ..#.. ..#.. ..#.. ..#..",
+	'Z': "##### ....# ...#. ..#.. .#... #.... #####",
+
+	'a': "..... ..... .###. ....# .#### #...# .####",
+	'b': "#.... #.... #.##. ##..# #...# #...# ####.",
+	'c': "..... ..... .###. #.... #.... #...# .###.",
+	'd': "....# ....# .##.# #..## #...# #...# .####",
+	'e': "..... ..... .###. #...# ##### #.... .###.",
+	'f': "..##. .#..# .#... ###.. .#... .#... .#...",
+	'g': "..... ..... .#### #...# #...# .#### ....# #...# .###.",
+	'h': "#.... #.... #.##. ##..# #...# #...# #...#",
+	'i': "..#.. ..... .##.. ..#.. ..#.. ..#.. .###.",
+	'j': "...#. ..... ..##. ...#. ...#. ...#. ...#. #..#. .##..",
+	'k': "#.... #.... #..#. #.#.. ##... #.#.. #..#.",
+	'l': ".##.. ..#.. ..#.. ..#.. ..#.. ..#.. .###.",
+	'm': "..... ..... ##.#. #.#.# #.#.# #...# #...#",
+	'n': "..... ..... #.##. ##..# #...# #...# #...#",
+	'o': "..... ..... .###. #...# #...# #...# .###.",
+	'p': "..... ..... ####. #...# #...# ####. #.... #.... #....",
+	'q': "..... ..... .#### #...# #...# .#### ....# ....# ....#",
+	'r': "..... ..... #.##. ##..# #.... #.... #....",
+	's': "..... ..... .###. #.... .###. ....# ####.",
+	't': ".#... .#... ###.. .#... .#... .#..# ..##.",
+	'u': "..... ..... #...# #...# #...# #..## .##.#",
+	'v': "..... ..... #...# #...# #...# .#.#. ..#..",
+	'w': "..... ..... #...# #...# #.#.# #.#.# .#.#.",
+	'x': "..... ..... #...# .#.#. ..#.. .#.#. #...#",
+	'y': "..... ..... #...# #...# #...# .#### ....# #...# .###.",
+	'z': "..... ..... ##### ...#. ..#.. .#... #####",
+
+	' ':  "",
+	'+':  "..... ..#.. ..#.. ##### ..#.. ..#..",
+	'-':  "..... ..... ..... #####",
+	'−':  "..... ..... ..... #####",
+	'=':  "..... ..... ##### ..... #####",
+	'≠':  "..... ...#. ##### ..#.. ##### .#...",
+	'(':  "...#. ..#.. .#... .#... .#... ..#.. ...#.",
+	')':  ".#... ..#.. ...#. ...#. ...#. ..#.. .#...",
+	'[':  ".###. .#... .#... .#... .#... .#... .###.",
+	']':  ".###. ...#. ...#. ...#. ...#. ...#. .###.",
+	',':  "..... ..... ..... ..... ..... .##.. ..#.. .#...",
+	'.':  "..... ..... ..... ..... ..... .##.. .##..",
+	';':  "..... .##.. .##.. ..... .##.. ..#.. .#...",
+	':':  "..... .##.. .##.. ..... .##.. .##..",
+	'/':  "..... ....# ...#. ..#.. .#... #....",
+	'*':  "..... ..#.. #.#.# .###. #.#.# ..#..",
+	'×':  "..... ..... #...# .#.#. ..#.. .#.#. #...#",
+	'⋅':  "..... ..... ..... ..#..",
+	'÷':  "..... ..#.. ..... ##### ..... ..#..",
+	'~':  "..... ..... .#... #.#.# ...#.",
+	'>':  ".#... ..#.. ...#. ....# ...#. ..#.. .#...",
+	'<':  "...#. ..#.. .#... #.... .#... ..#.. ...#.",
+	'_':  "..... ..... ..... ..... ..... ..... ..... #####",
+	'^':  "..#.. .#.#. #...#",
+	'%':  "##... ##..# ...#. ..#.. .#... #..## ...##",
+	'!':  "..#.. ..#.. ..#.. ..#.. ..#.. ..... ..#..",
+	'?':  ".###. #...# ....# ...#. ..#.. ..... ..#..",
+	'\'': "..#.. ..#.. .#...",
+	'π':  "..... ..... ##### .#.#. .#.#. .#.#. .#..#",
+	'±':  "..#.. ..#.. ##### ..#.. ..#.. ..... #####",
+	'≤':  "...#. ..#.. .#... ..#.. ...#. ..... #####",
+	'≥':  ".#... ..#.. ...#. ..#.. .#... ..... #####",
+	'∞':  "..... ..... .#.#. #.#.# .#.#.",
+	'→':  "..... ..#.. ...#. ##### ...#. ..#..",
+	'°':  ".##.. #..#. .##..",
+}
+
+// unknownGlyph is drawn for runes the font doesn't have.
+const unknownGlyph = "##### #...# #...# #...# #...# #...# #####"
+
+// bitmap is a parsed glyph, indexed by row and then column.
+type bitmap [glyphHeight][glyphWidth]bool
+
+var bitmaps = parseGlyphs()
+
+func parseGlyphs() map[rune]*bitmap {
+	m := make(map[rune]*bitmap, len(glyphs)+1)
+	for r, s := range glyphs {
+		m[r] = parseGlyph(s)
+	}
+	m[-1] = parseGlyph(unknownGlyph)
+
+	return m
+}
+
+func parseGlyph(s string) *bitmap {
+	var b bitmap
+	for y, row := range strings.Fields(s) {
+		for x, c := range row {
+			b[y][x] = c == '#'
+		}
+	}
+
+	return &b
+}
+
+func glyph(r rune) *bitmap {
+	if b, ok := bitmaps[r]; ok {
+		return b
+	}
+
+	return bitmaps[-1]
+}
@@ -0,0 +1,152 @@
+// Package render draws calc expressions and charts as images, using nothing
+// but the standard library and a bitmap font embedded in the source.
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+
+	"github.com/luism6n/calcbot/calc"
+)
+
+const (
+	// formulaScale is how many times the font is magnified in formulas.
+	formulaScale = 3
+	// margin is the blank space around images, in pixels.
+	margin = 20
+)
+
+// Formula typesets stmts one per line, like a blackboard would, and writes
+// " = result" after the last one.
+func Formula(stmts []calc.Node, result string) *image.RGBA {
+	lines := make([]box, len(stmts))
+	for i, stmt := range stmts {
+		lines[i] = typeset(stmt, formulaScale)
+	}
+	if len(lines) > 0 && result != "" {
+		last := len(lines) - 1
+		lines[last] = hbox(lines[last], text(" = "+result, formulaScale))
+	}
+
+	return paint(vbox(4*formulaScale, lines...))
+}
+
+// paint draws b on a white image just big enough to hold it.
+func paint(b box) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, b.width+2*margin, b.height()+2*margin))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	b.draw(img, margin, margin+b.ascent)
+
+	return img
+}
+
+// typeset lays n out at the given font scale.
+func typeset(n calc.Node, scale int) box {
+	switch n := n.(type) {
+	case *calc.NumberLit:
+		if n.Int != nil {
+			return text(calc.FormatInteger(n.Int, calc.NumberFormat{}), scale)
+		}
+		return number(n.Value, scale)
+	case *calc.Ident:
+		return text(n.Name, scale)
+	case *calc.UnaryExpr:
+		return hbox(text(calc.MathSymbol(n.Op), scale), operand(n.X, calc.MathProduct+1, scale))
+	case *calc.BinaryExpr:
+		op := text(" "+calc.MathSymbol(n.Op)+" ", scale)
+		switch n.Op {
+		case "/":
+			return fraction(typeset(n.X, scale), typeset(n.Y, scale), scale)
+		case "*":
+			return hbox(operand(n.X, calc.MathProduct, scale), op, operand(n.Y, calc.MathProduct, scale))
+		case "%":
+			return hbox(operand(n.X, calc.MathProduct, scale), op, operand(n.Y, calc.MathProduct+1, scale))
+		case "<", ">", "<=", ">=", "==", "!=":
+			return hbox(operand(n.X, calc.MathCompare+1, scale), op, operand(n.Y, calc.MathCompare+1, scale))
+		}
+		return hbox(operand(n.X, calc.MathSum, scale), op, operand(n.Y, calc.MathSum+1, scale))
+	case *calc.CallExpr:
+		return call(n, scale)
+	case *calc.ListExpr:
+		elems := make([]box, 0, 2*len(n.Elems))
+		for i, elem := range n.Elems {
+			if i > 0 {
+				elems = append(elems, text(", ", scale))
+			}
+			elems = append(elems, typeset(elem, scale))
+		}
+		return hbox(text("[", scale), hbox(elems...), text("]", scale))
+	case *calc.AssignExpr:
+		return hbox(text(n.Name+" = ", scale), typeset(n.Value, scale))
+	}
+
+	return text(n.String(), scale)
+}
+
+func call(n *calc.CallExpr, scale int) box {
+	small := smaller(scale)
+	space := hspace(glyphAdvance * scale)
+
+	switch {
+	case n.Func == "pow" && len(n.Args) == 2:
+		if exp, ok := n.Args[1].(*calc.NumberLit); ok && exp.Value == 0.5 {
+			return radical(typeset(n.Args[0], scale), scale)
+		}
+		base := operand(n.Args[0], calc.MathAtom, scale)
+		if calc.IsSuperscripted(n.Args[0]) {
+			// Otherwise pow(pow(x, y), z) would look like x^(y^z).
+			base = parens(base, scale)
+		}
+		return superscript(base, typeset(n.Args[1], small))
+	case n.Func == "exp" && len(n.Args) == 1:
+		return superscript(text("e", scale), typeset(n.Args[0], small))
+	case n.Func == "log" && len(n.Args) == 2:
+		return hbox(subscript(text("log", scale), typeset(n.Args[0], small)), space, operand(n.Args[1], calc.MathAtom, scale))
+	case n.Func == "log10" && len(n.Args) == 1:
+		return hbox(subscript(text("log", scale), text("10", small)), space, operand(n.Args[0], calc.MathAtom, scale))
+	case n.Func == "log2" && len(n.Args) == 1:
+		return hbox(subscript(text("log", scale), text("2", small)), space, operand(n.Args[0], calc.MathAtom, scale))
+	case n.Func == "ln" && len(n.Args) == 1:
+		return hbox(text("ln", scale), space, operand(n.Args[0], calc.MathAtom, scale))
+	}
+
+	args := make([]box, 0, 2*len(n.Args))
+	for i, arg := range n.Args {
+		if i > 0 {
+			args = append(args, text(", ", scale))
+		}
+		args = append(args, typeset(arg, scale))
+	}
+
+	return hbox(text(n.Func, scale), parens(hbox(args...), scale))
+}
+
+// operand typesets n, wrapping it in parenthesis if it binds looser than
+// prec.
+func operand(n calc.Node, prec, scale int) box {
+	if calc.MathPrecedence(n) < prec {
+		return parens(typeset(n, scale), scale)
+	}
+
+	return typeset(n, scale)
+}
+
+// number typesets f, writing scientific notation as m × 10ᵉ.
+func number(f float64, scale int) box {
+	s := calc.Number(f).String()
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		exp := strings.TrimLeft(strings.TrimPrefix(s[i+1:], "+"), "0")
+		if strings.HasPrefix(exp, "-") {
+			exp = "−" + strings.TrimLeft(exp[1:], "0")
+		}
+		return hbox(text(s[:i]+" × ", scale), superscript(text("10", scale), text(exp, smaller(scale))))
+	}
+
+	return text(strings.Replace(s, "-", "−", 1), scale)
+}
+
+func smaller(scale int) int {
+	return max(1, scale-1)
+}
@@ -0,0 +1,115 @@
+package render
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/luism6n/calcbot/calc"
+)
+
+func TestFont(t *testing.T) {
+	t.Run("Should have well formed glyphs", func(t *testing.T) {
+		for r, g := range glyphs {
+			rows := strings.Fields(g)
+			if len(rows) > glyphHeight {
+				t.Fatalf("glyph %q has %d rows", r, len(rows))
+			}
+			for _, row := range rows {
+				if len(row) != glyphWidth || strings.Trim(row, ".#") != "" {
+					t.Fatalf("glyph %q has a bad row %q", r, row)
+				}
+			}
+		}
+	})
+
+	t.Run("Should have glyphs for the math symbols", func(t *testing.T) {
+		for op, symbol := range calc.MathSymbols {
+			for _, r := range symbol {
+				if _, ok := glyphs[r]; !ok {
+					t.Fatalf("no glyph for %q, the symbol of %s", r, op)
+				}
+			}
+		}
+	})
+}
+
+func TestFormula(t *testing.T) {
+	t.Run("Should draw formulas", func(t *testing.T) {
+		testCases := []string{
+			"1 + 2",
+			"a = 2; log2(a) * (a - 1)",
+			"pow(x + 1, 2) / (exp(-x) - pow(2, 0.5))",
+			"log(b, 6.67e-11) + f(τ, 1)",
+			"k % 2 == 1; x != -y",
+		}
+
+		for _, c := range testCases {
+			img := formula(t, c, "42")
+			if img.Bounds().Dx() <= 2*margin || img.Bounds().Dy() <= 2*margin {
+				t.Fatalf("image for %q is empty: %v", c, img.Bounds())
+			}
+
+			if !inked(img) {
+				t.Fatalf("nothing was drawn for %q", c)
+			}
+		}
+	})
+
+	t.Run("Should stack fractions", func(t *testing.T) {
+		flat := formula(t, "1 + 2", "")
+		stacked := formula(t, "1 / 2", "")
+		if stacked.Bounds().Dy() <= flat.Bounds().Dy() || stacked.Bounds().Dx() >= flat.Bounds().Dx() {
+			t.Fatalf("1 / 2 (%v) isn't taller and narrower than 1 + 2 (%v)", stacked.Bounds(), flat.Bounds())
+		}
+	})
+
+	t.Run("Should parenthesize powers of powers", func(t *testing.T) {
+		stmts, err := calc.Parse("pow(pow(x, y), z)")
+		if err != nil {
+			t.Fatalf("error (%s) not nil", err)
+		}
+		pow := stmts[0].(*calc.CallExpr)
+
+		got := typeset(pow, formulaScale).width
+		want := superscript(parens(typeset(pow.Args[0], formulaScale), formulaScale), text("z", smaller(formulaScale))).width
+		if got != want {
+			t.Fatalf("(x^y)^z is %d pixels wide, want %d", got, want)
+		}
+	})
+
+	t.Run("Should write every digit of large integers", func(t *testing.T) {
+		exact := formula(t, "100000000000000000001", "")
+		rounded := formula(t, "1e20", "")
+		if exact.Bounds().Dx() <= rounded.Bounds().Dx() {
+			t.Fatalf("100000000000000000001 (%v) isn't wider than 1e20 (%v)", exact.Bounds(), rounded.Bounds())
+		}
+	})
+
+	t.Run("Should put one statement per line", func(t *testing.T) {
+		one := formula(t, "a = 1", "")
+		two := formula(t, "a = 1; a", "")
+		if two.Bounds().Dy() < 2*one.Bounds().Dy()-2*margin {
+			t.Fatalf("two statements (%v) don't take two lines (%v)", two.Bounds(), one.Bounds())
+		}
+	})
+}
+
+func formula(t *testing.T, program, result string) *image.RGBA {
+	stmts, err := calc.Parse(program)
+	if err != nil {
+		t.Fatalf("error (%s) not nil for %q", err, program)
+	}
+
+	return Formula(stmts, result)
+}
+
+func inked(img *image.RGBA) bool {
+	for i := 0; i < len(img.Pix); i += 4 {
+		if img.Pix[i] == 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/luism6n/calcbot/calc"
+)
+
+// settings are the preferences of a chat and the results computed in it.
+// Inline queries use the ones of the private chat with the user asking.
+type settings struct {
+	locale    calc.Locale
+	format    calc.NumberFormat
+	plusMinus calc.PlusMinus
+	results   []calc.Value
+}
+
+// maxResults is how many results of a chat are remembered. Older ones are
+// forgotten, which renumbers the rest for _1, _2 and so on.
+const maxResults = 100
+
+var defaultSettings = settings{
+	locale: calc.English,
+	format: calc.NumberFormat{Mode: calc.Auto},
+}
+
+// settingsStore keeps the settings of every chat in memory.
+type settingsStore struct {
+	mu    sync.Mutex
+	chats map[int64]settings
+}
+
+func newSettingsStore() *settingsStore {
+	return &settingsStore{
+		chats: make(map[int64]settings),
+	}
+}
+
+func (s *settingsStore) get(chatID int64) settings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if st, ok := s.chats[chatID]; ok {
+		return st
+	}
+
+	return defaultSettings
+}
+
+func (s *settingsStore) update(chatID int64, f func(*settings)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.chats[chatID]
+	if !ok {
+		st = defaultSettings
+	}
+	f(&st)
+	s.chats[chatID] = st
+}
+
+// remember keeps the results of the programs evaluated in a chat, up to
+// maxResults of them.
+func (s *settingsStore) remember(chatID int64, results []calc.Value) {
+	if len(results) > maxResults {
+		results = results[len(results)-maxResults:]
+	}
+	s.update(chatID, func(st *settings) {
+		st.results = results
+	})
+}
+
+// setLocale implements the /locale command, which shows or sets the locale
+// of a chat.
+func setLocale(chatID int64, args string) string {
+	name := strings.TrimSpace(args)
+	if name == "" {
+		return fmt.Sprintf("The locale is %s. Choose one of: %s", chats.get(chatID).locale.Name, localeNames())
+	}
+
+	locale, ok := calc.LookupLocale(name)
+	if !ok {
+		return fmt.Sprintf("Unknown locale %s. Choose one of: %s", name, localeNames())
+	}
+
+	chats.update(chatID, func(st *settings) {
+		st.locale = locale
+	})
+
+	return fmt.Sprintf("Locale set to %s: %s", locale.Name, locale.Localize("1234567.89"))
+}
+
+func localeNames() string {
+	names := make([]string, len(calc.Locales))
+	for i, l := range calc.Locales {
+		names[i] = l.Name
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// numberModes are the notations /format chooses from, by name.
+var numberModes = []struct {
+	name string
+	mode calc.NumberMode
+}{
+	{"auto", calc.Auto},
+	{"shortest", calc.Shortest},
+	{"fixed", calc.Fixed},
+	{"sig", calc.Significant},
+	{"sci", calc.Scientific},
+	{"eng", calc.Engineering},
+	{"roman", calc.Roman},
+	{"words", calc.Words},
+	{"base", calc.Base},
+}
+
+// setFormat implements the /format command, which shows or sets how a chat
+// wants numbers written, e.g. "/format fixed 2", "/format eng" or
+// "/format base 2".
+func setFormat(chatID int64, args string) string {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 {
+		return fmt.Sprintf("The format is %s. Choose one of: %s, optionally followed by a number of digits, or the base for base", formatName(chats.get(chatID).format), formatNames())
+	}
+
+	var nf calc.NumberFormat
+	found := false
+	for _, m := range numberModes {
+		if m.name == fields[0] {
+			nf.Mode, found = m.mode, true
+		}
+	}
+	if !found {
+		return fmt.Sprintf("Unknown format %s. Choose one of: %s", fields[0], formatNames())
+	}
+
+	if len(fields) == 2 && nf.Mode == calc.Base {
+		base, err := strconv.Atoi(fields[1])
+		if err != nil || base < 2 || base > 36 {
+			return fmt.Sprintf("The base must be between 2 and 36, got %s", fields[1])
+		}
+		nf.Base = base
+	} else if len(fields) == 2 {
+		digits, err := strconv.Atoi(fields[1])
+		if err != nil || digits < 0 || digits > 17 {
+			return fmt.Sprintf("The number of digits must be between 0 and 17, got %s", fields[1])
+		}
+		nf.Digits = digits
+	}
+
+	var locale calc.Locale
+	chats.update(chatID, func(st *settings) {
+		st.format = nf
+		locale = st.locale
+	})
+
+	nf.Locale = locale
+	example := 1234567.891
+	if nf.Mode == calc.Roman || nf.Mode == calc.Base {
+		example = 1994
+	}
+	return fmt.Sprintf("Format set to %s: %s", formatName(nf), calc.FormatNumber(example, nf))
+}
+
+func formatName(nf calc.NumberFormat) string {
+	for _, m := range numberModes {
+		if m.mode == nf.Mode {
+			if nf.Mode == calc.Base && nf.Base != 0 {
+				return fmt.Sprintf("%s %d", m.name, nf.Base)
+			}
+			if nf.Digits == 0 {
+				return m.name
+			}
+			return fmt.Sprintf("%s %d", m.name, nf.Digits)
+		}
+	}
+
+	return "unknown"
+}
+
+func formatNames() string {
+	names := make([]string, len(numberModes))
+	for i, m := range numberModes {
+		names[i] = m.name
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// plusMinusConventions are the meanings of ± /plusminus chooses from, by
+// name.
+var plusMinusConventions = []struct {
+	name      string
+	plusMinus calc.PlusMinus
+	example   string
+}{
+	{"interval", calc.PlusMinusInterval, "2 ± 0.1 is the interval from 1.9 to 2.1"},
+	{"uncertainty", calc.PlusMinusUncertainty, "9.81 ± 0.02 is a measurement with standard uncertainty 0.02"},
+}
+
+// setPlusMinus implements the /plusminus command, which shows or sets what
+// ± means in a chat.
+func setPlusMinus(chatID int64, args string) string {
+	name := strings.TrimSpace(args)
+	if name == "" {
+		current := chats.get(chatID).plusMinus
+		for _, c := range plusMinusConventions {
+			if c.plusMinus == current {
+				return fmt.Sprintf("± means %s: %s. Choose one of: %s", c.name, c.example, plusMinusNames())
+			}
+		}
+	}
+
+	for _, c := range plusMinusConventions {
+		if c.name == name {
+			chats.update(chatID, func(st *settings) {
+				st.plusMinus = c.plusMinus
+			})
+			return fmt.Sprintf("± set to %s: %s", c.name, c.example)
+		}
+	}
+
+	return fmt.Sprintf("Unknown meaning %s. Choose one of: %s", name, plusMinusNames())
+}
+
+func plusMinusNames() string {
+	names := make([]string, len(plusMinusConventions))
+	for i, c := range plusMinusConventions {
+		names[i] = c.name
+	}
+
+	return strings.Join(names, ", ")
+}